@@ -79,6 +79,10 @@ func ByName(klName string) KeyLock {
 func (kl *keyLock) Acquire(id string) LockHandle {
 	h := kl.getOrCreateLock(id)
 	h.mutex.Lock()
+	// The handle is shared with Release and getOrCreateLock, which update it
+	// under the keyLock lock.
+	kl.Lock()
+	defer kl.Unlock()
 	h.genNum++
 	return *h
 }
@@ -90,6 +90,27 @@ func TestLottaLocks(t *testing.T) {
 	endState(t, kl, 0)
 }
 
+func TestConcurrentAcquireRelease(t *testing.T) {
+	kl := New()
+	done := make(chan error)
+	for i := 0; i < 8; i++ {
+		go func() {
+			for j := 0; j < 100; j++ {
+				h := kl.Acquire("foo")
+				if err := kl.Release(&h); err != nil {
+					done <- err
+					return
+				}
+			}
+			done <- nil
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		require.NoError(t, <-done, "unlock")
+	}
+	endState(t, kl, 0)
+}
+
 func lockAndSleep(t *testing.T, kl KeyLock, key string, doneCb chan<- int) {
 	cb := make(chan *LockHandle)
 	go lock(t, kl, key, cb)
@@ -0,0 +1,144 @@
+package mount
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AllowedDirs returns the directories mountpaths must be in. Any mountpath
+// is allowed if it is empty, unless WithStrictAllowedDirs is set.
+func (m *Mounter) AllowedDirs() []string {
+	m.RLock()
+	defer m.RUnlock()
+	return append([]string(nil), m.allowedDirs...)
+}
+
+// AddAllowedDir allows mountpaths in dir. Mounts already outside of the
+// allowed directories are not affected.
+func (m *Mounter) AddAllowedDir(dir string) {
+	dir = filepath.Clean(dir)
+	m.Lock()
+	defer m.Unlock()
+	for _, allowedDir := range m.allowedDirs {
+		if filepath.Clean(allowedDir) == dir {
+			return
+		}
+	}
+	m.allowedDirs = append(m.allowedDirs, dir)
+}
+
+// RemoveAllowedDir stops allowing mountpaths in dir. The existing mounts in
+// dir are kept. Removing the last directory allows any mountpath, unless
+// WithStrictAllowedDirs is set.
+func (m *Mounter) RemoveAllowedDir(dir string) {
+	dir = filepath.Clean(dir)
+	m.Lock()
+	defer m.Unlock()
+	allowedDirs := m.allowedDirs[:0:0]
+	for _, allowedDir := range m.allowedDirs {
+		if filepath.Clean(allowedDir) != dir {
+			allowedDirs = append(allowedDirs, allowedDir)
+		}
+	}
+	m.allowedDirs = allowedDirs
+}
+
+// WithStrictAllowedDirs makes an empty list of allowed directories allow no
+// mountpath, so that Mount fails with ErrMountpathNotAllowed if none were
+// configured rather than allowing all of them.
+func WithStrictAllowedDirs() MounterOption {
+	return func(m *Mounter) {
+		m.strictAllowedDirs = true
+	}
+}
+
+// WithDeniedDirs makes Mount refuse mountpaths in dirs with
+// ErrMountpathDenied, even if they are in an allowed directory.
+func WithDeniedDirs(dirs ...string) MounterOption {
+	return func(m *Mounter) {
+		m.deniedDirs = append(m.deniedDirs, dirs...)
+	}
+}
+
+// ValidateMountpath returns an error if Mount would refuse path as a
+// mountpoint, without mounting: ErrMountpathNotAllowed if it is not in one of
+// the allowed directories, ErrMountpathDenied if it is in a denied directory
+// and ErrTargetIsSymlink if it is a symlink.
+func (m *Mounter) ValidateMountpath(path string) error {
+	return m.validateMountpath(normalizeMountPath(path))
+}
+
+// validateMountpath is ValidateMountpath for a normalized path.
+func (m *Mounter) validateMountpath(path string) error {
+	allowedDirs := m.AllowedDirs()
+	checkAllowed := len(allowedDirs) > 0 || m.strictAllowedDirs
+	if checkAllowed && !inDirs(path, allowedDirs) {
+		return ErrMountpathNotAllowed
+	}
+	if len(m.deniedDirs) > 0 && inDirs(path, m.deniedDirs) {
+		return ErrMountpathDenied
+	}
+	// A symlink could point out of the allowed directories or into a
+	// denied one.
+	if checkAllowed || len(m.deniedDirs) > 0 || !m.allowSymlinkTargets {
+		return checkTargetSymlink(path)
+	}
+	return nil
+}
+
+// isAllowed returns true if path is in one of the allowed directories.
+func (m *Mounter) isAllowed(path string) bool {
+	return inDirs(path, m.AllowedDirs())
+}
+
+// inDirs returns true if path is in one of dirs. Both are compared with their
+// symlinks evaluated, so that a symlink can neither point out of a directory
+// nor hide that a path is in one.
+func inDirs(path string, dirs []string) bool {
+	resolved := resolveExisting(path)
+	for _, dir := range dirs {
+		if isInDir(resolved, resolveExisting(dir)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInDir returns true if the clean path is dir or below it. Paths are
+// matched by element, so /mnt/data2 is not in /mnt/data.
+func isInDir(path, dir string) bool {
+	if path == dir || dir == string(filepath.Separator) {
+		return true
+	}
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// resolveExisting returns the absolute path of path with the symlinks of its
+// longest existing ancestor evaluated. The elements that do not exist yet are
+// appended as they are. ".." elements are only cleaned after the symlinks
+// before them are evaluated, as the kernel does.
+func resolveExisting(path string) string {
+	if !filepath.IsAbs(path) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return filepath.Clean(path)
+		}
+		path = wd + string(filepath.Separator) + path
+	}
+	elems := strings.Split(path, string(filepath.Separator))
+	for i := len(elems); i > 0; i-- {
+		dir := strings.Join(elems[:i], string(filepath.Separator))
+		if dir == "" {
+			dir = string(filepath.Separator)
+		}
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, elems[i:]...)...)
+		}
+		if !os.IsNotExist(err) {
+			break
+		}
+	}
+	return filepath.Clean(path)
+}
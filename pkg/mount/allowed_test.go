@@ -0,0 +1,179 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowedDirsSymlinks(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	allowed := filepath.Join(root, "allowed")
+	outside := filepath.Join(root, "outside")
+	require.NoError(t, os.MkdirAll(filepath.Join(outside, "sub"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(allowed, "vol"), 0755))
+	// escape points out of the allowed directory.
+	require.NoError(t, os.Symlink(filepath.Join(outside, "sub"), filepath.Join(allowed, "escape")))
+	// alias is a symlink to the allowed directory.
+	alias := filepath.Join(root, "alias")
+	require.NoError(t, os.Symlink(allowed, alias))
+	// into points to the allowed directory from outside.
+	require.NoError(t, os.Symlink(allowed, filepath.Join(outside, "into")))
+
+	m, _ := newTestMounter(t)
+	m.allowedDirs = []string{allowed}
+	for path, ok := range map[string]bool{
+		filepath.Join(allowed, "vol"):                 true,
+		filepath.Join(allowed, "new", "vol"):          true,
+		filepath.Join(alias, "vol"):                   true,
+		filepath.Join(outside, "into", "vol"):         true,
+		filepath.Join(outside, "into", "new"):         true,
+		filepath.Join(allowed, "escape", "vol"):       false,
+		filepath.Join(allowed, "escape") + "/../vol":  false,
+		filepath.Join(allowed, "escape") + "/../../x": false,
+		filepath.Join(outside, "sub"):                 false,
+	} {
+		require.Equal(t, ok, m.isAllowed(path), path)
+	}
+
+	// The allowed directory is resolved too.
+	m.allowedDirs = []string{alias}
+	require.True(t, m.isAllowed(filepath.Join(allowed, "vol")))
+	require.False(t, m.isAllowed(filepath.Join(outside, "sub")))
+
+	err = m.Mount(0, "/dev/allowed", filepath.Join(allowed, "escape")+"/../vol", "ext4", 0, "", 0, nil)
+	require.Equal(t, ErrMountpathNotAllowed, err)
+	require.NoError(t, m.Mount(0, "/dev/allowed", filepath.Join(allowed, "vol"), "ext4", 0, "", 0, nil))
+}
+
+func TestAllowedDirsByElement(t *testing.T) {
+	m, _ := newTestMounter(t)
+	m.allowedDirs = []string{"/mnt/data"}
+	for path, ok := range map[string]bool{
+		"/mnt/data":         true,
+		"/mnt/data/x":       true,
+		"/mnt/data/x/y":     true,
+		"/mnt/data2":        false,
+		"/mnt/data2/x":      false,
+		"/mnt/database":     false,
+		"/foo/mnt/data":     false,
+		"/foo/mnt/data/x":   false,
+		"/mnt":              false,
+		"/mnt/dat":          false,
+		"/mnt/other/data/x": false,
+	} {
+		require.Equal(t, ok, m.isAllowed(path), path)
+	}
+	require.Equal(t, ErrMountpathNotAllowed, m.Mount(0, "/dev/data", "/mnt/data2", "ext4", 0, "", 0, nil))
+	require.Equal(t, ErrMountpathNotAllowed, m.Mount(0, "/dev/data", "/foo/mnt/data/x", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/dev/data", "/mnt/data/x", "ext4", 0, "", 0, nil))
+
+	m.allowedDirs = []string{"/"}
+	require.True(t, m.isAllowed("/mnt/data2"), "Expected / to allow every path")
+}
+
+func TestValidateMountpath(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	allowed := filepath.Join(root, "allowed")
+	require.NoError(t, os.MkdirAll(filepath.Join(allowed, "vol"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "allowed2"), 0755))
+	link := filepath.Join(allowed, "link")
+	require.NoError(t, os.Symlink(filepath.Join(allowed, "vol"), link))
+
+	m, mi := newTestMounter(t)
+	m.allowedDirs = []string{allowed}
+	require.NoError(t, m.ValidateMountpath(filepath.Join(allowed, "vol")))
+	require.NoError(t, m.ValidateMountpath(filepath.Join(allowed, "vol")+"/"))
+	require.NoError(t, m.ValidateMountpath(filepath.Join(allowed, "new")))
+	require.Equal(t, ErrMountpathNotAllowed, m.ValidateMountpath(filepath.Join(root, "allowed2")),
+		"Expected a sibling sharing the prefix not to be allowed")
+	require.Equal(t, ErrMountpathNotAllowed, m.ValidateMountpath(filepath.Join(root, "vol")))
+	require.Equal(t, ErrTargetIsSymlink, m.ValidateMountpath(link))
+	require.Empty(t, mi.calls, "Expected nothing to be mounted")
+
+	m.allowedDirs = nil
+	require.NoError(t, m.ValidateMountpath(filepath.Join(root, "vol")))
+	require.Equal(t, ErrTargetIsSymlink, m.ValidateMountpath(link))
+	m.allowSymlinkTargets = true
+	require.NoError(t, m.ValidateMountpath(link))
+}
+
+func TestAllowedDirsAtRuntime(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	first := filepath.Join(root, "first")
+	second := filepath.Join(root, "second")
+
+	m, _ := newTestMounter(t)
+	m.AddAllowedDir(first)
+	require.Equal(t, []string{first}, m.AllowedDirs())
+	path := filepath.Join(second, "vol")
+	require.Equal(t, ErrMountpathNotAllowed, m.Mount(0, "/dev/allowed", path, "ext4", 0, "", 0, nil))
+
+	m.AddAllowedDir(second + "/")
+	m.AddAllowedDir(second)
+	require.Equal(t, []string{first, second}, m.AllowedDirs(), "Expected a directory to be added once")
+	require.NoError(t, m.Mount(0, "/dev/allowed", path, "ext4", 0, "", 0, nil))
+
+	m.RemoveAllowedDir(second)
+	require.Equal(t, []string{first}, m.AllowedDirs())
+	require.Equal(t, ErrMountpathNotAllowed, m.Mount(0, "/dev/other", filepath.Join(second, "other"), "ext4", 0, "", 0, nil))
+	require.Equal(t, 1, m.HasMounts("/dev/allowed"), "Expected existing mounts to be kept")
+
+	m.RemoveAllowedDir(first)
+	require.Empty(t, m.AllowedDirs())
+	require.NoError(t, m.Mount(0, "/dev/other", filepath.Join(second, "other"), "ext4", 0, "", 0, nil),
+		"Expected any mountpath to be allowed without allowed directories")
+}
+
+func TestDeniedDirs(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	critical := filepath.Join(root, "critical")
+	require.NoError(t, os.MkdirAll(critical, 0755))
+	alias := filepath.Join(root, "alias")
+	require.NoError(t, os.Symlink(critical, alias))
+
+	m, _ := newTestMounter(t, WithDeniedDirs(critical))
+	m.allowedDirs = []string{root}
+	require.NoError(t, m.ValidateMountpath(filepath.Join(root, "vol")))
+	require.NoError(t, m.ValidateMountpath(critical+"2"), "Expected a sibling sharing the prefix not to be denied")
+	require.Equal(t, ErrMountpathDenied, m.ValidateMountpath(critical))
+	require.Equal(t, ErrMountpathDenied, m.ValidateMountpath(filepath.Join(critical, "vol")))
+	require.Equal(t, ErrMountpathDenied, m.ValidateMountpath(filepath.Join(alias, "vol")),
+		"Expected a symlink not to hide a denied path")
+	require.Equal(t, ErrMountpathDenied,
+		m.Mount(0, "/dev/denied", filepath.Join(critical, "vol"), "ext4", 0, "", 0, nil))
+	require.Equal(t, 0, m.HasMounts("/dev/denied"))
+
+	m.allowedDirs = nil
+	require.Equal(t, ErrMountpathDenied, m.ValidateMountpath(filepath.Join(critical, "vol")),
+		"Expected the deny-list to apply without an allow-list")
+}
+
+func TestStrictAllowedDirs(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	path := filepath.Join(root, "vol")
+
+	m, _ := newTestMounter(t)
+	require.NoError(t, m.ValidateMountpath(path), "Expected no allowed directories to allow any mountpath")
+
+	m, _ = newTestMounter(t, WithStrictAllowedDirs())
+	require.Equal(t, ErrMountpathNotAllowed, m.ValidateMountpath(path))
+	require.Equal(t, ErrMountpathNotAllowed, m.Mount(0, "/dev/strict", path, "ext4", 0, "", 0, nil))
+	require.Equal(t, 0, m.HasMounts("/dev/strict"))
+
+	m.AddAllowedDir(root)
+	require.NoError(t, m.Mount(0, "/dev/strict", path, "ext4", 0, "", 0, nil))
+	m.RemoveAllowedDir(root)
+	require.Equal(t, ErrMountpathNotAllowed, m.ValidateMountpath(path),
+		"Expected removing the last directory to allow nothing")
+}
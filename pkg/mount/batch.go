@@ -0,0 +1,220 @@
+package mount
+
+import (
+	"fmt"
+	"time"
+)
+
+// MountOptions are the arguments of a mount made with MountWithOptions or
+// MountBatch.
+type MountOptions struct {
+	Minor   int
+	Device  string
+	Path    string
+	Fs      string
+	Flags   uintptr
+	Data    string
+	Timeout int
+	Opts    map[string]string
+	// ReadOnly adds MS_RDONLY to Flags. If the mount table shows the new
+	// mount read-write, as some filesystems ignore MS_RDONLY on the initial
+	// mount, it is remounted with MS_REMOUNT|MS_RDONLY.
+	ReadOnly bool
+	// NoSymFollow adds MS_NOSYMFOLLOW to Flags, so that symlinks are not
+	// followed in the mount. It is ignored before Linux 5.10.
+	NoSymFollow bool
+	// SELinuxLabel labels the files of the mount with an SELinux context,
+	// such as system_u:object_r:container_file_t:s0, with the context mount
+	// option. Bind mounts are relabeled after mounting instead.
+	SELinuxLabel string
+	// Recursive makes a bind mount recursive (rbind), so that the mounts
+	// under the source are mounted under Path too. They are recorded in the
+	// table after mounting and can be unmounted with the root by passing
+	// options.OptionsUnmountChildren to Unmount.
+	Recursive bool
+	// CreateTarget creates Path if it does not exist. Directories created
+	// are removed if the mount fails.
+	CreateTarget *CreateTarget
+	// FormatIfEmpty creates a filesystem on Device if it has none before
+	// mounting it. Fs defaults to the filesystem of the device.
+	FormatIfEmpty *Format
+	// Owner is applied to the mount root after mounting. The mount is rolled
+	// back if it cannot be applied. For filesystems without file ownership,
+	// such as vfat, it is passed as the uid, gid and umask options in Data.
+	Owner *Ownership
+	// VerifyWritable creates and removes a file in Path after a read-write
+	// mount, and fails with ErrFilesystemReadOnly if the filesystem turns out
+	// to be read-only. The mount is rolled back if the probe fails.
+	VerifyWritable bool
+	// FailIfMounted returns ErrAlreadyMounted if Device is already mounted
+	// at Path, instead of succeeding without mounting. Remounts are not
+	// affected.
+	FailIfMounted bool
+	// RollbackOnError unmounts the mounts of the batch that succeeded if this
+	// mount fails, and skips the remaining ones.
+	RollbackOnError bool
+	// TTL unmounts the mount automatically once it expires, unless it is
+	// postponed with Refresh. The TTL runs on the clock and scheduler of the
+	// Mounter.
+	TTL time.Duration
+}
+
+// MountWithOptions mounts o.Device at o.Path as described by o.
+func (m *Mounter) MountWithOptions(o MountOptions) error {
+	return m.mountWithOptions(o, nil)
+}
+
+// mountWithOptions is MountWithOptions, setting *added if o.Path was mounted
+// by this call rather than found mounted already.
+func (m *Mounter) mountWithOptions(o MountOptions, added *bool) error {
+	var isNew bool
+	if added == nil {
+		added = &isNew
+	}
+	var call []mountOption
+	if m.prepareMount != nil {
+		prepared, err := m.prepareMount(&o)
+		if err != nil {
+			return err
+		}
+		call = append(call, prepared...)
+	}
+	flags := o.Flags
+	if o.ReadOnly {
+		flags |= msRdonly
+	}
+	if o.NoSymFollow {
+		flags |= msNosymfollow
+	}
+	if o.Recursive {
+		if !isBindMount(flags) {
+			return fmt.Errorf("recursive mount of %s is not a bind mount: %w", o.Device, ErrEinval)
+		}
+		flags |= msRec
+	}
+	data := o.Data
+	if o.SELinuxLabel != "" {
+		if err := validateSELinuxLabel(o.SELinuxLabel); err != nil {
+			return err
+		}
+		if isBindMount(flags) {
+			call = append(call, withPostMountHook(m.relabelHook(o.SELinuxLabel)))
+		} else {
+			var err error
+			if data, err = withSELinuxContext(data, o.SELinuxLabel); err != nil {
+				return err
+			}
+		}
+	}
+	if o.FormatIfEmpty != nil || o.CreateTarget != nil {
+		// Fail before destroying data on the device or creating directories
+		// if the mount would.
+		if err := m.checkMount(mountDevice(o.Device, o.Opts), normalizeMountPath(o.Path)); err != nil {
+			return err
+		}
+	}
+	if o.FormatIfEmpty != nil {
+		fs, err := m.formatIfEmpty(o.Device, o.FormatIfEmpty)
+		if err != nil {
+			return err
+		}
+		if o.Fs == "" {
+			o.Fs = fs
+		}
+	}
+	created := ""
+	if o.CreateTarget != nil {
+		var err error
+		if created, err = createTarget(o.Path, o.CreateTarget); err != nil {
+			return fmt.Errorf("failed to create mountpoint %s: %w", o.Path, err)
+		}
+	}
+	if o.ReadOnly {
+		call = append(call, withReadOnlyRemount())
+	}
+	if o.VerifyWritable && flags&msRdonly == 0 {
+		call = append(call, withPostMountHook(m.writeProbeHook()))
+	}
+	if o.Owner != nil {
+		var ok bool
+		if data, ok = ownershipData(o.Fs, data, o.Owner); !ok {
+			call = append(call, withPostMountHook(m.ownershipHook(o.Owner)))
+		}
+	}
+	if o.FailIfMounted {
+		call = append(call, withFailIfMounted())
+	}
+	call = append(call, withAdded(added))
+	err := m.mount(o.Minor, o.Device, mountDevice(o.Device, o.Opts), o.Path, o.Fs,
+		flags, data, o.Timeout, call...)
+	if err != nil && created != "" {
+		if e := removeCreated(o.Path, created); e != nil {
+			m.logger.Warnf("Failed to remove mountpoint %s after mount failure: %v", o.Path, e)
+		}
+	}
+	if err == nil && o.Recursive {
+		if e := m.recordChildMounts(normalizeMountPath(o.Path)); e != nil {
+			m.logger.Warnf("Failed to record the submounts of %s: %v", o.Path, e)
+		}
+	}
+	if err == nil && o.TTL > 0 {
+		device := mountDevice(o.Device, o.Opts)
+		if err = m.addExpiry(device, normalizeMountPath(o.Path), o.TTL); err != nil && *added {
+			if e := m.Unmount(device, o.Path, 0, o.Timeout, nil); e != nil {
+				m.logger.Warnf("Failed to roll back mount of %s at %s: %v", device, o.Path, e)
+			}
+		}
+	}
+	return err
+}
+
+// MountBatch mounts each entry of opts in order and returns an error per
+// entry, along with an error if any of them failed. If a failing entry has
+// RollbackOnError set, the entries mounted before it are unmounted and the
+// rest are not attempted; their errors are ErrBatchRolledBack. Entries found
+// mounted already are not unmounted by a rollback. The returned error wraps
+// a *MultiError of the failed mounts and rollbacks by path.
+func (m *Mounter) MountBatch(opts []MountOptions) ([]error, error) {
+	errs := make([]error, len(opts))
+	added := make([]bool, len(opts))
+	var merr MultiError
+	for i, o := range opts {
+		err := m.mountWithOptions(o, &added[i])
+		if err == nil {
+			continue
+		}
+		errs[i] = err
+		merr.add(o.Path, err)
+		if !o.RollbackOnError {
+			continue
+		}
+		m.rollback(opts[:i], added[:i], errs[:i], &merr)
+		for j := i + 1; j < len(opts); j++ {
+			errs[j] = ErrBatchRolledBack
+		}
+		return errs, fmt.Errorf("mount %d of %d failed, batch rolled back: %w",
+			i+1, len(opts), &merr)
+	}
+	if len(merr.Errors) > 0 {
+		return errs, fmt.Errorf("%d of %d mounts failed: %w", len(merr.Errors), len(opts), &merr)
+	}
+	return errs, nil
+}
+
+// rollback unmounts, in reverse order, the entries of opts that were added
+// by the batch and have no error in errs. Failed unmounts are added to merr.
+func (m *Mounter) rollback(opts []MountOptions, added []bool, errs []error, merr *MultiError) {
+	for i := len(opts) - 1; i >= 0; i-- {
+		if errs[i] != nil || !added[i] {
+			continue
+		}
+		o := opts[i]
+		if err := m.Unmount(o.Device, o.Path, 0, o.Timeout, o.Opts); err != nil {
+			m.logger.Warnf("Failed to roll back mount of %s at %s: %v", o.Device, o.Path, err)
+			errs[i] = fmt.Errorf("failed to roll back mount: %w", err)
+			merr.add(o.Path, errs[i])
+			continue
+		}
+		errs[i] = ErrBatchRolledBack
+	}
+}
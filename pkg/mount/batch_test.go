@@ -0,0 +1,104 @@
+package mount
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBatch(rollback bool) []MountOptions {
+	opts := make([]MountOptions, 5)
+	for i := range opts {
+		opts[i] = MountOptions{
+			Device:          "/dev/batch",
+			Path:            fmt.Sprintf("/mnt/batch%d", i),
+			Fs:              "ext4",
+			RollbackOnError: rollback,
+		}
+	}
+	return opts
+}
+
+func TestMountBatch(t *testing.T) {
+	m, _ := newTestMounter(t)
+	errs, err := m.MountBatch(newTestBatch(false))
+	require.NoError(t, err)
+	require.Equal(t, make([]error, 5), errs)
+	require.Equal(t, 5, m.HasMounts("/dev/batch"))
+}
+
+func TestMountBatchPartialFailure(t *testing.T) {
+	m, mi := newTestMounter(t)
+	mountErr := errors.New("mount failed")
+	mi.targetErrs["/mnt/batch2"] = mountErr
+
+	errs, err := m.MountBatch(newTestBatch(false))
+	require.Error(t, err)
+	require.Len(t, errs, 5)
+	for i, e := range errs {
+		if i == 2 {
+			require.True(t, errors.Is(e, mountErr))
+			continue
+		}
+		require.NoError(t, e)
+	}
+	require.Equal(t, 4, m.HasMounts("/dev/batch"))
+}
+
+func TestMountBatchRollback(t *testing.T) {
+	m, mi := newTestMounter(t)
+	mountErr := errors.New("mount failed")
+	mi.targetErrs["/mnt/batch2"] = mountErr
+
+	errs, err := m.MountBatch(newTestBatch(true))
+	require.Error(t, err)
+	require.True(t, errors.Is(err, mountErr))
+	require.Len(t, errs, 5)
+	require.Equal(t, ErrBatchRolledBack, errs[0])
+	require.Equal(t, ErrBatchRolledBack, errs[1])
+	require.True(t, errors.Is(errs[2], mountErr))
+	require.Equal(t, ErrBatchRolledBack, errs[3])
+	require.Equal(t, ErrBatchRolledBack, errs[4])
+
+	require.Equal(t, 0, m.HasMounts("/dev/batch"))
+	require.Equal(t, []string{"/mnt/batch1", "/mnt/batch0"}, mi.unmounted,
+		"Expected the first two mounts to be unmounted in reverse order")
+	require.Empty(t, mi.mounted)
+}
+
+func TestMountBatchRollbackKeepsExistingMounts(t *testing.T) {
+	m, mi := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/dev/batch", "/mnt/batch1", "ext4", 0, "", 0, nil))
+	mountErr := errors.New("mount failed")
+	mi.targetErrs["/mnt/batch2"] = mountErr
+
+	errs, err := m.MountBatch(newTestBatch(true))
+	require.True(t, errors.Is(err, mountErr))
+	require.Equal(t, ErrBatchRolledBack, errs[0])
+	require.NoError(t, errs[1], "Expected the existing mount to be left alone")
+	require.Equal(t, []string{"/mnt/batch0"}, mi.unmounted)
+	require.Equal(t, []string{"/mnt/batch1"}, m.Mounts("/dev/batch"))
+}
+
+func TestMountWithOptionsFailIfMounted(t *testing.T) {
+	m, mi := newTestMounter(t)
+	o := MountOptions{Device: "/dev/strict", Path: "/mnt/strict", Fs: "ext4"}
+	require.NoError(t, m.MountWithOptions(o))
+	calls := len(mi.calls)
+
+	// Mounting again is a no-op by default.
+	require.NoError(t, m.MountWithOptions(o))
+	require.Len(t, mi.calls, calls)
+
+	o.FailIfMounted = true
+	require.Equal(t, ErrAlreadyMounted, m.MountWithOptions(o))
+	require.Len(t, mi.calls, calls)
+	require.Equal(t, 1, m.HasMounts("/dev/strict"))
+
+	// A fresh path is mounted as usual.
+	o.Path = "/mnt/strict2"
+	require.NoError(t, m.MountWithOptions(o))
+	require.Equal(t, 2, m.HasMounts("/dev/strict"))
+}
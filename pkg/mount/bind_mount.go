@@ -1,5 +1,3 @@
-// +build linux
-
 package mount
 
 import (
@@ -12,6 +10,9 @@ import (
 
 const (
 	sharedMount = "shared"
+	// bindFs is the Fs of a source directory bind mounted with no
+	// filesystem given.
+	bindFs = "bind"
 )
 
 // bindMounter loads mounts that are bind mounted in the mount table
@@ -25,17 +26,17 @@ func NewBindMounter(
 	mountImpl MountImpl,
 	allowedDirs []string,
 	trashLocation string,
+	opts ...MounterOption,
 ) (*bindMounter, error) {
 	b := &bindMounter{
 		Mounter: Mounter{
 			mountImpl:     mountImpl,
-			mounts:        make(DeviceMap),
-			paths:         make(PathMap),
 			allowedDirs:   allowedDirs,
 			kl:            keylock.New(),
 			trashLocation: trashLocation,
 		},
 	}
+	b.setOptions(opts)
 	if err := b.Load(rootSubstrings); err != nil {
 		return nil, err
 	}
@@ -46,20 +47,48 @@ func (b *bindMounter) Reload(rootSubstring string) error {
 	newBm, err := NewBindMounter(
 		[]*regexp.Regexp{regexp.MustCompile(regexp.QuoteMeta(rootSubstring))},
 		b.mountImpl,
-		b.allowedDirs,
+		b.AllowedDirs(),
 		b.trashLocation,
 	)
 	if err != nil {
 		return err
 	}
 
-	return b.reload(rootSubstring, newBm.mounts[rootSubstring])
+	info, _ := newBm.lookup(rootSubstring)
+	return b.reload(rootSubstring, info)
 }
 
 func (b *bindMounter) Load(rootSubstrings []*regexp.Regexp) error {
 	return b.load(rootSubstrings, bindFindMountPoint)
 }
 
+// BindMountFrom bind mounts source, a tracked mountpoint, at target. The
+// target is tracked as another mountpoint of the device mounted at source,
+// sharing its Info. ErrEnoent is returned if source is not tracked.
+func (m *Mounter) BindMountFrom(source, target string, timeout int) error {
+	if msBind == 0 {
+		return ErrUnsupported
+	}
+	source = normalizeMountPath(source)
+	device, ok := m.HasTarget(source)
+	if !ok {
+		return ErrEnoent
+	}
+	return m.mount(0, source, device, target, "", msBind, "", timeout)
+}
+
+// isBindSource returns true if source is tracked as a bind mounted source
+// directory.
+func (m *Mounter) isBindSource(source string) bool {
+	info, ok := m.lookup(source)
+	if !ok {
+		return false
+	}
+	info.Lock()
+	defer info.Unlock()
+	return info.Fs == bindFs
+}
+
 func bindFindMountPoint(sInfo *mount.Info, destination *regexp.Regexp, infos []*mount.Info) (bool, string, string) {
 	for _, dInfo := range infos {
 		if !destination.MatchString(dInfo.Mountpoint) {
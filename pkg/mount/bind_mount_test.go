@@ -0,0 +1,71 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindMountFrom(t *testing.T) {
+	m, mi := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/dev/bindfrom", "/mnt/bindfrom", "ext4", 0, "", 0, nil))
+	info := m.testInfo("/dev/bindfrom")
+
+	require.NoError(t, m.BindMountFrom("/mnt/bindfrom/", "/mnt/bindfrom2", 0))
+	call := mi.lastCall()
+	require.Equal(t, "/mnt/bindfrom", call.source)
+	require.Equal(t, "/mnt/bindfrom2", call.target)
+	require.Equal(t, uintptr(syscall.MS_BIND), call.flags)
+
+	require.Equal(t, 2, m.HasMounts("/dev/bindfrom"))
+	require.True(t, info == m.testInfo("/dev/bindfrom"), "Expected the Info to be shared")
+	device, ok := m.HasTarget("/mnt/bindfrom2")
+	require.True(t, ok)
+	require.Equal(t, "/dev/bindfrom", device)
+
+	// Unmounting the source keeps the bind target tracked.
+	require.NoError(t, m.Unmount("/dev/bindfrom", "/mnt/bindfrom", 0, 0, nil))
+	require.Equal(t, []string{"/mnt/bindfrom2"}, m.Mounts("/dev/bindfrom"))
+
+	require.Equal(t, ErrEnoent, m.BindMountFrom("/mnt/untracked", "/mnt/bindfrom3", 0))
+	require.Equal(t, ErrEnoent, m.BindMountFrom("/mnt/bindfrom", "/mnt/bindfrom3", 0))
+}
+
+func TestBindMountSource(t *testing.T) {
+	notDevice := func(device string) error { return ErrDeviceNotFound }
+	m, mi := newTestMounter(t, withDeviceCheck(notDevice))
+	require.NoError(t, m.Mount(0, "/var/src", "/mnt/bind1", "", syscall.MS_BIND, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/var/src", "/mnt/bind2", "", syscall.MS_BIND, "", 0, nil))
+	require.Equal(t, 2, m.HasMounts("/var/src"))
+	require.Equal(t, bindFs, m.testInfo("/var/src").Fs)
+	require.NoError(t, m.Mount(0, "/var/src", "/mnt/bind1", "", syscall.MS_REMOUNT|syscall.MS_RDONLY, "", 0, nil),
+		"Expected a remount of a bind source not to check it as a device")
+	require.True(t, errors.Is(m.Mount(0, "/var/src", "/mnt/ext4", "ext4", 0, "", 0, nil), ErrDeviceNotFound),
+		"Expected a mount of a bind source to check it as a device")
+	m.checkDevice = nil
+	require.Equal(t, ErrEinval, m.Mount(0, "/var/src", "/mnt/ext4", "ext4", 0, "", 0, nil),
+		"Expected an ext4 mount not to attach to a bind source")
+	m.checkDevice = notDevice
+	require.Len(t, m.Mounts("/var/src"), 2)
+
+	require.NoError(t, m.Unmount("/var/src", "/mnt/bind1", 0, 0, nil))
+	require.Equal(t, []string{"/mnt/bind2"}, m.Mounts("/var/src"))
+	device, ok := m.HasTarget("/mnt/bind2")
+	require.True(t, ok)
+	require.Equal(t, "/var/src", device)
+
+	require.NoError(t, m.Unmount("/var/src", "/mnt/bind2", 0, 0, nil))
+	require.Equal(t, []string{"/mnt/bind1", "/mnt/bind2"}, mi.unmounted)
+	require.Equal(t, 0, m.HasMounts("/var/src"))
+	m.RLock()
+	_, ok = m.lookup("/var/src")
+	m.RUnlock()
+	require.False(t, ok, "Expected the bind source to be removed with its last target")
+	require.Error(t, m.Mount(0, "/var/src", "/mnt/bind1", "ext4", 0, "", 0, nil),
+		"Expected a mount of the untracked source to check it as a device")
+}
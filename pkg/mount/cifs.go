@@ -0,0 +1,239 @@
+package mount
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/pkg/mount"
+	"github.com/libopenstorage/openstorage/pkg/keylock"
+)
+
+const (
+	cifsType = "cifs"
+	// cifsRedacted replaces the password in the mount data kept in the table.
+	cifsRedacted = "********"
+)
+
+// CIFSOptions are the SMB client options of CIFSMount. Zero values are left
+// out of the mount data so that the kernel defaults apply.
+type CIFSOptions struct {
+	// Username is the user to authenticate as.
+	Username string
+	// Password is the password of Username. It is not recorded in the mount
+	// table and cannot be combined with CredentialsFile.
+	Password string
+	// Domain is the domain or workgroup of Username.
+	Domain string
+	// CredentialsFile is a file with username=, password= and domain= lines,
+	// as read by mount.cifs. Username and Domain take precedence over it.
+	CredentialsFile string
+	// Version is the SMB protocol version, e.g. 2.1, 3.0 or 3.1.1.
+	Version string
+	// Sec is the security mode, e.g. ntlmssp or krb5.
+	Sec string
+}
+
+var (
+	cifsVersions = map[string]bool{
+		"1.0": true, "2.0": true, "2.1": true, "3": true, "3.0": true, "3.02": true,
+		"3.1.1": true, "default": true,
+	}
+	cifsSecModes = map[string]bool{
+		"none": true, "krb5": true, "krb5i": true, "ntlm": true, "ntlmi": true,
+		"ntlmv2": true, "ntlmv2i": true, "ntlmssp": true, "ntlmsspi": true,
+	}
+)
+
+// CIFSManager is a Manager that mounts SMB shares.
+type CIFSManager interface {
+	Manager
+	// CIFSMount mounts share of the server at target with opts.
+	CIFSMount(share, target string, opts CIFSOptions, timeout int) error
+}
+
+// cifsMounter implements CIFSManager for the shares of a server.
+type cifsMounter struct {
+	server string
+	Mounter
+}
+
+// NewCIFSMounter returns a Mounter tracking the SMB shares of server mounted
+// with CIFS.
+func NewCIFSMounter(
+	server string,
+	mountImpl MountImpl,
+	allowedDirs []string,
+	opts ...MounterOption,
+) (CIFSManager, error) {
+	if server == "" || strings.ContainsAny(server, `/\`) {
+		return nil, fmt.Errorf("invalid CIFS server %q: %w", server, ErrEinval)
+	}
+	m := &cifsMounter{
+		server: server,
+		Mounter: Mounter{
+			mountImpl:   mountImpl,
+			allowedDirs: allowedDirs,
+			kl:          keylock.New(),
+		},
+	}
+	m.setOptions(opts)
+	if err := m.Load(nil); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Load loads the shares of the server from the mount table. The input value
+// is not used.
+func (m *cifsMounter) Load(source []*regexp.Regexp) error {
+	prefix := regexp.MustCompile("^" + regexp.QuoteMeta("//"+m.server+"/"))
+	return m.load([]*regexp.Regexp{prefix}, cifsFindMountPoint)
+}
+
+// Reload reloads the mount table for the specified share source.
+func (m *cifsMounter) Reload(source string) error {
+	newM, err := NewCIFSMounter(m.server, m.mountImpl, m.AllowedDirs())
+	if err != nil {
+		return err
+	}
+	info, _ := newM.(*cifsMounter).lookup(source)
+	return m.reload(source, info)
+}
+
+func cifsFindMountPoint(info *mount.Info, destination *regexp.Regexp, infos []*mount.Info) (bool, string, string) {
+	if (info.Fstype == cifsType || info.Fstype == "smb3") && destination.MatchString(info.Source) {
+		return true, info.Source, info.Source
+	}
+	return false, "", ""
+}
+
+// CIFSMount mounts share of the server at target. The mount is tracked under
+// the //server/share source reported by the mount table, with the password
+// left out of its data.
+func (m *cifsMounter) CIFSMount(share, target string, opts CIFSOptions, timeout int) error {
+	source, err := cifsSource(m.server, share)
+	if err != nil {
+		return err
+	}
+	data, err := opts.data()
+	if err != nil {
+		return err
+	}
+	return m.mount(0, source, source, target, cifsType, 0, data, timeout,
+		withRedactedData(redactCIFSData))
+}
+
+// cifsSource returns the //server/share source of a CIFS mount.
+func cifsSource(server, share string) (string, error) {
+	share = strings.TrimPrefix(share, "/")
+	if share == "" || strings.Contains(share, `\`) {
+		return "", fmt.Errorf("invalid CIFS share %q: %w", share, ErrEinval)
+	}
+	return "//" + server + "/" + share, nil
+}
+
+// data returns the validated CIFS mount data for o. The password comes last
+// so that redactCIFSData can drop it without parsing its escapes.
+func (o CIFSOptions) data() (string, error) {
+	if o.CredentialsFile != "" {
+		if o.Password != "" {
+			return "", fmt.Errorf("CIFS password and credentials file are exclusive: %w", ErrEinval)
+		}
+		creds, err := readCIFSCredentials(o.CredentialsFile)
+		if err != nil {
+			return "", err
+		}
+		if o.Username == "" {
+			o.Username = creds.Username
+		}
+		if o.Domain == "" {
+			o.Domain = creds.Domain
+		}
+		o.Password = creds.Password
+	}
+	var opts []string
+	for _, opt := range []struct {
+		name, value string
+	}{
+		{"username", o.Username},
+		{"domain", o.Domain},
+	} {
+		if strings.ContainsAny(opt.value, ",\n") {
+			return "", fmt.Errorf("CIFS option %s contains a comma or newline: %w", opt.name, ErrEinval)
+		}
+		if opt.value != "" {
+			opts = append(opts, opt.name+"="+opt.value)
+		}
+	}
+	if o.Version != "" {
+		if !cifsVersions[o.Version] {
+			return "", fmt.Errorf("unsupported CIFS version %q: %w", o.Version, ErrEinval)
+		}
+		opts = append(opts, "vers="+o.Version)
+	}
+	if o.Sec != "" {
+		if !cifsSecModes[o.Sec] {
+			return "", fmt.Errorf("unsupported CIFS security mode %q: %w", o.Sec, ErrEinval)
+		}
+		opts = append(opts, "sec="+o.Sec)
+	}
+	if o.Password != "" {
+		if strings.Contains(o.Password, "\n") {
+			return "", fmt.Errorf("CIFS password contains a newline: %w", ErrEinval)
+		}
+		// The kernel reads a doubled comma as a comma of the password.
+		opts = append(opts, "password="+strings.Replace(o.Password, ",", ",,", -1))
+	}
+	return strings.Join(opts, ","), nil
+}
+
+// readCIFSCredentials reads a mount.cifs credentials file. Errors do not
+// quote the file contents.
+func readCIFSCredentials(path string) (CIFSOptions, error) {
+	var creds CIFSOptions
+	f, err := os.Open(path)
+	if err != nil {
+		return creds, fmt.Errorf("failed to open CIFS credentials file: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return creds, fmt.Errorf("CIFS credentials file %s line %d: %w", path, n, ErrEinval)
+		}
+		value := line[i+1:]
+		switch strings.TrimSpace(line[:i]) {
+		case "username", "user":
+			creds.Username = value
+		case "password", "pass":
+			creds.Password = value
+		case "domain", "dom":
+			creds.Domain = value
+		default:
+			return creds, fmt.Errorf("CIFS credentials file %s line %d: %w", path, n, ErrEinval)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return creds, fmt.Errorf("failed to read CIFS credentials file %s: %w", path, err)
+	}
+	return creds, nil
+}
+
+// redactCIFSData returns data with the password replaced.
+func redactCIFSData(data string) string {
+	if strings.HasPrefix(data, "password=") {
+		return "password=" + cifsRedacted
+	}
+	if i := strings.Index(data, ",password="); i >= 0 {
+		return data[:i] + ",password=" + cifsRedacted
+	}
+	return data
+}
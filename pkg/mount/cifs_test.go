@@ -0,0 +1,134 @@
+package mount
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIFSOptionsData(t *testing.T) {
+	tests := []struct {
+		opts     CIFSOptions
+		expected string
+	}{
+		{opts: CIFSOptions{}, expected: ""},
+		{opts: CIFSOptions{Version: "3.1.1", Sec: "ntlmssp"}, expected: "vers=3.1.1,sec=ntlmssp"},
+		{
+			opts: CIFSOptions{
+				Username: "svc",
+				Password: "s3,cret",
+				Domain:   "CORP",
+				Version:  "3.0",
+				Sec:      "ntlmsspi",
+			},
+			expected: "username=svc,domain=CORP,vers=3.0,sec=ntlmsspi,password=s3,,cret",
+		},
+	}
+	for _, test := range tests {
+		data, err := test.opts.data()
+		require.NoError(t, err)
+		require.Equal(t, test.expected, data)
+	}
+
+	for _, opts := range []CIFSOptions{
+		{Version: "4.0"},
+		{Sec: "plain"},
+		{Username: "a,b"},
+		{Domain: "CORP\nsec=none"},
+		{Password: "a\nb"},
+		{Password: "secret", CredentialsFile: "/etc/creds"},
+	} {
+		_, err := opts.data()
+		require.True(t, errors.Is(err, ErrEinval), "Expected %+v to be rejected, got %v", opts, err)
+		require.NotContains(t, err.Error(), "secret")
+	}
+}
+
+func TestCIFSCredentialsFile(t *testing.T) {
+	creds := filepath.Join(t.TempDir(), "creds")
+	require.NoError(t, ioutil.WriteFile(creds,
+		[]byte("# smb credentials\nusername=svc\npassword=p=ss\ndomain=CORP\n"), 0600))
+
+	data, err := CIFSOptions{CredentialsFile: creds, Version: "3.0"}.data()
+	require.NoError(t, err)
+	require.Equal(t, "username=svc,domain=CORP,vers=3.0,password=p=ss", data)
+
+	// Inline options take precedence over the file.
+	data, err = CIFSOptions{CredentialsFile: creds, Username: "other"}.data()
+	require.NoError(t, err)
+	require.Equal(t, "username=other,domain=CORP,password=p=ss", data)
+
+	require.NoError(t, ioutil.WriteFile(creds, []byte("username=svc\ns3cret\n"), 0600))
+	_, err = CIFSOptions{CredentialsFile: creds}.data()
+	require.True(t, errors.Is(err, ErrEinval))
+	require.NotContains(t, err.Error(), "s3cret")
+
+	_, err = CIFSOptions{CredentialsFile: filepath.Join(t.TempDir(), "missing")}.data()
+	require.True(t, os.IsNotExist(errors.Unwrap(err)), "got %v", err)
+}
+
+func TestCIFSSource(t *testing.T) {
+	source, err := cifsSource("fileserver", "/share")
+	require.NoError(t, err)
+	require.Equal(t, "//fileserver/share", source)
+	source, err = cifsSource("10.0.0.1", "share/sub")
+	require.NoError(t, err)
+	require.Equal(t, "//10.0.0.1/share/sub", source)
+
+	_, err = cifsSource("fileserver", "")
+	require.True(t, errors.Is(err, ErrEinval))
+	_, err = cifsSource("fileserver", `share\sub`)
+	require.True(t, errors.Is(err, ErrEinval))
+	_, err = NewCIFSMounter("//fileserver", newTestMountImpl(), nil)
+	require.True(t, errors.Is(err, ErrEinval))
+}
+
+func TestRedactCIFSData(t *testing.T) {
+	require.Equal(t, "username=svc,password="+cifsRedacted,
+		redactCIFSData("username=svc,password=a,,b"))
+	require.Equal(t, "password="+cifsRedacted, redactCIFSData("password=x"))
+	require.Equal(t, "vers=3.0", redactCIFSData("vers=3.0"))
+}
+
+func TestCIFSMount(t *testing.T) {
+	hook := &logHook{}
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+	logger.AddHook(hook)
+	mi := newTestMountImpl()
+	m, err := NewCIFSMounter("fileserver", mi, nil, withFsOps(newTestFsOps()), WithLogger(logger))
+	require.NoError(t, err)
+	target := filepath.Join(t.TempDir(), "cifs")
+	require.NoError(t, os.Mkdir(target, 0755))
+
+	opts := CIFSOptions{Username: "svc", Password: "s3cret", Version: "3.0"}
+	require.NoError(t, m.CIFSMount("share", target, opts, 0))
+	call := mi.lastCall()
+	require.Equal(t, "//fileserver/share", call.source)
+	require.Equal(t, "cifs", call.fstype)
+	require.Equal(t, "username=svc,vers=3.0,password=s3cret", call.data)
+
+	paths := m.Inspect("//fileserver/share")
+	require.Len(t, paths, 1)
+	require.Equal(t, target, paths[0].Path)
+	require.Equal(t, "username=svc,vers=3.0,password="+cifsRedacted, paths[0].Data)
+	require.NotContains(t, m.(*cifsMounter).Dump(), "s3cret")
+
+	// Mounting again logs that the mountpoint exists.
+	require.NoError(t, m.CIFSMount("share", target, opts, 0))
+	require.NoError(t, m.Unmount("//fileserver/share", target, 0, 0, nil))
+	hook.Lock()
+	defer hook.Unlock()
+	require.NotEmpty(t, hook.entries)
+	for _, e := range hook.entries {
+		line, err := e.String()
+		require.NoError(t, err)
+		require.False(t, strings.Contains(line, "s3cret"), "Password logged: %s", line)
+	}
+}
@@ -0,0 +1,88 @@
+package mount
+
+import (
+	"time"
+
+	"github.com/libopenstorage/openstorage/pkg/sched"
+)
+
+// Clock is the source of the current time of a Mounter.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock reads the wall clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock sets the clock used for mount times and for scheduling delayed
+// path removals. It defaults to the wall clock.
+func WithClock(c Clock) MounterOption {
+	return func(m *Mounter) {
+		m.clock = c
+	}
+}
+
+// WithScheduler sets the scheduler running delayed path removals. It
+// defaults to sched.Instance() at the time of the removal.
+func WithScheduler(s sched.Scheduler) MounterOption {
+	return func(m *Mounter) {
+		m.scheduler = s
+	}
+}
+
+// schedule runs task once after the remove delay, unless the Mounter is
+// closed before.
+func (m *Mounter) schedule(task sched.ScheduleTask) error {
+	return m.scheduleAfter(task, m.removeDelay)
+}
+
+// scheduleAfter runs task once after delay, unless the Mounter is closed
+// before.
+func (m *Mounter) scheduleAfter(task sched.ScheduleTask, delay time.Duration) error {
+	t := &scheduledTask{}
+	m.Lock()
+	if m.closed {
+		m.Unlock()
+		return ErrClosed
+	}
+	if m.tasks == nil {
+		m.tasks = make(map[*scheduledTask]struct{})
+	}
+	m.tasks[t] = struct{}{}
+	m.Unlock()
+
+	id, err := m.getScheduler().Schedule(
+		func(i sched.Interval) {
+			m.Lock()
+			closed := m.closed
+			delete(m.tasks, t)
+			m.Unlock()
+			if !closed {
+				task(i)
+			}
+		},
+		sched.Periodic(time.Second),
+		m.clock.Now().Add(delay),
+		true /* run only once */)
+
+	m.Lock()
+	defer m.Unlock()
+	if err != nil {
+		delete(m.tasks, t)
+		return err
+	}
+	t.id = id
+	return nil
+}
+
+// getScheduler returns the scheduler of the Mounter.
+func (m *Mounter) getScheduler() sched.Scheduler {
+	if m.scheduler == nil {
+		return sched.Instance()
+	}
+	return m.scheduler
+}
@@ -0,0 +1,152 @@
+package mount
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libopenstorage/openstorage/pkg/options"
+	"github.com/libopenstorage/openstorage/pkg/sched"
+	"github.com/stretchr/testify/require"
+)
+
+// testClock is a clock that only moves when advanced.
+type testClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// testScheduler is a sched.Scheduler that runs tasks when its clock is
+// advanced past their run time.
+type testScheduler struct {
+	sync.Mutex
+	clock *testClock
+	tasks map[sched.TaskID]*testTask
+	next  sched.TaskID
+}
+
+type testTask struct {
+	task  sched.ScheduleTask
+	runAt time.Time
+}
+
+func newTestScheduler(clock *testClock) *testScheduler {
+	return &testScheduler{clock: clock, tasks: make(map[sched.TaskID]*testTask)}
+}
+
+func (s *testScheduler) Schedule(
+	task sched.ScheduleTask,
+	interval sched.Interval,
+	runAt time.Time,
+	onlyOnce bool,
+) (sched.TaskID, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.next++
+	s.tasks[s.next] = &testTask{task: task, runAt: runAt}
+	return s.next, nil
+}
+
+func (s *testScheduler) Cancel(id sched.TaskID) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.tasks, id)
+	return nil
+}
+
+func (s *testScheduler) Start() {}
+
+func (s *testScheduler) Stop() {}
+
+// Advance moves the clock forward by d and runs the tasks that are due.
+func (s *testScheduler) Advance(d time.Duration) {
+	s.clock.Advance(d)
+	now := s.clock.Now()
+	s.Lock()
+	var due []*testTask
+	for id, task := range s.tasks {
+		if !task.runAt.After(now) {
+			due = append(due, task)
+			delete(s.tasks, id)
+		}
+	}
+	s.Unlock()
+	for _, task := range due {
+		task.task(sched.Periodic(time.Second))
+	}
+}
+
+func (s *testScheduler) pending() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.tasks)
+}
+
+func TestRemoveMountPathFakeClock(t *testing.T) {
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	trash := t.TempDir()
+	m, err := NewDeviceMounter(nil, newTestMountImpl(), nil, trash,
+		withFsOps(newTestFsOps()), WithClock(clk), WithScheduler(s))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "mnt")
+	require.NoError(t, os.Mkdir(path, 0755))
+
+	opts := map[string]string{options.OptionsWaitBeforeDelete: "true"}
+	require.NoError(t, m.RemoveMountPath(path, opts))
+	require.Equal(t, 1, s.pending())
+
+	s.Advance(mountPathRemoveDelay - time.Second)
+	_, err = os.Stat(path)
+	require.NoError(t, err, "Expected %v to exist until the delay elapses", path)
+
+	s.Advance(time.Second)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err), "Expected %v to be removed after the delay", path)
+	require.Equal(t, 0, s.pending())
+}
+
+func TestRemoveMountPathRemountedDuringDelay(t *testing.T) {
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	trash := t.TempDir()
+	fsops := newTestFsOps()
+	m, err := NewDeviceMounter(nil, newTestMountImpl(), nil, trash,
+		withFsOps(fsops), withDeviceCheck(nil), WithClock(clk), WithScheduler(s))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "mnt")
+	require.NoError(t, os.Mkdir(path, 0755))
+
+	// The removal is requested with a trailing slash, which Mount drops.
+	opts := map[string]string{options.OptionsWaitBeforeDelete: "true"}
+	require.NoError(t, m.RemoveMountPath(path+"/", opts))
+	require.Equal(t, 1, s.pending())
+
+	s.Advance(mountPathRemoveDelay / 2)
+	require.NoError(t, m.Mount(0, "/dev/remount", path, "ext4", 0, "", 0, nil))
+
+	s.Advance(mountPathRemoveDelay)
+	require.Equal(t, 0, s.pending())
+	_, err = os.Stat(path)
+	require.NoError(t, err, "Expected %v to survive the scheduled removal", path)
+	require.True(t, fsops.IsImmutable(path), "Expected %v to stay immutable", path)
+	require.Equal(t, []string{path}, m.Mounts("/dev/remount"))
+}
@@ -0,0 +1,52 @@
+package mount
+
+import (
+	"github.com/libopenstorage/openstorage/pkg/sched"
+)
+
+// scheduledTask is a task scheduled by the Mounter that has not run yet.
+type scheduledTask struct {
+	id sched.TaskID
+}
+
+// Close cancels the pending path removals, which leave PendingRemovals, and
+// the usage checks of WithUsageMonitor, and makes Mount, Unmount,
+// RemoveMountPath and EmptyTrashDir return ErrClosed. Operations in progress
+// are not waited for. Closing a closed Mounter is a no-op.
+func (m *Mounter) Close() error {
+	m.Lock()
+	if m.closed {
+		m.Unlock()
+		return nil
+	}
+	m.closed = true
+	ids := make([]sched.TaskID, 0, len(m.tasks))
+	for t := range m.tasks {
+		// Tasks not scheduled yet check closed before running.
+		if sched.ValidTaskID(t.id) {
+			ids = append(ids, t.id)
+		}
+	}
+	m.tasks = nil
+	m.pendingRemovals = nil
+	m.Unlock()
+
+	s := m.getScheduler()
+	var firstErr error
+	for _, id := range ids {
+		if err := s.Cancel(id); err != nil {
+			m.logger.Warnf("Failed to cancel scheduled task %v: %v", id, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// isClosed returns true if the Mounter is closed.
+func (m *Mounter) isClosed() bool {
+	m.RLock()
+	defer m.RUnlock()
+	return m.closed
+}
@@ -0,0 +1,78 @@
+package mount
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/libopenstorage/openstorage/pkg/options"
+	"github.com/libopenstorage/openstorage/pkg/sched"
+	"github.com/stretchr/testify/require"
+)
+
+func newClosingMounter(t *testing.T, s sched.Scheduler, clk *testClock) (*deviceMounter, string) {
+	m, _ := newTestMounter(t, WithClock(clk), WithScheduler(s))
+	m.trashLocation = t.TempDir()
+	path := filepath.Join(t.TempDir(), "mnt")
+	require.NoError(t, os.Mkdir(path, 0755))
+	opts := map[string]string{options.OptionsWaitBeforeDelete: "true"}
+	require.NoError(t, m.RemoveMountPath(path, opts))
+	return m, path
+}
+
+func TestCloseCancelsRemovals(t *testing.T) {
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	m, path := newClosingMounter(t, s, clk)
+	require.Equal(t, 1, s.pending())
+
+	require.NoError(t, m.Close())
+	require.Equal(t, 0, s.pending(), "Expected the removal to be cancelled")
+	s.Advance(mountPathRemoveDelay)
+	_, err := os.Stat(path)
+	require.NoError(t, err, "Expected %v to be kept after Close", path)
+
+	require.NoError(t, m.Close(), "Closing twice must be a no-op")
+}
+
+// uncancellableScheduler ignores Cancel.
+type uncancellableScheduler struct {
+	*testScheduler
+}
+
+func (uncancellableScheduler) Cancel(sched.TaskID) error {
+	return nil
+}
+
+func TestCloseSkipsUncancelledRemovals(t *testing.T) {
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	m, path := newClosingMounter(t, uncancellableScheduler{s}, clk)
+
+	require.NoError(t, m.Close())
+	require.Equal(t, 1, s.pending())
+	s.Advance(mountPathRemoveDelay)
+	_, err := os.Stat(path)
+	require.NoError(t, err, "Expected %v to be kept after Close", path)
+}
+
+func TestCloseRejectsOperations(t *testing.T) {
+	m, mi := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/dev/closed", "/mnt/closed", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Close())
+
+	require.Equal(t, ErrClosed, m.Mount(0, "/dev/other", "/mnt/other", "ext4", 0, "", 0, nil))
+	require.Equal(t, ErrClosed, m.Unmount("/dev/closed", "/mnt/closed", 0, 0, nil))
+	require.Equal(t, ErrClosed, m.RemoveMountPath(t.TempDir(), nil))
+	require.Equal(t, ErrClosed, m.EmptyTrashDir())
+	require.Empty(t, mi.unmounted)
+
+	// Lookups still work.
+	require.Equal(t, 1, m.HasMounts("/dev/closed"))
+
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	m.scheduler = s
+	require.Equal(t, ErrClosed, m.schedule(func(sched.Interval) {}))
+	require.Equal(t, 0, s.pending())
+}
@@ -0,0 +1,263 @@
+package mount
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ManagerRoute routes the sources matching one of Sources to Manager.
+type ManagerRoute struct {
+	Sources []*regexp.Regexp
+	Manager Manager
+}
+
+// CompositeManager is a Manager routing calls to the Managers of several
+// mount types, such as a device and an NFS Manager. A call for a source goes
+// to the Manager tracking it, or to the first route matching it if none
+// does. A call for a path goes to the Manager tracking a mount at the path.
+type CompositeManager struct {
+	routes []ManagerRoute
+}
+
+var _ Manager = &CompositeManager{}
+
+// NewCompositeManager returns a CompositeManager for routes, in order of
+// preference.
+func NewCompositeManager(routes ...ManagerRoute) *CompositeManager {
+	return &CompositeManager{routes: routes}
+}
+
+// sourceManager returns the Manager of source, or nil if no route matches
+// it.
+func (c *CompositeManager) sourceManager(source string) Manager {
+	for _, r := range c.routes {
+		if r.Manager.HasMounts(source) > 0 {
+			return r.Manager
+		}
+	}
+	for _, r := range c.routes {
+		for _, re := range r.Sources {
+			if re.MatchString(source) {
+				return r.Manager
+			}
+		}
+	}
+	return nil
+}
+
+// pathManager returns the Manager tracking a mount at path, or nil if none
+// does.
+func (c *CompositeManager) pathManager(path string) Manager {
+	for _, r := range c.routes {
+		if _, ok := r.Manager.HasTarget(path); ok {
+			return r.Manager
+		}
+	}
+	return nil
+}
+
+// String joins the representations of the Managers.
+func (c *CompositeManager) String() string {
+	s := make([]string, len(c.routes))
+	for i, r := range c.routes {
+		s[i] = r.Manager.String()
+	}
+	return strings.Join(s, "\n")
+}
+
+// Reload reloads source in its Manager. ErrUnsupported is returned if no
+// route matches source.
+func (c *CompositeManager) Reload(source string) error {
+	m := c.sourceManager(source)
+	if m == nil {
+		return ErrUnsupported
+	}
+	return m.Reload(source)
+}
+
+// Load loads the mount table of every Manager. It stops at the first error.
+func (c *CompositeManager) Load(source []*regexp.Regexp) error {
+	for _, r := range c.routes {
+		if err := r.Manager.Load(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Inspect returns the mountpoints of source in its Manager.
+func (c *CompositeManager) Inspect(source string) []*PathInfo {
+	m := c.sourceManager(source)
+	if m == nil {
+		return nil
+	}
+	return m.Inspect(source)
+}
+
+// Mounts returns the paths of source in its Manager.
+func (c *CompositeManager) Mounts(source string) []string {
+	m := c.sourceManager(source)
+	if m == nil {
+		return nil
+	}
+	return m.Mounts(source)
+}
+
+// HasMounts returns the number of mounts of source in its Manager.
+func (c *CompositeManager) HasMounts(source string) int {
+	m := c.sourceManager(source)
+	if m == nil {
+		return 0
+	}
+	return m.HasMounts(source)
+}
+
+// HasTarget returns the source mounted at target in any of the Managers.
+func (c *CompositeManager) HasTarget(target string) (string, bool) {
+	for _, r := range c.routes {
+		if source, ok := r.Manager.HasTarget(target); ok {
+			return source, true
+		}
+	}
+	return "", false
+}
+
+// Exists returns true if source is mounted at path in its Manager.
+func (c *CompositeManager) Exists(source, path string) (bool, error) {
+	m := c.sourceManager(source)
+	if m == nil {
+		return false, ErrEnoent
+	}
+	return m.Exists(source, path)
+}
+
+// GetRootPath returns the root of the mount at mountPath in any of the
+// Managers.
+func (c *CompositeManager) GetRootPath(mountPath string) (string, error) {
+	m := c.pathManager(mountPath)
+	if m == nil {
+		return "", ErrEnoent
+	}
+	return m.GetRootPath(mountPath)
+}
+
+// GetSourcePath returns the source of the mount at mountPath in any of the
+// Managers.
+func (c *CompositeManager) GetSourcePath(mountPath string) (string, error) {
+	m := c.pathManager(mountPath)
+	if m == nil {
+		return "", ErrEnoent
+	}
+	return m.GetSourcePath(mountPath)
+}
+
+// GetSourcePaths returns the source paths of all the Managers.
+func (c *CompositeManager) GetSourcePaths() []string {
+	var paths []string
+	for _, r := range c.routes {
+		paths = append(paths, r.Manager.GetSourcePaths()...)
+	}
+	return paths
+}
+
+// Mount mounts device with its Manager. ErrUnsupported is returned if no
+// route matches device.
+func (c *CompositeManager) Mount(
+	minor int,
+	device string,
+	path string,
+	fs string,
+	flags uintptr,
+	data string,
+	timeout int,
+	opts map[string]string,
+) error {
+	m := c.sourceManager(mountDevice(device, opts))
+	if m == nil {
+		return ErrUnsupported
+	}
+	return m.Mount(minor, device, path, fs, flags, data, timeout, opts)
+}
+
+// Unmount unmounts source from path with the Manager of source, or of path
+// if source is not tracked. ErrEnoent is returned if neither is.
+func (c *CompositeManager) Unmount(source, path string, flags int, timeout int, opts map[string]string) error {
+	m := c.sourceManager(mountDevice(source, opts))
+	if m == nil || m.HasMounts(mountDevice(source, opts)) == 0 {
+		if pm := c.pathManager(path); pm != nil {
+			m = pm
+		}
+	}
+	if m == nil {
+		return ErrEnoent
+	}
+	return m.Unmount(source, path, flags, timeout, opts)
+}
+
+// RemoveMountPath removes path with the Manager tracking a mount at it, or
+// with the first Manager.
+func (c *CompositeManager) RemoveMountPath(path string, opts map[string]string) error {
+	m := c.pathManager(path)
+	if m == nil {
+		if len(c.routes) == 0 {
+			return ErrUnsupported
+		}
+		m = c.routes[0].Manager
+	}
+	return m.RemoveMountPath(path, opts)
+}
+
+// EmptyTrashDir empties the trash directory of every Manager. It stops at
+// the first error.
+func (c *CompositeManager) EmptyTrashDir() error {
+	for _, r := range c.routes {
+		if err := r.Manager.EmptyTrashDir(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateMountpath returns the first error of the Managers for path, as the
+// source a mount at path will be routed by is not known.
+func (c *CompositeManager) ValidateMountpath(path string) error {
+	for _, r := range c.routes {
+		if err := r.Manager.ValidateMountpath(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Usage returns the usage of the mount at path in any of the Managers.
+func (c *CompositeManager) Usage(path string) (MountUsage, error) {
+	m := c.pathManager(path)
+	if m == nil {
+		return MountUsage{}, ErrEnoent
+	}
+	return m.Usage(path)
+}
+
+// IsMountpoint returns true if path is a mountpoint in the kernel. It is
+// answered by the Manager tracking a mount at path, or by the first one.
+func (c *CompositeManager) IsMountpoint(path string) (bool, error) {
+	m := c.pathManager(path)
+	if m == nil {
+		if len(c.routes) == 0 {
+			return IsMountpoint(path)
+		}
+		m = c.routes[0].Manager
+	}
+	return m.IsMountpoint(path)
+}
+
+// Close closes every Manager and returns the first error.
+func (c *CompositeManager) Close() error {
+	var firstErr error
+	for _, r := range c.routes {
+		if err := r.Manager.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,62 @@
+package mount
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCompositeManager(t *testing.T) (*CompositeManager, *deviceMounter, *nfsMounter) {
+	dm, _ := newTestMounter(t)
+	// Without servers, the NFS Manager loads every mount of the host.
+	nm, err := NewNFSMounter([]*regexp.Regexp{regexp.MustCompile(`^server$`)}, newTestMountImpl(), nil, "",
+		withFsOps(newTestFsOps()))
+	require.NoError(t, err)
+	c := NewCompositeManager(
+		ManagerRoute{Sources: []*regexp.Regexp{regexp.MustCompile(`^/dev/`)}, Manager: dm},
+		ManagerRoute{Sources: []*regexp.Regexp{regexp.MustCompile(`^[^/]+:/`)}, Manager: nm},
+	)
+	return c, dm, nm.(*nfsMounter)
+}
+
+func TestCompositeManagerRouting(t *testing.T) {
+	c, dm, nm := newTestCompositeManager(t)
+
+	require.NoError(t, c.Mount(0, "/dev/sdb", "/mnt/dev", "ext4", 0, "", 0, nil))
+	require.NoError(t, c.Mount(0, "server:/export", "/mnt/nfs", "nfs", 0, "", 0, nil))
+	require.Equal(t, ErrUnsupported, c.Mount(0, "tmpfs", "/mnt/tmp", "tmpfs", 0, "", 0, nil))
+	require.Equal(t, 1, dm.HasMounts("/dev/sdb"))
+	require.Equal(t, 0, dm.HasMounts("server:/export"))
+	require.Equal(t, 1, nm.HasMounts("server:/export"))
+
+	require.Equal(t, 1, c.HasMounts("server:/export"))
+	require.Equal(t, []string{"/mnt/dev"}, c.Mounts("/dev/sdb"))
+	require.Len(t, c.Inspect("server:/export"), 1)
+	exists, err := c.Exists("server:/export", "/mnt/nfs")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.ElementsMatch(t, []string{"/dev/sdb", "server:/export"}, c.GetSourcePaths())
+
+	for path, source := range map[string]string{"/mnt/dev": "/dev/sdb", "/mnt/nfs": "server:/export"} {
+		s, ok := c.HasTarget(path)
+		require.True(t, ok, path)
+		require.Equal(t, source, s)
+		s, err := c.GetSourcePath(path)
+		require.NoError(t, err)
+		require.Equal(t, source, s)
+	}
+	_, ok := c.HasTarget("/mnt/none")
+	require.False(t, ok)
+	_, err = c.GetSourcePath("/mnt/none")
+	require.Equal(t, ErrEnoent, err)
+
+	require.NoError(t, c.Unmount("server:/export", "/mnt/nfs", 0, 0, nil))
+	require.Equal(t, 0, nm.HasMounts("server:/export"))
+	require.Equal(t, 1, dm.HasMounts("/dev/sdb"))
+	require.Equal(t, ErrEnoent, c.Unmount("/dev/sdc", "/mnt/none", 0, 0, nil))
+
+	require.NoError(t, c.Close())
+	require.Equal(t, ErrClosed, c.Mount(0, "/dev/sdb", "/mnt/dev2", "ext4", 0, "", 0, nil))
+	require.Equal(t, ErrClosed, nm.Mount(0, "server:/export", "/mnt/nfs", "nfs", 0, "", 0, nil))
+}
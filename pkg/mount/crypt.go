@@ -0,0 +1,80 @@
+package mount
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+)
+
+const (
+	// cryptNamePrefix prefixes the dm-crypt mapping names created by CryptMount.
+	cryptNamePrefix = "osd-crypt-"
+	cryptMapperDir  = "/dev/mapper"
+)
+
+// cryptDevices opens and closes dm-crypt mappings.
+type cryptDevices interface {
+	// Open maps the LUKS device under name using key.
+	Open(device, name string, key []byte) error
+	// Close removes the mapping name.
+	Close(name string) error
+}
+
+// withCryptDevices sets the dm-crypt implementation, used by tests.
+func withCryptDevices(crypt cryptDevices) MounterOption {
+	return func(m *Mounter) {
+		m.crypt = crypt
+	}
+}
+
+// cryptName returns the dm-crypt mapping name for device.
+func cryptName(device string) string {
+	sum := md5.Sum([]byte(device))
+	return cryptNamePrefix + hex.EncodeToString(sum[:])
+}
+
+// CryptMount opens the LUKS device with key and mounts the decrypted device
+// at target with filesystem fs. The mount is tracked under device and the
+// mapping is recorded in its Info, so that Unmount of the last mountpoint
+// closes it. key is zeroed before CryptMount returns.
+func (m *Mounter) CryptMount(device, target, fs string, key []byte, timeout int) error {
+	defer func() {
+		for i := range key {
+			key[i] = 0
+		}
+	}()
+
+	// The mapping is opened under the device lock of device, which Unmount
+	// takes to close it.
+	openCrypt := func(info *Info) (*lockedSource, error) {
+		if info.CryptName != "" {
+			return &lockedSource{devPath: filepath.Join(cryptMapperDir, info.CryptName)}, nil
+		}
+		name := cryptName(device)
+		if err := m.crypt.Open(device, name, key); err != nil {
+			return nil, fmt.Errorf("failed to open crypt device %s: %w", device, err)
+		}
+		return &lockedSource{
+			devPath: filepath.Join(cryptMapperDir, name),
+			record:  func(info *Info) { info.CryptName = name },
+			release: func() {
+				if e := m.crypt.Close(name); e != nil {
+					m.logger.Warnf("Failed to close crypt device %s: %v", name, e)
+				}
+			},
+		}, nil
+	}
+	return m.mount(0, "", device, target, fs, 0, "", timeout, withLockedSource(openCrypt))
+}
+
+// closeCrypt closes the dm-crypt mapping of info, if it has one.
+func (m *Mounter) closeCrypt(info *Info) error {
+	if info == nil || info.CryptName == "" {
+		return nil
+	}
+	if err := m.crypt.Close(info.CryptName); err != nil {
+		return fmt.Errorf("failed to close crypt device %s: %w", info.CryptName, err)
+	}
+	return nil
+}
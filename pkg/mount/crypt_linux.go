@@ -0,0 +1,35 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// defaultCryptDevices opens dm-crypt mappings with cryptsetup.
+var defaultCryptDevices cryptDevices = cryptsetup{}
+
+// cryptsetup implements cryptDevices by running the cryptsetup binary. The
+// key is passed on stdin so it does not show up in the process arguments.
+type cryptsetup struct{}
+
+func (cryptsetup) Open(device, name string, key []byte) error {
+	cmd := exec.Command("cryptsetup", "open", "--type", "luks", "--key-file", "-", device, name)
+	cmd.Stdin = bytes.NewReader(key)
+	return runCryptsetup(cmd)
+}
+
+func (cryptsetup) Close(name string) error {
+	return runCryptsetup(exec.Command("cryptsetup", "close", name))
+}
+
+func runCryptsetup(cmd *exec.Cmd) error {
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package mount
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// testCryptDevices is a cryptDevices that records mappings in memory.
+type testCryptDevices struct {
+	sync.Mutex
+	opened  map[string]string
+	keys    [][]byte
+	closed  []string
+	openErr error
+}
+
+func newTestCryptDevices() *testCryptDevices {
+	return &testCryptDevices{opened: make(map[string]string)}
+}
+
+func (f *testCryptDevices) Open(device, name string, key []byte) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.openErr != nil {
+		return f.openErr
+	}
+	f.keys = append(f.keys, append([]byte(nil), key...))
+	f.opened[name] = device
+	return nil
+}
+
+func (f *testCryptDevices) Close(name string) error {
+	f.Lock()
+	defer f.Unlock()
+	if _, ok := f.opened[name]; !ok {
+		return ErrEnoent
+	}
+	delete(f.opened, name)
+	f.closed = append(f.closed, name)
+	return nil
+}
+
+func TestCryptMount(t *testing.T) {
+	hook := &logHook{}
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.SetLevel(logrus.DebugLevel)
+	logger.AddHook(hook)
+
+	crypt := newTestCryptDevices()
+	m, mi := newTestMounter(t, withCryptDevices(crypt), WithLogger(logger))
+	target := filepath.Join(t.TempDir(), "mnt")
+	require.NoError(t, os.Mkdir(target, 0755))
+
+	const secret = "s3cr3t-passphrase"
+	key := []byte(secret)
+	require.NoError(t, m.CryptMount("/dev/sdz", target, "ext4", key, 0))
+	require.Equal(t, make([]byte, len(secret)), key, "Expected the key to be zeroed")
+	require.Equal(t, [][]byte{[]byte(secret)}, crypt.keys)
+
+	name := cryptName("/dev/sdz")
+	require.Equal(t, "/dev/mapper/"+name, mi.lastCall().source)
+	require.Equal(t, name, m.testInfo("/dev/sdz").CryptName)
+
+	require.NoError(t, m.Unmount("/dev/sdz", target, 0, 0, nil))
+	require.Equal(t, []string{name}, crypt.closed)
+	require.Empty(t, crypt.opened)
+
+	for _, e := range hook.entries {
+		s, err := e.String()
+		require.NoError(t, err)
+		require.False(t, strings.Contains(s, secret), "Key leaked into log: %s", s)
+	}
+}
+
+func TestCryptMountFailure(t *testing.T) {
+	crypt := newTestCryptDevices()
+	m, mi := newTestMounter(t, withCryptDevices(crypt))
+	target := t.TempDir()
+
+	// A failed mount closes the mapping again.
+	mi.mountErr = errors.New("wrong fs type")
+	key := []byte("key")
+	require.Error(t, m.CryptMount("/dev/sdz", target, "ext4", key, 0))
+	require.Equal(t, []string{cryptName("/dev/sdz")}, crypt.closed)
+	require.True(t, bytes.Equal(make([]byte, 3), key), "Expected the key to be zeroed")
+
+	// A failed open does not mount.
+	mi.mountErr = nil
+	crypt.openErr = errors.New("no key available")
+	key = []byte("key")
+	err := m.CryptMount("/dev/sdz", target, "ext4", key, 0)
+	require.True(t, errors.Is(err, crypt.openErr), "Unexpected error %v", err)
+	require.Len(t, mi.calls, 1)
+	require.True(t, bytes.Equal(make([]byte, 3), key), "Expected the key to be zeroed")
+}
+
+func TestCryptMountImmediateUnmount(t *testing.T) {
+	crypt := newTestCryptDevices()
+	o := &unmountObserver{}
+	m, _ := newTestMounter(t, withCryptDevices(crypt), WithObservers(o))
+	o.m = &m.Mounter
+
+	// The last Unmount closes the mapping as soon as the mount is visible.
+	require.NoError(t, m.CryptMount("/dev/luks", "/mnt/luks", "ext4", []byte("key"), 0))
+	require.Equal(t, []error{nil}, o.errs)
+	require.Empty(t, crypt.opened, "Expected the mapping to be closed")
+	require.Equal(t, []string{cryptName("/dev/luks")}, crypt.closed)
+}
@@ -2,6 +2,7 @@ package mount
 
 import (
 	"regexp"
+	"sync"
 
 	"github.com/libopenstorage/openstorage/pkg/keylock"
 )
@@ -18,8 +19,9 @@ type CustomMounter func() (CustomLoad, CustomReload)
 // CustomMounterHandler implements the Mounter interface
 type CustomMounterHandler struct {
 	Mounter
-	cl CustomLoad
-	cr CustomReload
+	cl       CustomLoad
+	cr       CustomReload
+	dispatch *fsDispatcher
 }
 
 // NewCustomMounter returns a new CustomMounter
@@ -28,17 +30,19 @@ func NewCustomMounter(
 	mountImpl MountImpl,
 	customMounter CustomMounter,
 	allowedDirs []string,
+	opts ...MounterOption,
 ) (*CustomMounterHandler, error) {
 
+	dispatch := newFsDispatcher(mountImpl)
 	m := &CustomMounterHandler{
 		Mounter: Mounter{
-			mountImpl:   mountImpl,
-			mounts:      make(DeviceMap),
-			paths:       make(PathMap),
+			mountImpl:   dispatch,
 			allowedDirs: allowedDirs,
 			kl:          keylock.New(),
 		},
 	}
+	m.dispatch = dispatch
+	m.setOptions(opts)
 	cl, cr := customMounter()
 	m.cl = cl
 	m.cr = cr
@@ -51,10 +55,163 @@ func NewCustomMounter(
 
 // Load mount table
 func (c *CustomMounterHandler) Load(devRegexes []*regexp.Regexp) error {
-	return c.cl(devRegexes, c.mounts, c.paths)
+	return c.updateTable(func(mounts DeviceMap, paths PathMap) error {
+		return c.cl(devRegexes, mounts, paths)
+	})
 }
 
 // Reload mount table for a device
 func (c *CustomMounterHandler) Reload(device string) error {
-	return c.cr(device, c.mounts, c.paths)
+	return c.updateTable(func(mounts DeviceMap, paths PathMap) error {
+		return c.cr(device, mounts, paths)
+	})
+}
+
+// RegisterFsMounter registers mountImpl to mount and unmount filesystems of
+// type fstype. Once a handler is registered, Mount and Unmount of any other
+// filesystem type return ErrUnsupported instead of using the MountImpl
+// passed to NewCustomMounter, except for bind mounts and the other calls
+// with no filesystem type, which still use it.
+func (c *CustomMounterHandler) RegisterFsMounter(fstype string, mountImpl MountImpl) {
+	c.dispatch.register(fstype, mountImpl)
+}
+
+// Mount mounts device at path with the handler registered for fs.
+func (c *CustomMounterHandler) Mount(
+	minor int,
+	device, path, fs string,
+	flags uintptr,
+	data string,
+	timeout int,
+	opts map[string]string,
+) error {
+	if _, err := c.dispatch.handler(fs); err != nil {
+		return err
+	}
+	return c.Mounter.Mount(minor, device, path, fs, flags, data, timeout, opts)
+}
+
+// Unmount unmounts device from path with the handler registered for the
+// filesystem device was mounted with.
+func (c *CustomMounterHandler) Unmount(
+	device, path string,
+	flags int,
+	timeout int,
+	opts map[string]string,
+) error {
+	info, ok := c.lookup(device)
+	if ok {
+		info.Lock()
+		fs := info.Fs
+		info.Unlock()
+		if _, err := c.dispatch.handler(fs); err != nil {
+			return err
+		}
+		// Mounts loaded from the mount table were not mounted through the
+		// dispatcher, which does not know their filesystem type.
+		c.dispatch.addTarget(normalizeMountPath(path), fs)
+	}
+	return c.Mounter.Unmount(device, path, flags, timeout, opts)
+}
+
+// fsDispatcher is a MountImpl that passes calls to the MountImpl registered
+// for the filesystem type. Unmount has no filesystem type argument, it is
+// looked up from the targets mounted, so that the unmounts made by the
+// Mounter itself, such as rollbacks, go to the same MountImpl as the mount.
+type fsDispatcher struct {
+	sync.Mutex
+	base MountImpl
+	// fuse handles FUSE filesystems when base is the DefaultMounter, which
+	// cannot unmount them without privileges.
+	fuse     MountImpl
+	handlers map[string]MountImpl
+	targets  map[string]string
+}
+
+func newFsDispatcher(base MountImpl) *fsDispatcher {
+	d := &fsDispatcher{
+		base:     base,
+		handlers: make(map[string]MountImpl),
+		targets:  make(map[string]string),
+	}
+	if _, ok := base.(*DefaultMounter); ok {
+		d.fuse = NewFuseMounter(base)
+	}
+	return d
+}
+
+func (d *fsDispatcher) register(fstype string, mountImpl MountImpl) {
+	d.Lock()
+	defer d.Unlock()
+	d.handlers[fstype] = mountImpl
+}
+
+// handler returns the MountImpl for fstype.
+func (d *fsDispatcher) handler(fstype string) (MountImpl, error) {
+	d.Lock()
+	defer d.Unlock()
+	if len(d.handlers) == 0 {
+		if d.fuse != nil && isFuseType(fstype) {
+			return d.fuse, nil
+		}
+		return d.base, nil
+	}
+	h, ok := d.handlers[fstype]
+	if !ok {
+		// Bind mounts and the unmounts of paths not mounted with a
+		// handler have no filesystem type of their own.
+		if fstype == "" || fstype == bindFs {
+			return d.base, nil
+		}
+		return nil, ErrUnsupported
+	}
+	return h, nil
+}
+
+// addTarget records the filesystem type of target for Unmount, unless it is
+// recorded already.
+func (d *fsDispatcher) addTarget(target, fstype string) {
+	d.Lock()
+	defer d.Unlock()
+	if _, ok := d.targets[target]; !ok && fstype != "" {
+		d.targets[target] = fstype
+	}
+}
+
+func (d *fsDispatcher) Mount(
+	source string,
+	target string,
+	fstype string,
+	flags uintptr,
+	data string,
+	timeout int,
+) error {
+	h, err := d.handler(fstype)
+	if err != nil {
+		return err
+	}
+	if err := h.Mount(source, target, fstype, flags, data, timeout); err != nil {
+		return err
+	}
+	if flags&msRemount == 0 {
+		d.addTarget(target, fstype)
+	}
+	return nil
+}
+
+func (d *fsDispatcher) Unmount(target string, flags int, timeout int) error {
+	d.Lock()
+	fstype := d.targets[target]
+	d.Unlock()
+	h, err := d.handler(fstype)
+	if err != nil {
+		return err
+	}
+	if err := h.Unmount(target, flags, timeout); err != nil {
+		return err
+	}
+	d.Lock()
+	delete(d.targets, target)
+	d.Unlock()
+	return nil
 }
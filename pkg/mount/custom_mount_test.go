@@ -0,0 +1,87 @@
+package mount
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCustomMounter(t *testing.T, mountImpl MountImpl) *CustomMounterHandler {
+	customMounter := func() (CustomLoad, CustomReload) {
+		load := func([]*regexp.Regexp, DeviceMap, PathMap) error { return nil }
+		reload := func(string, DeviceMap, PathMap) error { return nil }
+		return load, reload
+	}
+	m, err := NewCustomMounter(nil, mountImpl, customMounter, nil, withFsOps(newTestFsOps()))
+	require.NoError(t, err)
+	return m
+}
+
+func TestCustomMounterDispatch(t *testing.T) {
+	base := newTestMountImpl()
+	ceph := newTestMountImpl()
+	gluster := newTestMountImpl()
+	m := newTestCustomMounter(t, base)
+	m.RegisterFsMounter("ceph", ceph)
+	m.RegisterFsMounter("glusterfs", gluster)
+
+	dir := t.TempDir()
+	cephPath := filepath.Join(dir, "ceph")
+	glusterPath := filepath.Join(dir, "gluster")
+	require.NoError(t, os.Mkdir(cephPath, 0755))
+	require.NoError(t, os.Mkdir(glusterPath, 0755))
+
+	require.NoError(t, m.Mount(0, "mon:/vol", cephPath, "ceph", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "srv:/vol", glusterPath, "glusterfs", 0, "", 0, nil))
+	require.Equal(t, "mon:/vol", ceph.mounted[cephPath])
+	require.Equal(t, "srv:/vol", gluster.mounted[glusterPath])
+	require.Empty(t, base.calls)
+
+	require.NoError(t, m.Unmount("srv:/vol", glusterPath+"/", 0, 0, nil))
+	require.Equal(t, []string{glusterPath}, gluster.unmounted)
+	require.Empty(t, ceph.unmounted)
+	require.NoError(t, m.Unmount("mon:/vol", cephPath, 0, 0, nil))
+	require.Equal(t, []string{cephPath}, ceph.unmounted)
+
+	require.Equal(t, ErrUnsupported, m.Mount(0, "bucket", cephPath, "s3fs", 0, "", 0, nil))
+	require.Empty(t, base.calls)
+	require.Equal(t, 0, m.HasMounts("bucket"))
+}
+
+func TestCustomMounterWithoutRegistry(t *testing.T) {
+	base := newTestMountImpl()
+	m := newTestCustomMounter(t, base)
+	path := t.TempDir()
+
+	require.NoError(t, m.Mount(0, "bucket", path, "s3fs", 0, "", 0, nil))
+	require.Equal(t, "bucket", base.mounted[path])
+	require.NoError(t, m.Unmount("bucket", path, 0, 0, nil))
+	require.Equal(t, []string{path}, base.unmounted)
+}
+
+func TestCustomMounterBindAndInternalUnmount(t *testing.T) {
+	base := newTestMountImpl()
+	ceph := newTestMountImpl()
+	m := newTestCustomMounter(t, base)
+	m.RegisterFsMounter("ceph", ceph)
+	dir := t.TempDir()
+	cephPath := filepath.Join(dir, "ceph")
+	bindPath := filepath.Join(dir, "bind")
+	require.NoError(t, os.Mkdir(cephPath, 0755))
+	require.NoError(t, os.Mkdir(bindPath, 0755))
+
+	// Bind mounts have no filesystem type and use the base MountImpl.
+	require.NoError(t, m.Mount(0, dir, bindPath, "", msBind, "", 0, nil))
+	require.Equal(t, dir, base.mounted[bindPath])
+	require.NoError(t, m.Unmount(dir, bindPath, 0, 0, nil))
+	require.Equal(t, []string{bindPath}, base.unmounted)
+
+	// Unmounts made by the Mounter itself go to the handler of the mount.
+	require.NoError(t, m.Mount(0, "mon:/vol", cephPath, "ceph", 0, "", 0, nil))
+	require.NoError(t, m.Mounter.Unmount("mon:/vol", cephPath, 0, 0, nil))
+	require.Equal(t, []string{cephPath}, ceph.unmounted)
+	require.Equal(t, []string{bindPath}, base.unmounted)
+}
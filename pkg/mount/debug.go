@@ -0,0 +1,68 @@
+package mount
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// DebugMountsPath is the path DebugHandler is meant to be registered at.
+const DebugMountsPath = "/debug/mounts"
+
+// debugEntry is the JSON form of a MountEntry served by DebugHandler.
+type debugEntry struct {
+	Source string  `json:"source"`
+	Device string  `json:"device"`
+	Minor  int     `json:"minor"`
+	Fs     string  `json:"fs,omitempty"`
+	Root   string  `json:"root,omitempty"`
+	Path   string  `json:"path"`
+	Flags  uintptr `json:"flags"`
+	// Options are the Flags rendered by FlagsToString.
+	Options string `json:"options"`
+	// RefCount is the number of mountpoints of the source.
+	RefCount  int        `json:"refCount"`
+	ReadOnly  bool       `json:"readOnly"`
+	MountedAt *time.Time `json:"mountedAt,omitempty"`
+}
+
+// DebugHandler returns a handler serving the List of mountpoints as JSON,
+// to be registered at DebugMountsPath.
+func (m *Mounter) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		list := m.List()
+		refs := make(map[string]int)
+		for _, e := range list {
+			refs[e.Source]++
+		}
+		entries := make([]debugEntry, 0, len(list))
+		for _, e := range list {
+			d := debugEntry{
+				Source:   e.Source,
+				Device:   e.Device,
+				Minor:    e.Minor,
+				Fs:       e.Fs,
+				Root:     e.Root,
+				Path:     e.Path,
+				Flags:    e.Flags,
+				Options:  FlagsToString(e.Flags),
+				RefCount: refs[e.Source],
+				ReadOnly: e.ReadOnly,
+			}
+			if !e.MountedAt.IsZero() {
+				mountedAt := e.MountedAt
+				d.MountedAt = &mountedAt
+			}
+			entries = append(entries, d)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			m.logger.Warnf("Failed to write the mount table: %v", err)
+		}
+	})
+}
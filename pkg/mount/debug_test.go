@@ -0,0 +1,69 @@
+package mount
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func getDebugMounts(t *testing.T, srv *httptest.Server) []debugEntry {
+	resp, err := http.Get(srv.URL + DebugMountsPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var entries []debugEntry
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&entries))
+	return entries
+}
+
+func TestDebugHandler(t *testing.T) {
+	clk := newTestClock()
+	m, _ := newTestMounter(t, WithClock(clk))
+	mux := http.NewServeMux()
+	mux.Handle(DebugMountsPath, m.DebugHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	require.Empty(t, getDebugMounts(t, srv))
+
+	require.NoError(t, m.Mount(2, "/dev/debug", "/mnt/debug/a", "ext4", msRdonly, "", 0, nil))
+	require.NoError(t, m.Mount(2, "/dev/debug", "/mnt/debug/b", "ext4", 0, "", 0, nil))
+	entries := getDebugMounts(t, srv)
+	require.Len(t, entries, 2)
+	mountedAt := clk.Now()
+	require.True(t, mountedAt.Equal(*entries[0].MountedAt))
+	entries[0].MountedAt, entries[1].MountedAt = nil, nil
+	require.Equal(t, []debugEntry{
+		{Source: "/dev/debug", Device: "/dev/debug", Minor: 2, Fs: "ext4", Path: "/mnt/debug/a",
+			Flags: msRdonly, Options: "ro", RefCount: 2, ReadOnly: true},
+		{Source: "/dev/debug", Device: "/dev/debug", Minor: 2, Fs: "ext4", Path: "/mnt/debug/b",
+			Options: "rw", RefCount: 2},
+	}, entries)
+
+	require.NoError(t, m.Unmount("/dev/debug", "/mnt/debug/a", 0, 0, nil))
+	entries = getDebugMounts(t, srv)
+	require.Len(t, entries, 1)
+	require.Equal(t, "/mnt/debug/b", entries[0].Path)
+	require.Equal(t, 1, entries[0].RefCount)
+
+	resp, err := http.Post(srv.URL+DebugMountsPath, "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestDebugHandlerLoaded(t *testing.T) {
+	m := newTestTable()
+	rec := httptest.NewRecorder()
+	m.DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, DebugMountsPath, nil))
+	var entries []debugEntry
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	require.Len(t, entries, 3)
+	for _, e := range entries {
+		require.Nil(t, e.MountedAt, "Loaded mounts have no mount time")
+	}
+}
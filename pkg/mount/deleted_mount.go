@@ -1,13 +1,9 @@
-// +build linux
-
 package mount
 
 import (
 	"fmt"
 	"regexp"
 	"strings"
-
-	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -31,7 +27,7 @@ func NewDeletedMounter(
 		return nil, err
 	}
 	deletedMounts := make(DeviceMap)
-	for k, v := range devMounter.mounts {
+	for k, v := range devMounter.devices() {
 		if matchDeleted(rootSubstring, k) {
 			deletedMounts[k] = v
 		} else {
@@ -42,7 +38,9 @@ func NewDeletedMounter(
 			}
 		}
 	}
-	devMounter.mounts = deletedMounts
+	devMounter.Lock()
+	devMounter.setTableLocked(deletedMounts, nil)
+	devMounter.Unlock()
 	return &deletedMounter{deviceMounter: devMounter}, nil
 }
 
@@ -80,17 +78,18 @@ func (m *deletedMounter) Unmount(
 	defer m.Unlock()
 
 	failedUnmounts := make(DeviceMap)
-	for k, v := range m.mounts {
+	mounts, _ := m.tableLocked()
+	for k, v := range mounts {
 		for _, p := range v.Mountpoint {
-			logrus.Warnf("Unmounting deleted mount path %v->%v", k, p)
+			m.logger.Warnf("Unmounting deleted mount path %v->%v", k, p)
 			if err := m.mountImpl.Unmount(p.Path, flags, timeout); err != nil {
-				logrus.Warnf("Failed to unmount mount path %v->%v", k, p)
+				m.logger.Warnf("Failed to unmount mount path %v->%v", k, p)
 				addMountpoint(failedUnmounts, k, p)
 			}
 		}
 	}
-	m.mounts = failedUnmounts
-	if len(m.mounts) > 0 {
+	m.setTableLocked(failedUnmounts, nil)
+	if len(failedUnmounts) > 0 {
 		return fmt.Errorf("Not all paths could be unmounted")
 	}
 
@@ -98,20 +97,20 @@ func (m *deletedMounter) Unmount(
 }
 
 func (m *deletedMounter) Mounts(sourcePath string) []string {
-	m.Lock()
-	defer m.Unlock()
+	m.RLock()
+	defer m.RUnlock()
 
 	if sourcePath != AllDevices {
-		logrus.Warnf("DeletedMounter accepts only %v as sourcePath",
+		m.logger.Warnf("DeletedMounter accepts only %v as sourcePath",
 			AllDevices)
 		return nil
 	}
 	paths := make([]string, 0)
-	for _, v := range m.mounts {
+	m.eachDeviceLocked(func(device string, v *Info) {
 		for _, p := range v.Mountpoint {
 			paths = append(paths, p.Path)
 		}
-	}
+	})
 
 	return paths
 }
@@ -0,0 +1,98 @@
+package mount
+
+import (
+	"fmt"
+	"sort"
+)
+
+// addDeps records that the mount at path is backed by the directories dirs,
+// so that it is unmounted before the mounts holding them.
+func (m *Mounter) addDeps(path string, dirs []string) {
+	path = normalizeMountPath(path)
+	backing := make([]string, len(dirs))
+	for i, dir := range dirs {
+		backing[i] = normalizeMountPath(dir)
+	}
+	m.Lock()
+	defer m.Unlock()
+	if m.deps == nil {
+		m.deps = make(map[string][]string)
+	}
+	m.deps[path] = backing
+}
+
+// UnmountRespectingDeps unmounts the tracked mounts at and under dir. A mount
+// is unmounted before the mounts it depends on: the ones it is mounted under,
+// and for an overlay the ones holding its lower, upper and work directories,
+// which would be busy otherwise. It goes on after a failure and returns a
+// MultiError of the paths that failed.
+func (m *Mounter) UnmountRespectingDeps(dir string, flags, timeout int) error {
+	dir = normalizeMountPath(dir)
+	var merr MultiError
+	for _, path := range m.teardownOrder(dir) {
+		source, ok := m.HasTarget(path)
+		if !ok {
+			continue
+		}
+		if err := m.Unmount(source, path, flags, timeout, nil); err != nil {
+			merr.add(path, fmt.Errorf("failed to unmount under %s: %w", dir, err))
+		}
+	}
+	return merr.errOrNil()
+}
+
+// teardownOrder returns the tracked paths at and under dir, each one after
+// the paths depending on it.
+func (m *Mounter) teardownOrder(dir string) []string {
+	m.RLock()
+	var paths []string
+	m.eachTargetLocked(func(p string, sources []string) {
+		if isWithin(dir, p) {
+			paths = append(paths, p)
+		}
+	})
+	deps := make(map[string][]string, len(m.deps))
+	for p, backing := range m.deps {
+		deps[p] = backing
+	}
+	m.RUnlock()
+	// Deepest paths first, so that the order is stable and children come
+	// first among the paths with no other dependency.
+	sort.Slice(paths, func(i, j int) bool {
+		if len(paths[i]) != len(paths[j]) {
+			return len(paths[i]) > len(paths[j])
+		}
+		return paths[i] < paths[j]
+	})
+	// dependsOn returns true if the mount at a must go before the one at b.
+	dependsOn := func(a, b string) bool {
+		if a != b && isWithin(b, a) {
+			return true
+		}
+		for _, d := range deps[a] {
+			if isWithin(b, d) {
+				return true
+			}
+		}
+		return false
+	}
+	order := make([]string, 0, len(paths))
+	visited := make(map[string]bool, len(paths))
+	var visit func(p string)
+	visit = func(p string) {
+		if visited[p] {
+			return
+		}
+		visited[p] = true
+		for _, q := range paths {
+			if q != p && dependsOn(q, p) {
+				visit(q)
+			}
+		}
+		order = append(order, p)
+	}
+	for _, p := range paths {
+		visit(p)
+	}
+	return order
+}
@@ -0,0 +1,61 @@
+package mount
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmountRespectingDeps(t *testing.T) {
+	m, mi := newTestMounter(t)
+	dir := t.TempDir()
+	lowerMnt := filepath.Join(dir, "lowermnt")
+	upperMnt := filepath.Join(dir, "uppermnt")
+	target := filepath.Join(dir, "o")
+	require.NoError(t, m.Mount(0, "/dev/lower", lowerMnt, "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/dev/upper", upperMnt, "ext4", 0, "", 0, nil))
+	for _, d := range []string{"layer", "upper", "work"} {
+		mnt := lowerMnt
+		if d != "layer" {
+			mnt = upperMnt
+		}
+		require.NoError(t, os.MkdirAll(filepath.Join(mnt, d), 0755))
+	}
+	require.NoError(t, os.Mkdir(target, 0755))
+	require.NoError(t, m.OverlayMount(target, []string{filepath.Join(lowerMnt, "layer")},
+		filepath.Join(upperMnt, "upper"), filepath.Join(upperMnt, "work"), 0))
+	child := filepath.Join(target, "child")
+	require.NoError(t, m.Mount(0, "/dev/child", child, "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/dev/other", "/mnt/other", "ext4", 0, "", 0, nil))
+
+	require.NoError(t, m.UnmountRespectingDeps(dir, 0, 0))
+	require.Equal(t, []string{child, target, lowerMnt, upperMnt}, mi.unmounted)
+	require.Equal(t, 0, m.HasMounts(OverlayDevice))
+	require.Equal(t, 0, m.HasMounts("/dev/lower"))
+	require.Equal(t, []string{"/mnt/other"}, m.Mounts("/dev/other"))
+	require.Empty(t, m.deps)
+}
+
+func TestUnmountRespectingDepsErrors(t *testing.T) {
+	m, mi := newTestMounter(t)
+	dir := t.TempDir()
+	lowerMnt := filepath.Join(dir, "lowermnt")
+	target := filepath.Join(dir, "o")
+	require.NoError(t, m.Mount(0, "/dev/lower", lowerMnt, "ext4", 0, "", 0, nil))
+	require.NoError(t, os.MkdirAll(filepath.Join(lowerMnt, "layer"), 0755))
+	require.NoError(t, os.Mkdir(target, 0755))
+	require.NoError(t, m.OverlayMount(target, []string{filepath.Join(lowerMnt, "layer")}, "", "", 0))
+	mi.unmountErr = ErrEinval
+
+	err := m.UnmountRespectingDeps(dir, 0, 0)
+	var merr *MultiError
+	require.ErrorAs(t, err, &merr)
+	require.Len(t, merr.Errors, 2)
+	require.Equal(t, target, merr.Errors[0].Path)
+	require.Equal(t, lowerMnt, merr.Errors[1].Path)
+	require.True(t, errors.Is(err, ErrEinval))
+	require.Equal(t, []string{target}, m.Mounts(OverlayDevice))
+}
@@ -0,0 +1,55 @@
+package mount
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	osdexec "github.com/libopenstorage/openstorage/pkg/exec"
+)
+
+// blkidExitNotFound is the exit status of blkid when no filesystem is found.
+const blkidExitNotFound = 2
+
+// blkid returns the filesystem type of device reported by blkid, or an
+// empty string if there is none. It is replaced by tests.
+var blkid = func(device string) (string, error) {
+	out, err := exec.Command(osdexec.Which("blkid"), "-p", "-s", "TYPE", "-o", "value", device).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == blkidExitNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("blkid %s: %w", device, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// DetectFs returns the filesystem type on device, e.g. ext4 or xfs. It
+// returns ErrNoFilesystem if the device is not formatted.
+func DetectFs(device string) (string, error) {
+	fs, err := blkid(device)
+	if err != nil {
+		return "", err
+	}
+	if fs == "" {
+		return "", fmt.Errorf("%s: %w", device, ErrNoFilesystem)
+	}
+	return fs, nil
+}
+
+// deviceFs returns the filesystem of device recorded in the table if it is
+// mounted, or the one found on the device otherwise.
+func (m *Mounter) deviceFs(device string) (string, error) {
+	info, ok := m.lookup(device)
+	if ok {
+		info.Lock()
+		fs := info.Fs
+		info.Unlock()
+		if fs != "" {
+			return fs, nil
+		}
+	}
+	return DetectFs(device)
+}
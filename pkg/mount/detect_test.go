@@ -0,0 +1,62 @@
+package mount
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setTestBlkid makes blkid report the filesystems in types.
+func setTestBlkid(t *testing.T, types map[string]string) *int {
+	calls := 0
+	orig := blkid
+	blkid = func(device string) (string, error) {
+		calls++
+		return types[device], nil
+	}
+	t.Cleanup(func() { blkid = orig })
+	return &calls
+}
+
+func TestDetectFs(t *testing.T) {
+	setTestBlkid(t, map[string]string{"/dev/sda": "ext4", "/dev/sdb": "xfs"})
+
+	fs, err := DetectFs("/dev/sda")
+	require.NoError(t, err)
+	require.Equal(t, "ext4", fs)
+	fs, err = DetectFs("/dev/sdb")
+	require.NoError(t, err)
+	require.Equal(t, "xfs", fs)
+	_, err = DetectFs("/dev/sdc")
+	require.True(t, errors.Is(err, ErrNoFilesystem), "got %v", err)
+
+	errBlkid := errors.New("blkid failed")
+	blkid = func(string) (string, error) { return "", errBlkid }
+	_, err = DetectFs("/dev/sda")
+	require.Equal(t, errBlkid, err)
+}
+
+func TestDeviceMountDetectsFs(t *testing.T) {
+	calls := setTestBlkid(t, map[string]string{"/dev/sda": "ext4", "/dev/sdb": "xfs"})
+	m, mi := newTestMounter(t)
+
+	require.NoError(t, m.Mount(0, "/dev/sda", "/mnt/sda", "", 0, "", 0, nil))
+	require.Equal(t, "ext4", mi.lastCall().fstype)
+	require.NoError(t, m.Mount(0, "/dev/sdb", "/mnt/sdb", "", 0, "", 0, nil))
+	require.Equal(t, "xfs", mi.lastCall().fstype)
+	require.Equal(t, 2, *calls)
+
+	// The filesystem of a mounted device is taken from the table.
+	require.NoError(t, m.Mount(0, "/dev/sda", "/mnt/sda2", "", 0, "", 0, nil))
+	require.Equal(t, "ext4", mi.lastCall().fstype)
+	require.Equal(t, 2, *calls)
+
+	err := m.Mount(0, "/dev/sdc", "/mnt/sdc", "", 0, "", 0, nil)
+	require.True(t, errors.Is(err, ErrNoFilesystem), "got %v", err)
+	require.Equal(t, 0, m.HasMounts("/dev/sdc"))
+
+	// An explicit filesystem is not detected.
+	require.NoError(t, m.Mount(0, "/dev/sdc", "/mnt/sdc", "ext4", 0, "", 0, nil))
+	require.Equal(t, 3, *calls)
+}
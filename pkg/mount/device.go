@@ -1,5 +1,3 @@
-// +build linux
-
 package mount
 
 import (
@@ -10,6 +8,7 @@ import (
 
 	"github.com/docker/docker/pkg/mount"
 	"github.com/libopenstorage/openstorage/pkg/keylock"
+	"github.com/libopenstorage/openstorage/pkg/options"
 )
 
 // deviceMounter implements Ops and tracks active mounts for volume drivers.
@@ -23,18 +22,19 @@ func NewDeviceMounter(
 	mountImpl MountImpl,
 	allowedDirs []string,
 	trashLocation string,
+	opts ...MounterOption,
 ) (*deviceMounter, error) {
 
 	m := &deviceMounter{
 		Mounter: Mounter{
 			mountImpl:     mountImpl,
-			mounts:        make(DeviceMap),
-			paths:         make(PathMap),
 			allowedDirs:   allowedDirs,
 			kl:            keylock.New(),
 			trashLocation: trashLocation,
 		},
 	}
+	m.prepareMount = m.prepareDeviceMount
+	m.setOptions(opts)
 	err := m.Load(devRegexes)
 	if err != nil {
 		return nil, err
@@ -49,18 +49,93 @@ func NewDeviceMounter(
 	return m, nil
 }
 
+// Mount validates that devPath is a block device and mounts it at path, as
+// MountWithOptions does.
+func (m *deviceMounter) Mount(
+	minor int,
+	devPath, path, fs string,
+	flags uintptr,
+	data string,
+	timeout int,
+	opts map[string]string,
+) error {
+	return m.MountWithOptions(MountOptions{
+		Minor:   minor,
+		Device:  devPath,
+		Path:    path,
+		Fs:      fs,
+		Flags:   flags,
+		Data:    data,
+		Timeout: timeout,
+		Opts:    opts,
+	})
+}
+
+// prepareDeviceMount validates that o.Device is a block device before it is
+// mounted by Mount, MountWithOptions or MountBatch. Fuse mounts and bind
+// mounts are not validated. If o.Fs is empty, it is the filesystem found on
+// the device, unless it is to be formatted. A device of the form
+// UUID=<uuid> or LABEL=<label> is resolved with ResolveDevice; the mount is
+// tracked under the resolved device, with the tag as its SourceID. Unmount
+// and the lookups by source accept either.
+func (m *deviceMounter) prepareDeviceMount(o *MountOptions) ([]mountOption, error) {
+	_, fuse := o.Opts[options.OptionsDeviceFuseMount]
+	var call []mountOption
+	if tag, value, ok := parseDeviceTag(o.Device); ok && !fuse {
+		resolved, err := ResolveDevice(o.Device)
+		if err != nil {
+			return nil, newMountError(OpMount, o.Device, o.Path, o.Fs, err)
+		}
+		call = append(call, withSourceID(tag+"="+value))
+		o.Device = resolved
+	}
+	// A bind mount source is a directory and not a block device. Only bind
+	// mounts and remounts may use one.
+	bind := isBindMount(o.Flags) || (o.Flags&msRemount != 0 && m.isBindSource(o.Device))
+	if !fuse && !bind && m.checkDevice != nil {
+		if err := m.checkDevice(o.Device); err != nil {
+			return nil, newMountError(OpMount, o.Device, o.Path, o.Fs, err)
+		}
+	}
+	if o.Fs == "" && o.FormatIfEmpty == nil && !fuse && !isBindMount(o.Flags) && o.Flags&msRemount == 0 {
+		fs, err := m.deviceFs(o.Device)
+		if err != nil {
+			return nil, newMountError(OpMount, o.Device, o.Path, fs, err)
+		}
+		o.Fs = fs
+	}
+	return call, nil
+}
+
+// checkBlockDevice returns ErrDeviceNotFound if device does not exist and
+// ErrNotBlockDevice if it is not a block device.
+func checkBlockDevice(device string) error {
+	fi, err := os.Stat(device)
+	if os.IsNotExist(err) {
+		return ErrDeviceNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeDevice == 0 || fi.Mode()&os.ModeCharDevice != 0 {
+		return ErrNotBlockDevice
+	}
+	return nil
+}
+
 // Reload reloads the mount table
 func (m *deviceMounter) Reload(device string) error {
 	newDm, err := NewDeviceMounter([]*regexp.Regexp{regexp.MustCompile(regexp.QuoteMeta(device))},
 		m.mountImpl,
-		m.Mounter.allowedDirs,
+		m.Mounter.AllowedDirs(),
 		m.trashLocation,
 	)
 	if err != nil {
 		return err
 	}
 
-	return m.reload(device, newDm.mounts[device])
+	info, _ := newDm.lookup(device)
+	return m.reload(device, info)
 }
 
 // Load mount table
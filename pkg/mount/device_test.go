@@ -1,11 +1,22 @@
+//go:build linux
+// +build linux
+
 package mount
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/pkg/mount"
+	"github.com/libopenstorage/openstorage/pkg/options"
 	"github.com/stretchr/testify/require"
 )
 
@@ -231,6 +242,83 @@ func testMountsAPI(t *testing.T, device string, expectedMounts []string, dm Mana
 	}
 }
 
+func TestDeviceMounterValidatesDevice(t *testing.T) {
+	mi := newTestMountImpl()
+	m, err := NewDeviceMounter(nil, mi, nil, "", withFsOps(newTestFsOps()))
+	require.NoError(t, err)
+	dir := t.TempDir()
+	target := filepath.Join(dir, "mnt")
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0644))
+	err = m.Mount(0, file, target, "ext4", 0, "", 0, nil)
+	require.True(t, errors.Is(err, ErrNotBlockDevice), "got %v", err)
+
+	err = m.Mount(0, filepath.Join(dir, "missing"), target, "ext4", 0, "", 0, nil)
+	require.True(t, errors.Is(err, ErrDeviceNotFound), "got %v", err)
+
+	err = m.Mount(0, "/dev/null", target, "ext4", 0, "", 0, nil)
+	require.True(t, errors.Is(err, ErrNotBlockDevice), "Expected a character device to be rejected: %v", err)
+	require.Empty(t, mi.calls, "Invalid devices must not be mounted")
+	require.Empty(t, m.GetSourcePaths())
+
+	// Fuse mounts are not validated.
+	opts := map[string]string{options.OptionsDeviceFuseMount: "fuse-device"}
+	require.NoError(t, m.Mount(0, "fuse", target, "fuse", 0, "", 0, opts))
+	require.Equal(t, 1, m.HasMounts("fuse-device"))
+}
+
+func TestDeviceMounterMountWithOptionsValidatesDevice(t *testing.T) {
+	mi := newTestMountImpl()
+	m, err := NewDeviceMounter(nil, mi, nil, "", withFsOps(newTestFsOps()))
+	require.NoError(t, err)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0644))
+
+	o := MountOptions{Device: file, Path: filepath.Join(dir, "mnt"), Fs: "ext4"}
+	err = m.MountWithOptions(o)
+	require.True(t, errors.Is(err, ErrNotBlockDevice), "got %v", err)
+	errs, err := m.MountBatch([]MountOptions{o})
+	require.True(t, errors.Is(err, ErrNotBlockDevice), "got %v", err)
+	require.True(t, errors.Is(errs[0], ErrNotBlockDevice), "got %v", errs[0])
+	require.Empty(t, mi.calls, "Invalid devices must not be mounted")
+	require.Empty(t, m.GetSourcePaths())
+}
+
+func TestDeviceMounterMountWithOptionsResolvesDevice(t *testing.T) {
+	setDeviceTags(t, map[string]string{"UUID=1234-abcd": "/dev/sdb1"})
+	setTestBlkid(t, map[string]string{"/dev/sdb1": "xfs"})
+	m, mi := newTestMounter(t)
+
+	require.NoError(t, m.MountWithOptions(MountOptions{Device: "UUID=1234-abcd", Path: "/mnt/uuid"}))
+	require.Equal(t, "/dev/sdb1", mi.lastCall().source)
+	require.Equal(t, "xfs", mi.lastCall().fstype)
+	require.Equal(t, "UUID=1234-abcd", m.testInfo("/dev/sdb1").SourceID)
+	require.Equal(t, []string{"/mnt/uuid"}, m.Mounts("UUID=1234-abcd"))
+}
+
+func TestDeviceMounterLoopDevice(t *testing.T) {
+	if _, err := os.Stat(loopControlPath); err != nil {
+		t.Skipf("%s not available: %v", loopControlPath, err)
+	}
+	image := filepath.Join(t.TempDir(), "disk.img")
+	require.NoError(t, ioutil.WriteFile(image, make([]byte, 1<<20), 0644))
+	device, err := ioctlLoopDevices{}.Attach(image, true)
+	if os.IsPermission(err) {
+		t.Skipf("Cannot attach loop devices: %v", err)
+	}
+	require.NoError(t, err)
+	defer ioctlLoopDevices{}.Detach(device)
+
+	mi := newTestMountImpl()
+	m, err := NewDeviceMounter(nil, mi, nil, "", withFsOps(newTestFsOps()))
+	require.NoError(t, err)
+	target := filepath.Join(t.TempDir(), "mnt")
+	require.NoError(t, m.Mount(0, device, target, "ext4", 0, "", 0, nil))
+	require.Equal(t, device, mi.mounted[target])
+}
+
 func testInspect(t *testing.T, device string, expectedMounts []string, dm Manager) {
 	paths := dm.Inspect(device)
 	require.Equal(t, len(expectedMounts), len(paths), "Unexpected number of mounts")
@@ -256,3 +344,148 @@ func testGetSourcePath(t *testing.T, expectedSourcePath, mountPath string, dm Ma
 	require.EqualError(t, ErrEnoent, err.Error(), "Expected an ErrEnoent from GetSourcePath")
 	require.Equal(t, "", sourcePath, "Unexpected sourcePath from GetSourcePath")
 }
+
+// loadFixture is a mountinfo with several mounts of a few devices.
+const loadFixture = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+30 22 252:0 / /var/lib/osd/mounts/vol1 rw,relatime shared:2 - ext4 /dev/pxd/pxd1 rw
+31 22 252:0 / /var/lib/kubelet/pods/a/vol1 rw,relatime shared:2 - ext4 /dev/pxd/pxd1 rw
+32 22 252:16 / /var/lib/osd/mounts/vol2 ro,relatime shared:3 - xfs /dev/pxd/pxd2 ro
+33 22 252:32 / /var/lib/osd/mounts/vol3 rw,relatime shared:4 - ext4 /dev/pxd/pxd3 rw
+34 22 0:45 / /sys/fs/cgroup rw,nosuid shared:5 - cgroup2 cgroup2 rw
+`
+
+// setLoadFixture makes the mount table infos and returns the number of times
+// it is read.
+func setLoadFixture(t *testing.T, infos []*mount.Info) *int {
+	reads := 0
+	orig := mountTable
+	mountTable = func() ([]*mount.Info, error) {
+		reads++
+		return infos, nil
+	}
+	t.Cleanup(func() { mountTable = orig })
+	return &reads
+}
+
+func TestLoadMany(t *testing.T) {
+	infos, err := parseInfoFile(strings.NewReader(loadFixture))
+	require.NoError(t, err)
+	reads := setLoadFixture(t, infos)
+
+	m, err := NewDeviceMounter([]*regexp.Regexp{
+		regexp.MustCompile(regexp.QuoteMeta("/dev/pxd/pxd1")),
+		regexp.MustCompile(regexp.QuoteMeta("/dev/pxd/pxd2")),
+		regexp.MustCompile(regexp.QuoteMeta("/dev/pxd/pxd4")),
+	}, newTestMountImpl(), nil, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, *reads, "Expected the mount table to be read once")
+
+	require.ElementsMatch(t, []string{"/dev/pxd/pxd1", "/dev/pxd/pxd2"}, m.GetSourcePaths())
+	require.ElementsMatch(t,
+		[]string{"/var/lib/osd/mounts/vol1", "/var/lib/kubelet/pods/a/vol1"}, m.Mounts("/dev/pxd/pxd1"))
+	require.Equal(t, []string{"/var/lib/osd/mounts/vol2"}, m.Mounts("/dev/pxd/pxd2"))
+	minor, err := m.GetMinor("/dev/pxd/pxd2")
+	require.NoError(t, err)
+	require.Equal(t, 16, minor)
+	require.True(t, m.Inspect("/dev/pxd/pxd2")[0].ReadOnly)
+	source, err := m.GetSourcePath("/var/lib/kubelet/pods/a/vol1")
+	require.NoError(t, err)
+	require.Equal(t, "/dev/pxd/pxd1", source)
+	_, ok := m.HasTarget("/var/lib/osd/mounts/vol3")
+	require.False(t, ok)
+
+	// Loading again does not duplicate the mountpoints.
+	require.NoError(t, m.Load([]*regexp.Regexp{regexp.MustCompile(regexp.QuoteMeta("/dev/pxd/pxd1"))}))
+	require.Equal(t, 2, m.HasMounts("/dev/pxd/pxd1"))
+}
+
+// benchLoadTable returns a mountinfo with two mounts of each of devices and
+// the identifiers of the devices.
+func benchLoadTable(devices int) (string, []*regexp.Regexp) {
+	var table strings.Builder
+	ids := make([]*regexp.Regexp, 0, devices)
+	for i := 0; i < devices; i++ {
+		device := fmt.Sprintf("/dev/pxd/pxd%d", i)
+		for j, dir := range []string{"/var/lib/osd/mounts", "/var/lib/kubelet/pods"} {
+			fmt.Fprintf(&table, "%d 22 252:%d / %s/vol%d rw,relatime shared:%d - ext4 %s rw\n",
+				100+2*i+j, i, dir, i, i+2, device)
+		}
+		ids = append(ids, regexp.MustCompile(regexp.QuoteMeta(device)))
+	}
+	return table.String(), ids
+}
+
+func BenchmarkLoad(b *testing.B) {
+	orig := mountTable
+	defer func() { mountTable = orig }()
+	for _, devices := range []int{10, 100} {
+		table, ids := benchLoadTable(devices)
+		// Parse the mountinfo on every read like GetMounts.
+		mountTable = func() ([]*mount.Info, error) {
+			return parseInfoFile(strings.NewReader(table))
+		}
+		b.Run(fmt.Sprintf("per-source/devices=%d", devices), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m, _ := NewDeviceMounter(nil, newTestMountImpl(), nil, "")
+				for _, id := range ids {
+					if err := m.Load([]*regexp.Regexp{id}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("single-pass/devices=%d", devices), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m, _ := NewDeviceMounter(nil, newTestMountImpl(), nil, "")
+				if err := m.Load(ids); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestReloadKeepsSurvivingMounts(t *testing.T) {
+	clock := newTestClock()
+	m, _ := newTestMounter(t, WithClock(clock))
+	device := "/dev/pxd/pxd1"
+	require.NoError(t, m.Mount(0, device, "/var/lib/osd/mounts/vol1", "ext4", syscall.MS_NOATIME, "discard", 0, nil))
+	require.NoError(t, m.Mount(0, device, "/var/lib/kubelet/pods/a/vol1", "ext4", 0, "", 0, nil))
+	info := m.testInfo(device)
+	kept := m.Inspect(device)[0]
+	require.Equal(t, "/var/lib/osd/mounts/vol1", kept.Path)
+
+	// The kubelet mount vanished and another one appeared outside the
+	// Mounter.
+	setLoadFixture(t, []*mount.Info{
+		{Source: device, Mountpoint: "/var/lib/osd/mounts/vol1", Fstype: "ext4", Root: "/"},
+		{Source: device, Mountpoint: "/mnt/external", Fstype: "ext4", Root: "/", Opts: "ro"},
+		{Source: "/dev/pxd/pxd2", Mountpoint: "/mnt/other", Fstype: "ext4", Root: "/"},
+	})
+	clock.Advance(time.Hour)
+	require.NoError(t, m.Reload(device))
+
+	require.ElementsMatch(t, []string{"/var/lib/osd/mounts/vol1", "/mnt/external"}, m.Mounts(device))
+	require.True(t, info == m.testInfo(device), "Expected the Info to be kept")
+	paths := m.Inspect(device)
+	require.True(t, kept == paths[0], "Expected the surviving entry to be kept")
+	require.Equal(t, uintptr(syscall.MS_NOATIME), paths[0].Flags)
+	require.Equal(t, "discard", paths[0].Data)
+	require.Equal(t, clock.Now().Add(-time.Hour), paths[0].MountedAt)
+	require.True(t, paths[1].ReadOnly)
+	require.True(t, paths[1].MountedAt.IsZero())
+
+	_, ok := m.HasTarget("/var/lib/kubelet/pods/a/vol1")
+	require.False(t, ok)
+	source, err := m.GetSourcePath("/mnt/external")
+	require.NoError(t, err)
+	require.Equal(t, device, source)
+	require.NotContains(t, m.GetSourcePaths(), "/dev/pxd/pxd2")
+
+	// A device with no mounts left is removed.
+	setLoadFixture(t, nil)
+	require.NoError(t, m.Reload(device))
+	require.Equal(t, 0, m.HasMounts(device))
+	_, err = m.GetSourcePath("/mnt/external")
+	require.Equal(t, ErrEnoent, err)
+}
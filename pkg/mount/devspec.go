@@ -0,0 +1,129 @@
+package mount
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	osdexec "github.com/libopenstorage/openstorage/pkg/exec"
+)
+
+// deviceTags are the fstab forms of a device given by its filesystem, with
+// the /dev/disk directory of their udev symlinks.
+var deviceTags = map[string]string{
+	"UUID":  "/dev/disk/by-uuid",
+	"LABEL": "/dev/disk/by-label",
+}
+
+// resolveDeviceTag returns the device node of the filesystem with tag, UUID
+// or LABEL, set to value. It follows the udev symlinks and falls back to
+// blkid. It is replaced by tests.
+var resolveDeviceTag = func(tag, value string) (string, error) {
+	link := filepath.Join(deviceTags[tag], udevEncode(value))
+	if dev, err := filepath.EvalSymlinks(link); err == nil {
+		return dev, nil
+	}
+	out, err := exec.Command(osdexec.Which("blkid"), "-l", "-o", "device", "-t", tag+"="+value).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == blkidExitNotFound {
+		return "", ErrDeviceNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("blkid %s=%s: %w", tag, value, err)
+	}
+	dev := strings.TrimSpace(string(out))
+	if dev == "" {
+		return "", ErrDeviceNotFound
+	}
+	return dev, nil
+}
+
+// parseDeviceTag splits spec of the form UUID=<uuid> or LABEL=<label>. The
+// value may be quoted as in fstab.
+func parseDeviceTag(spec string) (string, string, bool) {
+	i := strings.IndexByte(spec, '=')
+	if i < 0 {
+		return "", "", false
+	}
+	tag := spec[:i]
+	if _, ok := deviceTags[tag]; !ok {
+		return "", "", false
+	}
+	value := strings.Trim(spec[i+1:], `"`)
+	if value == "" {
+		return "", "", false
+	}
+	return tag, value, true
+}
+
+// ResolveDevice returns the device node spec stands for. A spec of the form
+// UUID=<uuid> or LABEL=<label> is resolved to the device of the filesystem
+// with that UUID or label, or ErrDeviceNotFound if there is none. Any other
+// spec is returned as is.
+func ResolveDevice(spec string) (string, error) {
+	tag, value, ok := parseDeviceTag(spec)
+	if !ok {
+		return spec, nil
+	}
+	dev, err := resolveDeviceTag(tag, value)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", spec, err)
+	}
+	return dev, nil
+}
+
+// trackedSource returns the device tracked for source, which is either the
+// device or the SourceID it was mounted by. source is returned as is if it
+// is neither.
+func (m *Mounter) trackedSource(source string) string {
+	m.RLock()
+	defer m.RUnlock()
+	return m.trackedSourceLocked(source)
+}
+
+// trackedSourceLocked is trackedSource with the Mounter lock held.
+func (m *Mounter) trackedSourceLocked(source string) string {
+	if _, ok := m.lookupLocked(source); ok {
+		return source
+	}
+	tag, value, ok := parseDeviceTag(source)
+	if !ok {
+		return source
+	}
+	sourceID := tag + "=" + value
+	tracked := source
+	found := false
+	m.eachDeviceLocked(func(device string, info *Info) {
+		if found {
+			return
+		}
+		info.Lock()
+		id := info.SourceID
+		info.Unlock()
+		if id == sourceID {
+			tracked, found = device, true
+		}
+	})
+	return tracked
+}
+
+// udevEncode escapes s as udev does in the names of /dev/disk symlinks:
+// bytes other than alphanumerics, #+-.:=@_ and multibyte UTF-8 characters
+// are written as \xNN.
+func udevEncode(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			strings.ContainsRune("#+-.:=@_", r),
+			r >= utf8.RuneSelf && r != utf8.RuneError:
+			b.WriteRune(r)
+		default:
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,104 @@
+package mount
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setDeviceTags makes resolveDeviceTag resolve the tags in devices, keyed
+// by tag and value as in UUID=1234.
+func setDeviceTags(t *testing.T, devices map[string]string) {
+	orig := resolveDeviceTag
+	resolveDeviceTag = func(tag, value string) (string, error) {
+		if dev, ok := devices[tag+"="+value]; ok {
+			return dev, nil
+		}
+		return "", ErrDeviceNotFound
+	}
+	t.Cleanup(func() { resolveDeviceTag = orig })
+}
+
+func TestResolveDevice(t *testing.T) {
+	setDeviceTags(t, map[string]string{
+		"UUID=1234-abcd": "/dev/sdb1",
+		"LABEL=data":     "/dev/sdc",
+	})
+	for spec, dev := range map[string]string{
+		"UUID=1234-abcd":   "/dev/sdb1",
+		`UUID="1234-abcd"`: "/dev/sdb1",
+		"LABEL=data":       "/dev/sdc",
+		"/dev/sdd":         "/dev/sdd",
+		"PARTUUID=5678":    "PARTUUID=5678",
+	} {
+		resolved, err := ResolveDevice(spec)
+		require.NoError(t, err, spec)
+		require.Equal(t, dev, resolved, spec)
+	}
+	_, err := ResolveDevice("LABEL=missing")
+	require.True(t, errors.Is(err, ErrDeviceNotFound))
+}
+
+func TestMountDeviceTag(t *testing.T) {
+	setDeviceTags(t, map[string]string{
+		"UUID=1234-abcd": "/dev/sdb1",
+		"LABEL=data":     "/dev/sdc",
+	})
+	m, mi := newTestMounter(t)
+
+	for spec, dev := range map[string]string{
+		"UUID=1234-abcd": "/dev/sdb1",
+		"LABEL=data":     "/dev/sdc",
+	} {
+		path := "/mnt/" + dev[len("/dev/"):]
+		require.NoError(t, m.Mount(0, spec, path, "ext4", 0, "", 0, nil), spec)
+		require.Equal(t, dev, mi.lastCall().source, "Expected the resolved device to be mounted")
+		source, ok := m.HasTarget(path)
+		require.True(t, ok)
+		require.Equal(t, dev, source, "Expected the mount to be tracked under the resolved device")
+		require.Equal(t, spec, m.testInfo(dev).SourceID)
+	}
+
+	err := m.Mount(0, "LABEL=missing", "/mnt/missing", "ext4", 0, "", 0, nil)
+	require.True(t, errors.Is(err, ErrDeviceNotFound))
+	require.Len(t, mi.calls, 2)
+}
+
+func TestUdevEncode(t *testing.T) {
+	require.Equal(t, "my\\x20data\\x2fdisk", udevEncode("my data/disk"))
+	require.Equal(t, "Data_1.0-a#b+c:d=e@f", udevEncode("Data_1.0-a#b+c:d=e@f"))
+	require.Equal(t, "donnée", udevEncode("donnée"))
+}
+
+func TestUnmountBySourceID(t *testing.T) {
+	setDeviceTags(t, map[string]string{"UUID=1234-abcd": "/dev/sdb1"})
+	m, mi := newTestMounter(t)
+
+	require.NoError(t, m.Mount(0, "UUID=1234-abcd", "/mnt/a", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/dev/sdb1", "/mnt/b", "ext4", 0, "", 0, nil))
+	info := m.testInfo("/dev/sdb1")
+	info.Lock()
+	info.Minor = 17
+	info.Unlock()
+	for _, source := range []string{"UUID=1234-abcd", `UUID="1234-abcd"`, "/dev/sdb1"} {
+		require.Equal(t, 2, m.HasMounts(source), source)
+		require.Len(t, m.Inspect(source), 2, source)
+		require.ElementsMatch(t, []string{"/mnt/a", "/mnt/b"}, m.Mounts(source), source)
+		exists, err := m.Exists(source, "/mnt/a")
+		require.NoError(t, err)
+		require.True(t, exists, source)
+		minor, err := m.GetMinor(source)
+		require.NoError(t, err)
+		require.Equal(t, 17, minor, source)
+	}
+	require.Nil(t, m.Inspect("UUID=other"))
+	_, err := m.GetMinor("UUID=other")
+	require.Equal(t, ErrEnoent, err)
+
+	require.NoError(t, m.Unmount("UUID=1234-abcd", "/mnt/a", 0, 0, nil))
+	require.NoError(t, m.Unmount("UUID=1234-abcd", "/mnt/b", 0, 0, nil))
+	require.Equal(t, []string{"/mnt/a", "/mnt/b"}, mi.unmounted)
+	require.Equal(t, 0, m.HasMounts("/dev/sdb1"))
+	require.Equal(t, ErrEnoent, m.Unmount("UUID=1234-abcd", "/mnt/a", 0, 0, nil))
+}
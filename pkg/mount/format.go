@@ -0,0 +1,63 @@
+package mount
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	osdexec "github.com/libopenstorage/openstorage/pkg/exec"
+)
+
+// Format describes the filesystem MountWithOptions creates on an
+// unformatted device.
+type Format struct {
+	// Fs is the filesystem type, run as mkfs.<Fs>.
+	Fs string
+	// Args are passed to mkfs before the device. Formatting over a
+	// filesystem may need the force flag of mkfs, e.g. -F for ext4 or -f for
+	// xfs.
+	Args []string
+	// Force formats the device even if it has a filesystem, destroying its
+	// data. Mounted devices are never formatted.
+	Force bool
+}
+
+// mkfs creates a filesystem of type fs on device. It is replaced by tests.
+var mkfs = func(fs, device string, args []string) error {
+	cmd := exec.Command(osdexec.Which("mkfs."+fs), append(args, device)...)
+	return runMountHelper(cmd)
+}
+
+// formatIfEmpty formats device as described by f if it has no filesystem,
+// or if f.Force is set, and returns the filesystem of the device.
+func (m *Mounter) formatIfEmpty(device string, f *Format) (string, error) {
+	if f.Fs == "" || strings.ContainsAny(f.Fs, "/ ") {
+		return "", fmt.Errorf("invalid filesystem %q to format %s: %w", f.Fs, device, ErrEinval)
+	}
+	// Serialize with mounts of device so that it is not mounted while it is
+	// formatted.
+	dh := m.kl.Acquire(deviceLockKey(device))
+	defer m.kl.Release(&dh)
+
+	fs, err := DetectFs(device)
+	switch {
+	case errors.Is(err, ErrNoFilesystem):
+	case err != nil:
+		return "", err
+	case !f.Force:
+		return fs, nil
+	}
+	if m.HasMounts(device) > 0 {
+		return "", fmt.Errorf("refusing to format mounted device %s: %w", device, ErrEinval)
+	}
+	if err == nil {
+		m.logger.Warnf("Formatting %s with %s over its %s filesystem", device, f.Fs, fs)
+	} else {
+		m.logger.Infof("Formatting %s with %s", device, f.Fs)
+	}
+	if err := mkfs(f.Fs, device, f.Args); err != nil {
+		return "", fmt.Errorf("failed to format %s with %s: %w", device, f.Fs, err)
+	}
+	return f.Fs, nil
+}
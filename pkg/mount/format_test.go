@@ -0,0 +1,92 @@
+package mount
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setTestMkfs records the mkfs runs and makes blkid report the filesystems
+// they create, on top of the filesystems in types.
+func setTestMkfs(t *testing.T, types map[string]string) *[]string {
+	var runs []string
+	setTestBlkid(t, types)
+	orig := mkfs
+	mkfs = func(fs, device string, args []string) error {
+		runs = append(runs, fs+" "+device)
+		types[device] = fs
+		return nil
+	}
+	t.Cleanup(func() { mkfs = orig })
+	return &runs
+}
+
+func TestMountFormatIfEmpty(t *testing.T) {
+	runs := setTestMkfs(t, map[string]string{"/dev/xfs": "xfs"})
+	m, mi := newTestMounter(t)
+
+	o := MountOptions{Device: "/dev/new", Path: "/mnt/new", FormatIfEmpty: &Format{Fs: "ext4"}}
+	require.NoError(t, m.MountWithOptions(o))
+	require.Equal(t, []string{"ext4 /dev/new"}, *runs)
+	require.Equal(t, "ext4", mi.lastCall().fstype)
+	require.NoError(t, m.Unmount("/dev/new", "/mnt/new", 0, 0, nil))
+
+	// A formatted device is mounted as is.
+	require.NoError(t, m.MountWithOptions(o))
+	o = MountOptions{Device: "/dev/xfs", Path: "/mnt/xfs", FormatIfEmpty: &Format{Fs: "ext4"}}
+	require.NoError(t, m.MountWithOptions(o))
+	require.Equal(t, "xfs", mi.lastCall().fstype)
+	require.Equal(t, []string{"ext4 /dev/new"}, *runs)
+}
+
+func TestMountFormatForce(t *testing.T) {
+	runs := setTestMkfs(t, map[string]string{"/dev/xfs": "xfs", "/dev/busy": "xfs"})
+	m, mi := newTestMounter(t)
+
+	o := MountOptions{Device: "/dev/xfs", Path: "/mnt/xfs", FormatIfEmpty: &Format{Fs: "ext4", Force: true}}
+	require.NoError(t, m.MountWithOptions(o))
+	require.Equal(t, []string{"ext4 /dev/xfs"}, *runs)
+	require.Equal(t, "ext4", mi.lastCall().fstype)
+
+	// Mounted devices are never formatted.
+	require.NoError(t, m.Mount(0, "/dev/busy", "/mnt/busy", "xfs", 0, "", 0, nil))
+	o = MountOptions{Device: "/dev/busy", Path: "/mnt/busy2", FormatIfEmpty: &Format{Fs: "ext4", Force: true}}
+	require.True(t, errors.Is(m.MountWithOptions(o), ErrEinval))
+	require.Equal(t, []string{"ext4 /dev/xfs"}, *runs)
+	require.Equal(t, []string{"/mnt/busy"}, m.Mounts("/dev/busy"))
+}
+
+func TestMountFormatValidatesFirst(t *testing.T) {
+	runs := setTestMkfs(t, map[string]string{"/dev/xfs": "xfs"})
+	m, _ := newTestMounter(t)
+	o := MountOptions{Device: "/dev/xfs", Path: "/mnt/xfs", FormatIfEmpty: &Format{Fs: "ext4", Force: true}}
+
+	m.AddAllowedDir("/var/allowed")
+	require.Equal(t, ErrMountpathNotAllowed, m.MountWithOptions(o))
+	m.RemoveAllowedDir("/var/allowed")
+
+	require.NoError(t, m.Mount(0, "/dev/other", "/mnt/xfs", "xfs", 0, "", 0, nil))
+	require.Equal(t, ErrExist, m.MountWithOptions(o))
+	require.NoError(t, m.Unmount("/dev/other", "/mnt/xfs", 0, 0, nil))
+
+	m.Quiesce()
+	require.Equal(t, ErrQuiesced, m.MountWithOptions(o))
+	require.Empty(t, *runs, "Expected the device not to be formatted")
+}
+
+func TestMountFormatErrors(t *testing.T) {
+	setTestMkfs(t, map[string]string{})
+	m, _ := newTestMounter(t)
+
+	for _, fs := range []string{"", "../ext4"} {
+		o := MountOptions{Device: "/dev/new", Path: "/mnt/new", FormatIfEmpty: &Format{Fs: fs}}
+		require.True(t, errors.Is(m.MountWithOptions(o), ErrEinval))
+	}
+
+	errMkfs := errors.New("mkfs failed")
+	mkfs = func(string, string, []string) error { return errMkfs }
+	o := MountOptions{Device: "/dev/new", Path: "/mnt/new", FormatIfEmpty: &Format{Fs: "ext4"}}
+	require.True(t, errors.Is(m.MountWithOptions(o), errMkfs))
+	require.Equal(t, 0, m.HasMounts("/dev/new"))
+}
@@ -0,0 +1,128 @@
+package mount
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// mountFlagOption maps a mount option keyword to the flag it sets, or clears
+// if clear is set.
+type mountFlagOption struct {
+	name  string
+	flag  uintptr
+	clear bool
+}
+
+// fstabOnlyOptions are interpreted by mount(8) and not passed to the
+// filesystem.
+var fstabOnlyOptions = map[string]bool{
+	"defaults": true,
+	"auto":     true,
+	"noauto":   true,
+	"user":     true,
+	"nouser":   true,
+	"users":    true,
+	"owner":    true,
+	"group":    true,
+	"nofail":   true,
+	"_netdev":  true,
+}
+
+// ParseMountOptions maps the comma separated mount options of opts to their
+// mount flags. Options that are not flags are returned in data, in order.
+// Options only meaningful to mount(8), such as defaults, noauto or x-*, are
+// dropped.
+func ParseMountOptions(opts string) (flags uintptr, data string) {
+	var dataOpts []string
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "" || fstabOnlyOptions[opt] ||
+			strings.HasPrefix(opt, "x-") || strings.HasPrefix(opt, "comment=") {
+			continue
+		}
+		known := false
+		for _, o := range mountFlagOptions {
+			if o.name != opt {
+				continue
+			}
+			known = true
+			if o.clear {
+				flags &^= o.flag
+			} else {
+				flags |= o.flag
+			}
+			break
+		}
+		if !known {
+			dataOpts = append(dataOpts, opt)
+		}
+	}
+	return flags, strings.Join(dataOpts, ",")
+}
+
+// FlagsToString renders flags as comma separated mount options, the reverse
+// of ParseMountOptions. It starts with ro or rw, and bits without an option
+// are rendered as a hexadecimal number.
+func FlagsToString(flags uintptr) string {
+	opts := []string{"rw"}
+	if msRdonly != 0 && flags&msRdonly != 0 {
+		opts[0] = "ro"
+	}
+	left := flags &^ msRdonly
+	for _, o := range mountFlagOptions {
+		if o.clear || o.flag == msRdonly || left&o.flag != o.flag {
+			continue
+		}
+		opts = append(opts, o.name)
+		left &^= o.flag
+	}
+	if left != 0 {
+		opts = append(opts, fmt.Sprintf("%#x", left))
+	}
+	return strings.Join(opts, ",")
+}
+
+// ParseFstabLine parses a line of fstab(5) into the Device, Path, Fs, Flags
+// and Data of MountOptions. It returns ErrEmptyFstabLine for blank lines and
+// comments, and an error wrapping ErrEinval for malformed lines.
+func ParseFstabLine(line string) (MountOptions, error) {
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		if strings.HasPrefix(f, "#") {
+			fields = fields[:i]
+			break
+		}
+	}
+	if len(fields) == 0 {
+		return MountOptions{}, ErrEmptyFstabLine
+	}
+	if len(fields) < 3 || len(fields) > 6 {
+		return MountOptions{}, fmt.Errorf("invalid fstab line %q, expected 3 to 6 fields: %w",
+			line, ErrEinval)
+	}
+	opts := "defaults"
+	if len(fields) > 3 {
+		opts = fields[3]
+		// The dump frequency and fsck pass number.
+		for _, f := range fields[4:] {
+			if _, err := strconv.Atoi(f); err != nil {
+				return MountOptions{}, fmt.Errorf("invalid fstab line %q, %q is not a number: %w",
+					line, f, ErrEinval)
+			}
+		}
+	}
+	flags, data := ParseMountOptions(opts)
+	return MountOptions{
+		Device: unescapeFstab(fields[0]),
+		Path:   normalizeMountPath(unescapeFstab(fields[1])),
+		Fs:     fields[2],
+		Flags:  flags,
+		Data:   data,
+	}, nil
+}
+
+// unescapeFstab replaces the octal escapes of whitespace and backslashes
+// in an fstab field.
+func unescapeFstab(field string) string {
+	return strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`).Replace(field)
+}
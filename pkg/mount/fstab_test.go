@@ -0,0 +1,129 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseMountOptions(t *testing.T) {
+	for _, tc := range []struct {
+		opts  string
+		flags uintptr
+		data  string
+	}{
+		{"", 0, ""},
+		{"defaults", 0, ""},
+		{"ro", syscall.MS_RDONLY, ""},
+		{"rw", 0, ""},
+		{"ro,rw", 0, ""},
+		{"defaults,noatime", syscall.MS_NOATIME, ""},
+		{"ro,nosuid,nodev,noexec", syscall.MS_RDONLY | syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC, ""},
+		{"rw,sync,dirsync", syscall.MS_SYNCHRONOUS | syscall.MS_DIRSYNC, ""},
+		{"bind,ro", syscall.MS_BIND | syscall.MS_RDONLY, ""},
+		{"rbind", syscall.MS_BIND | syscall.MS_REC, ""},
+		{"relatime,nodiratime", syscall.MS_RELATIME | syscall.MS_NODIRATIME, ""},
+		{"noatime,atime", 0, ""},
+		{"noatime,discard,errors=remount-ro", syscall.MS_NOATIME, "discard,errors=remount-ro"},
+		{"vers=4.1,proto=tcp,soft,ro", syscall.MS_RDONLY, "vers=4.1,proto=tcp,soft"},
+		{"noauto,nofail,_netdev,x-systemd.automount,user,nodev", syscall.MS_NODEV, ""},
+		{"size=64m,mode=1777,nosuid", syscall.MS_NOSUID, "size=64m,mode=1777"},
+		{"nosymfollow,lazytime", unix.MS_NOSYMFOLLOW | unix.MS_LAZYTIME, ""},
+		{"nosymfollow,symfollow,lazytime,nolazytime", 0, ""},
+	} {
+		flags, data := ParseMountOptions(tc.opts)
+		require.Equal(t, tc.flags, flags, tc.opts)
+		require.Equal(t, tc.data, data, tc.opts)
+	}
+}
+
+func TestParseFstabLine(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		opts MountOptions
+	}{
+		{
+			"/dev/sdb /data ext4 defaults,noatime 0 0",
+			MountOptions{Device: "/dev/sdb", Path: "/data", Fs: "ext4", Flags: syscall.MS_NOATIME},
+		},
+		{
+			"  UUID=1234-abcd\t/boot/efi  vfat  umask=0077  0  1",
+			MountOptions{Device: "UUID=1234-abcd", Path: "/boot/efi", Fs: "vfat", Data: "umask=0077"},
+		},
+		{
+			"server:/export /mnt/nfs/ nfs ro,vers=4.1,soft",
+			MountOptions{Device: "server:/export", Path: "/mnt/nfs", Fs: "nfs", Flags: syscall.MS_RDONLY,
+				Data: "vers=4.1,soft"},
+		},
+		{
+			"tmpfs /tmp tmpfs",
+			MountOptions{Device: "tmpfs", Path: "/tmp", Fs: "tmpfs"},
+		},
+		{
+			`/dev/sdc /mnt/my\040disk xfs rw,nosuid 0 2 # data disk`,
+			MountOptions{Device: "/dev/sdc", Path: "/mnt/my disk", Fs: "xfs", Flags: syscall.MS_NOSUID},
+		},
+	} {
+		opts, err := ParseFstabLine(tc.line)
+		require.NoError(t, err, tc.line)
+		require.Equal(t, tc.opts, opts, tc.line)
+	}
+
+	for _, line := range []string{"", "   ", "\t", "# /dev/sdb /data ext4 defaults 0 0", "  #comment"} {
+		_, err := ParseFstabLine(line)
+		require.Equal(t, ErrEmptyFstabLine, err, "%q", line)
+	}
+
+	for _, line := range []string{
+		"/dev/sdb",
+		"/dev/sdb /data",
+		"/dev/sdb /data ext4 defaults 0 0 extra",
+		"/dev/sdb /data ext4 defaults zero 0",
+	} {
+		_, err := ParseFstabLine(line)
+		require.True(t, errors.Is(err, ErrEinval), "%q: %v", line, err)
+	}
+}
+
+func TestFlagsToString(t *testing.T) {
+	for _, tc := range []struct {
+		flags uintptr
+		opts  string
+	}{
+		{0, "rw"},
+		{syscall.MS_RDONLY, "ro"},
+		{syscall.MS_RDONLY | syscall.MS_NOATIME | syscall.MS_NODEV, "ro,nodev,noatime"},
+		{syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC, "rw,nosuid,nodev,noexec"},
+		{syscall.MS_BIND, "rw,bind"},
+		{syscall.MS_BIND | syscall.MS_REC | syscall.MS_RDONLY, "ro,rbind"},
+		{syscall.MS_REC, "rw,0x4000"},
+		{syscall.MS_NOATIME | 1<<30, "rw,noatime,0x40000000"},
+	} {
+		require.Equal(t, tc.opts, FlagsToString(tc.flags), "%#x", tc.flags)
+	}
+}
+
+func TestFlagsRoundTrip(t *testing.T) {
+	for _, opts := range []string{
+		"ro",
+		"rw",
+		"ro,nodev,noatime",
+		"rw,nosuid,nodev,noexec,relatime",
+		"ro,sync,dirsync,nodiratime",
+		"rw,bind",
+		"ro,rbind",
+		"rw,mand,strictatime,silent",
+	} {
+		flags, data := ParseMountOptions(opts)
+		require.Empty(t, data, opts)
+		require.Equal(t, opts, FlagsToString(flags))
+		again, _ := ParseMountOptions(FlagsToString(flags))
+		require.Equal(t, flags, again, opts)
+	}
+}
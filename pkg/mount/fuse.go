@@ -0,0 +1,80 @@
+package mount
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	osdexec "github.com/libopenstorage/openstorage/pkg/exec"
+)
+
+// fusermountBinaries are the FUSE unmount helpers, in order of preference.
+var fusermountBinaries = []string{"fusermount3", "fusermount"}
+
+// FuseMounter is a MountImpl for FUSE filesystems. Unprivileged processes
+// cannot unmount them with the syscall, so they are unmounted with
+// fusermount -u unless running as root.
+type FuseMounter struct {
+	base MountImpl
+	// run runs a helper binary, replaced by tests.
+	run func(name string, args ...string) error
+	// which returns the absolute path of a binary, or its name if it is not
+	// installed.
+	which  func(bin string) string
+	isRoot func() bool
+}
+
+// NewFuseMounter returns a FuseMounter mounting with base, and unmounting
+// with it when running as root or if fusermount is not installed. base is
+// the DefaultMounter if nil.
+func NewFuseMounter(base MountImpl) *FuseMounter {
+	if base == nil {
+		base = &DefaultMounter{}
+	}
+	return &FuseMounter{
+		base: base,
+		run: func(name string, args ...string) error {
+			return runMountHelper(exec.Command(name, args...))
+		},
+		which:  osdexec.Which,
+		isRoot: func() bool { return os.Geteuid() == 0 },
+	}
+}
+
+// Mount mounts with the base MountImpl.
+func (f *FuseMounter) Mount(source, target, fstype string, flags uintptr, data string, timeout int) error {
+	return f.base.Mount(source, target, fstype, flags, data, timeout)
+}
+
+// Unmount unmounts target with fusermount -u, or -uz for a lazy unmount.
+func (f *FuseMounter) Unmount(target string, flags int, timeout int) error {
+	if f.isRoot() {
+		return f.base.Unmount(target, flags, timeout)
+	}
+	bin := f.fusermount()
+	if bin == "" {
+		return f.base.Unmount(target, flags, timeout)
+	}
+	args := []string{"-u"}
+	if mntDetach != 0 && flags&mntDetach != 0 {
+		args = append(args, "-z")
+	}
+	return f.run(bin, append(args, target)...)
+}
+
+// fusermount returns the path of the installed FUSE unmount helper, or an
+// empty string if there is none.
+func (f *FuseMounter) fusermount() string {
+	for _, b := range fusermountBinaries {
+		if p := f.which(b); filepath.IsAbs(p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// isFuseType returns true if fstype is a FUSE filesystem, e.g. fuse.sshfs.
+func isFuseType(fstype string) bool {
+	return fstype == "fuse" || fstype == "fuseblk" || strings.HasPrefix(fstype, "fuse.")
+}
@@ -0,0 +1,88 @@
+package mount
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFuseMounter returns a FuseMounter recording the helper commands it
+// runs, as an unprivileged user with the installed helpers.
+func newTestFuseMounter(base MountImpl, installed ...string) (*FuseMounter, *[]string) {
+	var cmds []string
+	f := NewFuseMounter(base)
+	f.run = func(name string, args ...string) error {
+		cmds = append(cmds, name+" "+strings.Join(args, " "))
+		return nil
+	}
+	f.which = func(bin string) string {
+		for _, b := range installed {
+			if b == bin {
+				return "/usr/bin/" + bin
+			}
+		}
+		return bin
+	}
+	f.isRoot = func() bool { return false }
+	return f, &cmds
+}
+
+func TestFuseMounterUnmount(t *testing.T) {
+	base := newTestMountImpl()
+	f, cmds := newTestFuseMounter(base, "fusermount3", "fusermount")
+
+	require.NoError(t, f.Mount("sshfs#host:/", "/mnt/fuse", "fuse.sshfs", 0, "", 0))
+	require.Equal(t, "sshfs#host:/", base.lastCall().source)
+
+	require.NoError(t, f.Unmount("/mnt/fuse", 0, 0))
+	require.Equal(t, []string{"/usr/bin/fusermount3 -u /mnt/fuse"}, *cmds)
+	require.Empty(t, base.unmounted)
+	if mntDetach != 0 {
+		require.NoError(t, f.Unmount("/mnt/fuse", mntDetach, 0))
+		require.Equal(t, "/usr/bin/fusermount3 -u -z /mnt/fuse", (*cmds)[1])
+	}
+
+	// fusermount is used without fusermount3.
+	f, cmds = newTestFuseMounter(base, "fusermount")
+	require.NoError(t, f.Unmount("/mnt/fuse", 0, 0))
+	require.Equal(t, []string{"/usr/bin/fusermount -u /mnt/fuse"}, *cmds)
+
+	// The syscall is used without the helpers and as root.
+	f, cmds = newTestFuseMounter(base)
+	require.NoError(t, f.Unmount("/mnt/fuse", 0, 0))
+	f, rootCmds := newTestFuseMounter(base, "fusermount3")
+	f.isRoot = func() bool { return true }
+	require.NoError(t, f.Unmount("/mnt/fuse2", 0, 0))
+	require.Empty(t, *cmds)
+	require.Empty(t, *rootCmds)
+	require.Equal(t, []string{"/mnt/fuse", "/mnt/fuse2"}, base.unmounted)
+}
+
+func TestFuseMounterUnmountError(t *testing.T) {
+	f, _ := newTestFuseMounter(newTestMountImpl(), "fusermount")
+	errBusy := errors.New("fusermount: failed to unmount /mnt/fuse: Device or resource busy")
+	f.run = func(string, ...string) error { return errBusy }
+	require.Equal(t, errBusy, f.Unmount("/mnt/fuse", 0, 0))
+}
+
+func TestCustomMounterFuseDispatch(t *testing.T) {
+	d := newFsDispatcher(&DefaultMounter{})
+	h, err := d.handler("fuse.sshfs")
+	require.NoError(t, err)
+	require.IsType(t, &FuseMounter{}, h)
+	h, err = d.handler("ext4")
+	require.NoError(t, err)
+	require.IsType(t, &DefaultMounter{}, h)
+
+	// A MountImpl other than the DefaultMounter handles FUSE itself.
+	base := newTestMountImpl()
+	h, err = newFsDispatcher(base).handler("fuse.sshfs")
+	require.NoError(t, err)
+	require.Equal(t, base, h)
+
+	require.True(t, isFuseType("fuse"))
+	require.True(t, isFuseType("fuseblk"))
+	require.False(t, isFuseType("fusectl"))
+}
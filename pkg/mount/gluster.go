@@ -0,0 +1,193 @@
+package mount
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	osdexec "github.com/libopenstorage/openstorage/pkg/exec"
+)
+
+const (
+	glusterType = "glusterfs"
+	// glusterFuseType is the filesystem type of GlusterFS mounts in the
+	// mount table.
+	glusterFuseType = "fuse.glusterfs"
+)
+
+// GlusterMounter mounts GlusterFS volumes from a primary server, falling
+// back to backup volfile servers if it is down.
+type GlusterMounter struct {
+	*CustomMounterHandler
+	servers []string
+}
+
+// NewGlusterMounter returns a Mounter of the GlusterFS volumes of servers,
+// the first of which is the primary server. If mountImpl is nil, volumes are
+// mounted with the mount.glusterfs helper.
+func NewGlusterMounter(
+	servers []string,
+	mountImpl MountImpl,
+	allowedDirs []string,
+	opts ...MounterOption,
+) (*GlusterMounter, error) {
+	if err := validateGlusterServers(servers); err != nil {
+		return nil, err
+	}
+	if mountImpl == nil {
+		mountImpl = glusterHelper{}
+	}
+	h, err := NewCustomMounter(nil, mountImpl, GlusterCustomMounter(servers), allowedDirs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// Loaded mounts have the FUSE filesystem type of the mount table.
+	h.RegisterFsMounter(glusterType, mountImpl)
+	h.RegisterFsMounter(glusterFuseType, mountImpl)
+	return &GlusterMounter{CustomMounterHandler: h, servers: servers}, nil
+}
+
+// GlusterMount mounts volume at target. The mount is tracked under the
+// primary:/volume source.
+func (g *GlusterMounter) GlusterMount(volume, target string, flags uintptr, timeout int) error {
+	source, err := glusterSource(g.servers[0], volume)
+	if err != nil {
+		return err
+	}
+	return g.Mount(0, source, target, glusterType, flags, glusterData(g.servers[1:]), timeout, nil)
+}
+
+// GlusterCustomMounter returns the load and reload callbacks of a
+// CustomMounter tracking the GlusterFS mounts of servers.
+func GlusterCustomMounter(servers []string) CustomMounter {
+	return func() (CustomLoad, CustomReload) {
+		load := func(_ []*regexp.Regexp, mounts DeviceMap, paths PathMap) error {
+			return loadGlusterMounts(servers, "", mounts, paths)
+		}
+		reload := func(source string, mounts DeviceMap, paths PathMap) error {
+			delete(mounts, source)
+			for p, s := range paths {
+				if s == source {
+					delete(paths, p)
+				}
+			}
+			return loadGlusterMounts(servers, source, mounts, paths)
+		}
+		return load, reload
+	}
+}
+
+// loadGlusterMounts adds the GlusterFS mounts of servers in the mount table
+// to mounts and paths. If source is not empty, only its mounts are added.
+func loadGlusterMounts(servers []string, source string, mounts DeviceMap, paths PathMap) error {
+	infos, err := mountTable()
+	if err != nil {
+		return err
+	}
+	for _, v := range infos {
+		if v.Fstype != glusterFuseType || !isGlusterSource(servers, v.Source) {
+			continue
+		}
+		if source != "" && v.Source != source {
+			continue
+		}
+		info, ok := mounts[v.Source]
+		if !ok {
+			info = &Info{
+				Device:     v.Source,
+				Fs:         v.Fstype,
+				Minor:      v.Minor,
+				Mountpoint: make([]*PathInfo, 0),
+			}
+			mounts[v.Source] = info
+		}
+		path := normalizeMountPath(v.Mountpoint)
+		if _, ok := paths[path]; ok {
+			continue
+		}
+		info.Mountpoint = append(info.Mountpoint, &PathInfo{
+			Root: normalizeMountPath(v.Root),
+			Path: path,
+		})
+		paths[path] = v.Source
+	}
+	return nil
+}
+
+// isGlusterSource returns true if source is a volume of one of servers. The
+// mount table reports the server the volume was mounted from, which is the
+// primary unless servers changed.
+func isGlusterSource(servers []string, source string) bool {
+	i := strings.Index(source, ":")
+	if i <= 0 {
+		return false
+	}
+	for _, s := range servers {
+		if source[:i] == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateGlusterServers checks that servers can be encoded in the
+// backup-volfile-servers option, whose separator is a colon.
+func validateGlusterServers(servers []string) error {
+	if len(servers) == 0 {
+		return fmt.Errorf("no GlusterFS server: %w", ErrEinval)
+	}
+	for _, s := range servers {
+		if s == "" || strings.ContainsAny(s, ":,/ ") {
+			return fmt.Errorf("invalid GlusterFS server %q: %w", s, ErrEinval)
+		}
+	}
+	return nil
+}
+
+// glusterSource returns the server:/volume source of a GlusterFS mount.
+func glusterSource(server, volume string) (string, error) {
+	volume = strings.TrimPrefix(volume, "/")
+	if volume == "" || strings.ContainsAny(volume, ":, ") {
+		return "", fmt.Errorf("invalid GlusterFS volume %q: %w", volume, ErrEinval)
+	}
+	return server + ":/" + volume, nil
+}
+
+// glusterData returns the mount data listing the backup volfile servers.
+func glusterData(backups []string) string {
+	if len(backups) == 0 {
+		return ""
+	}
+	return "backup-volfile-servers=" + strings.Join(backups, ":")
+}
+
+// glusterHelper implements MountImpl with the mount and umount binaries, as
+// GlusterFS is mounted by the glusterfs FUSE client started by
+// mount.glusterfs. Unmounting ends the client.
+type glusterHelper struct{}
+
+func (glusterHelper) Mount(source, target, fstype string, flags uintptr, data string, timeout int) error {
+	args := []string{"-t", glusterType}
+	opts := FlagsToString(flags)
+	if data != "" {
+		opts += "," + data
+	}
+	args = append(args, "-o", opts, source, target)
+	return runMountHelper(exec.Command(osdexec.Which("mount"), args...))
+}
+
+func (glusterHelper) Unmount(target string, flags int, timeout int) error {
+	return runMountHelper(exec.Command(osdexec.Which("umount"), target))
+}
+
+func runMountHelper(cmd *exec.Cmd) error {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", strings.TrimSpace(out.String()), err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package mount
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/pkg/mount"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlusterData(t *testing.T) {
+	require.Equal(t, "", glusterData(nil))
+	require.Equal(t, "backup-volfile-servers=gfs2", glusterData([]string{"gfs2"}))
+	require.Equal(t, "backup-volfile-servers=gfs2:10.0.0.3:gfs4.example.com",
+		glusterData([]string{"gfs2", "10.0.0.3", "gfs4.example.com"}))
+
+	source, err := glusterSource("gfs1", "gv0")
+	require.NoError(t, err)
+	require.Equal(t, "gfs1:/gv0", source)
+	source, err = glusterSource("gfs1", "/gv0")
+	require.NoError(t, err)
+	require.Equal(t, "gfs1:/gv0", source)
+	_, err = glusterSource("gfs1", "")
+	require.True(t, errors.Is(err, ErrEinval))
+
+	for _, servers := range [][]string{nil, {""}, {"gfs1", "fd00::1"}, {"gfs1,gfs2"}} {
+		_, err := NewGlusterMounter(servers, newTestMountImpl(), nil)
+		require.True(t, errors.Is(err, ErrEinval), "Expected %q to be rejected, got %v", servers, err)
+	}
+}
+
+func TestGlusterMount(t *testing.T) {
+	mi := newTestMountImpl()
+	m, err := NewGlusterMounter([]string{"gfs1", "gfs2", "gfs3"}, mi, nil, withFsOps(newTestFsOps()))
+	require.NoError(t, err)
+	target := filepath.Join(t.TempDir(), "gluster")
+	require.NoError(t, os.Mkdir(target, 0755))
+
+	require.NoError(t, m.GlusterMount("gv0", target, 0, 0))
+	call := mi.lastCall()
+	require.Equal(t, "gfs1:/gv0", call.source)
+	require.Equal(t, "glusterfs", call.fstype)
+	require.Equal(t, "backup-volfile-servers=gfs2:gfs3", call.data)
+	require.Equal(t, []string{target}, m.Mounts("gfs1:/gv0"))
+
+	require.NoError(t, m.Unmount("gfs1:/gv0", target, 0, 0, nil))
+	require.Equal(t, []string{target}, mi.unmounted)
+	require.Equal(t, 0, m.HasMounts("gfs1:/gv0"))
+
+	require.Equal(t, ErrUnsupported, m.Mount(0, "gfs1:/gv0", target, "nfs", 0, "", 0, nil))
+}
+
+func TestGlusterLoad(t *testing.T) {
+	orig := mountTable
+	mountTable = func() ([]*mount.Info, error) {
+		return []*mount.Info{
+			{Source: "gfs1:/gv0", Mountpoint: "/mnt/gv0", Fstype: glusterFuseType, Root: "/"},
+			{Source: "gfs2:/gv1", Mountpoint: "/mnt/gv1", Fstype: glusterFuseType, Root: "/"},
+			{Source: "other:/gv2", Mountpoint: "/mnt/gv2", Fstype: glusterFuseType, Root: "/"},
+			{Source: "gfs1:/export", Mountpoint: "/mnt/nfs", Fstype: "nfs", Root: "/"},
+		}, nil
+	}
+	defer func() { mountTable = orig }()
+
+	mi := newTestMountImpl()
+	m, err := NewGlusterMounter([]string{"gfs1", "gfs2"}, mi, nil, withFsOps(newTestFsOps()))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"gfs1:/gv0", "gfs2:/gv1"}, m.GetSourcePaths())
+	require.NoError(t, m.Reload("gfs1:/gv0"))
+	require.Equal(t, []string{"/mnt/gv0"}, m.Mounts("gfs1:/gv0"))
+
+	// Mounts loaded with the FUSE filesystem type are unmounted by the
+	// GlusterFS MountImpl.
+	require.NoError(t, m.Unmount("gfs2:/gv1", "/mnt/gv1", 0, 0, nil))
+	require.Equal(t, []string{"/mnt/gv1"}, mi.unmounted)
+}
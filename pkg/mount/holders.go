@@ -0,0 +1,95 @@
+package mount
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// procDir is where the process information is read from, replaced by tests.
+var procDir = "/proc"
+
+// ProcessRef is a process using files under a path.
+type ProcessRef struct {
+	PID     int
+	Command string
+}
+
+// WhoHolds returns the processes keeping path busy, as fuser -m does: the
+// ones with open files, memory mapped files, a working directory, a root or
+// an executable under path. Processes that cannot be inspected, such as the
+// ones of other users, are skipped. The result is sorted by PID.
+func WhoHolds(path string) ([]ProcessRef, error) {
+	path = normalizeMountPath(path)
+	entries, err := ioutil.ReadDir(procDir)
+	if err != nil {
+		return nil, err
+	}
+	var refs []ProcessRef
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(procDir, e.Name())
+		if !holds(dir, path) {
+			continue
+		}
+		comm, _ := ioutil.ReadFile(filepath.Join(dir, "comm"))
+		refs = append(refs, ProcessRef{PID: pid, Command: strings.TrimSpace(string(comm))})
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].PID < refs[j].PID
+	})
+	return refs, nil
+}
+
+// holds returns true if the process of the /proc directory dir uses a file
+// under path.
+func holds(dir, path string) bool {
+	under := func(link string) bool {
+		target, err := os.Readlink(link)
+		if err != nil {
+			return false
+		}
+		return isWithin(path, strings.TrimSuffix(target, " (deleted)"))
+	}
+	for _, link := range []string{"cwd", "root", "exe"} {
+		if under(filepath.Join(dir, link)) {
+			return true
+		}
+	}
+	fds, _ := ioutil.ReadDir(filepath.Join(dir, "fd"))
+	for _, fd := range fds {
+		if under(filepath.Join(dir, "fd", fd.Name())) {
+			return true
+		}
+	}
+	return mapsUnder(filepath.Join(dir, "maps"), path)
+}
+
+// mapsUnder returns true if the maps file lists a file under path.
+func mapsUnder(maps, path string) bool {
+	f, err := os.Open(maps)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// address perms offset dev inode pathname
+		fields := strings.SplitN(scanner.Text(), " ", 6)
+		if len(fields) < 6 {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimSpace(fields[5]), " (deleted)")
+		if filepath.IsAbs(name) && isWithin(path, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeProcFixture creates the /proc directory of process pid in root, with
+// links mapping the names of the process files, such as fd/3 or cwd, to
+// their targets.
+func writeProcFixture(t *testing.T, root, pid, comm, maps string, links map[string]string) {
+	dir := filepath.Join(root, pid)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "fd"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "maps"), []byte(maps), 0644))
+	for name, target := range links {
+		require.NoError(t, os.Symlink(target, filepath.Join(dir, name)))
+	}
+}
+
+func TestWhoHolds(t *testing.T) {
+	root := t.TempDir()
+	orig := procDir
+	procDir = root
+	t.Cleanup(func() { procDir = orig })
+
+	writeProcFixture(t, root, "12", "editor", "", map[string]string{
+		"cwd":  "/home/user",
+		"fd/0": "/dev/pts/0",
+		"fd/3": "/mnt/busy/data/file.txt",
+	})
+	writeProcFixture(t, root, "7", "shell", "", map[string]string{
+		"cwd": "/mnt/busy",
+	})
+	writeProcFixture(t, root, "300", "db",
+		"7f0000000000-7f0000001000 r--s 00000000 08:01 1234                       /mnt/busy/db.map (deleted)\n"+
+			"7f0000001000-7f0000002000 r-xp 00000000 08:01 99                         /usr/lib/libc.so\n",
+		map[string]string{"fd/4": "socket:[5678]"})
+	writeProcFixture(t, root, "45", "other", "", map[string]string{
+		"cwd":  "/mnt/busy2",
+		"fd/1": "/mnt/other/file",
+		"exe":  "/usr/bin/other",
+	})
+	require.NoError(t, os.Mkdir(filepath.Join(root, "self"), 0755))
+
+	refs, err := WhoHolds("/mnt/busy/")
+	require.NoError(t, err)
+	require.Equal(t, []ProcessRef{
+		{PID: 7, Command: "shell"},
+		{PID: 12, Command: "editor"},
+		{PID: 300, Command: "db"},
+	}, refs)
+
+	refs, err = WhoHolds("/mnt/idle")
+	require.NoError(t, err)
+	require.Empty(t, refs)
+}
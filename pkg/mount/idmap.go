@@ -0,0 +1,63 @@
+package mount
+
+import (
+	"fmt"
+)
+
+// idmapSyscalls are the system calls of the new mount API creating an
+// idmapped mount.
+type idmapSyscalls interface {
+	// OpenTree returns a file descriptor of a detached clone of the mount at
+	// path, with open_tree and OPEN_TREE_CLONE.
+	OpenTree(path string) (int, error)
+	// SetIdmap applies the uid and gid mappings of the user namespace usernsFd
+	// to the detached mount fd, with mount_setattr and MOUNT_ATTR_IDMAP.
+	SetIdmap(fd, usernsFd int) error
+	// MoveMount attaches the detached mount fd at target, with move_mount.
+	MoveMount(fd int, target string) error
+	// Open returns a file descriptor of the namespace file at path.
+	Open(path string) (int, error)
+	// Close closes fd.
+	Close(fd int) error
+}
+
+// withIdmapSyscalls sets the idmapped mount system calls, used by tests.
+func withIdmapSyscalls(sys idmapSyscalls) MounterOption {
+	return func(m *Mounter) {
+		m.idmap = sys
+	}
+}
+
+// IdmappedMount mounts a clone of the mount of device at source on target,
+// with the uid and gid mappings of the user namespace at usernsPath, such as
+// /proc/<pid>/ns/user. Files owned by a mapped id on the source appear owned
+// by the id in the user namespace. The mount is tracked as a bind mount of
+// device at target. It needs kernel 5.12 and returns ErrUnsupported on older
+// kernels and other platforms than Linux.
+func (m *Mounter) IdmappedMount(device, source, target, usernsPath string, timeout int) error {
+	if !m.KernelSupports(KernelFeatureIdmap) {
+		return ErrUnsupported
+	}
+	source = normalizeMountPath(source)
+	idmapped := func(source, target, fstype string, flags uintptr, data string, timeout int) error {
+		usernsFd, err := m.idmap.Open(usernsPath)
+		if err != nil {
+			return fmt.Errorf("failed to open user namespace %s: %w", usernsPath, err)
+		}
+		defer m.idmap.Close(usernsFd)
+		fd, err := m.idmap.OpenTree(source)
+		if err != nil {
+			return fmt.Errorf("failed to clone mount %s: %w", source, err)
+		}
+		// Closing the detached mount before it is attached unmounts it.
+		defer m.idmap.Close(fd)
+		if err := m.idmap.SetIdmap(fd, usernsFd); err != nil {
+			return fmt.Errorf("failed to idmap mount %s: %w", source, err)
+		}
+		if err := m.idmap.MoveMount(fd, target); err != nil {
+			return fmt.Errorf("failed to attach idmapped mount at %s: %w", target, err)
+		}
+		return nil
+	}
+	return m.mount(0, source, device, target, "", msBind, "", timeout, withMountBackend(idmapped))
+}
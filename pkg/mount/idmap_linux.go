@@ -0,0 +1,39 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// defaultIdmapSyscalls creates idmapped mounts with the new mount API.
+var defaultIdmapSyscalls idmapSyscalls = unixIdmapSyscalls{}
+
+// unixIdmapSyscalls implements idmapSyscalls with the unix package.
+type unixIdmapSyscalls struct{}
+
+func (unixIdmapSyscalls) OpenTree(path string) (int, error) {
+	// OPEN_TREE_CLOEXEC is O_CLOEXEC.
+	return unix.OpenTree(unix.AT_FDCWD, path, unix.OPEN_TREE_CLONE|unix.O_CLOEXEC)
+}
+
+func (unixIdmapSyscalls) SetIdmap(fd, usernsFd int) error {
+	attr := &unix.MountAttr{
+		Attr_set:  unix.MOUNT_ATTR_IDMAP,
+		Userns_fd: uint64(usernsFd),
+	}
+	return unix.MountSetattr(fd, "", unix.AT_EMPTY_PATH, attr)
+}
+
+func (unixIdmapSyscalls) MoveMount(fd int, target string) error {
+	return unix.MoveMount(fd, "", unix.AT_FDCWD, target, unix.MOVE_MOUNT_F_EMPTY_PATH)
+}
+
+func (unixIdmapSyscalls) Open(path string) (int, error) {
+	return unix.Open(path, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+}
+
+func (unixIdmapSyscalls) Close(fd int) error {
+	return unix.Close(fd)
+}
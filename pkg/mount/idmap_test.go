@@ -0,0 +1,121 @@
+package mount
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testIdmapSyscalls is an idmapSyscalls recording the system calls.
+type testIdmapSyscalls struct {
+	sync.Mutex
+	calls   []string
+	nextFd  int
+	open    map[int]bool
+	moveErr error
+}
+
+func newTestIdmapSyscalls() *testIdmapSyscalls {
+	return &testIdmapSyscalls{nextFd: 10, open: make(map[int]bool)}
+}
+
+func (s *testIdmapSyscalls) newFd() int {
+	fd := s.nextFd
+	s.nextFd++
+	s.open[fd] = true
+	return fd
+}
+
+func (s *testIdmapSyscalls) OpenTree(path string) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.calls = append(s.calls, "open_tree "+path)
+	return s.newFd(), nil
+}
+
+func (s *testIdmapSyscalls) SetIdmap(fd, usernsFd int) error {
+	s.Lock()
+	defer s.Unlock()
+	if !s.open[fd] || !s.open[usernsFd] {
+		return errors.New("bad file descriptor")
+	}
+	s.calls = append(s.calls, "mount_setattr")
+	return nil
+}
+
+func (s *testIdmapSyscalls) MoveMount(fd int, target string) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.moveErr != nil {
+		return s.moveErr
+	}
+	s.calls = append(s.calls, "move_mount "+target)
+	return nil
+}
+
+func (s *testIdmapSyscalls) Open(path string) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.calls = append(s.calls, "open "+path)
+	return s.newFd(), nil
+}
+
+func (s *testIdmapSyscalls) Close(fd int) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.open, fd)
+	return nil
+}
+
+func newIdmapTestMounter(t *testing.T, v kernelVersion) (*deviceMounter, *testMountImpl, *testIdmapSyscalls) {
+	sys := newTestIdmapSyscalls()
+	m, mi := newTestMounter(t, withIdmapSyscalls(sys), withKernelVersion(func() (kernelVersion, error) {
+		return v, nil
+	}))
+	return m, mi, sys
+}
+
+func TestIdmappedMount(t *testing.T) {
+	m, mi, sys := newIdmapTestMounter(t, kernelVersion{5, 15, 0})
+	require.NoError(t, m.Mount(0, "/dev/idmap", "/mnt/idmap", "ext4", 0, "", 0, nil))
+
+	require.NoError(t, m.IdmappedMount("/dev/idmap", "/mnt/idmap", "/mnt/idmapped", "/proc/42/ns/user", 0))
+	require.Equal(t, []string{
+		"open /proc/42/ns/user",
+		"open_tree /mnt/idmap",
+		"mount_setattr",
+		"move_mount /mnt/idmapped",
+	}, sys.calls)
+	require.Empty(t, sys.open, "Expected all file descriptors to be closed")
+	require.Len(t, mi.calls, 1, "Expected the MountImpl not to be used")
+
+	dev, ok := m.HasTarget("/mnt/idmapped")
+	require.True(t, ok)
+	require.Equal(t, "/dev/idmap", dev)
+	require.Equal(t, 2, m.HasMounts("/dev/idmap"))
+	require.Equal(t, "ext4", m.testInfo("/dev/idmap").Fs, "Expected the fs to be kept")
+
+	require.NoError(t, m.Unmount("/dev/idmap", "/mnt/idmapped", 0, 0, nil))
+	require.Equal(t, []string{"/mnt/idmapped"}, mi.unmounted)
+}
+
+func TestIdmappedMountFailure(t *testing.T) {
+	m, _, sys := newIdmapTestMounter(t, kernelVersion{5, 15, 0})
+	sys.moveErr = errors.New("move failed")
+
+	err := m.IdmappedMount("/dev/idmap", "/mnt/idmap", "/mnt/idmapped", "/proc/42/ns/user", 0)
+	require.ErrorIs(t, err, sys.moveErr)
+	require.Empty(t, sys.open, "Expected all file descriptors to be closed")
+	_, ok := m.HasTarget("/mnt/idmapped")
+	require.False(t, ok)
+}
+
+func TestIdmappedMountOldKernel(t *testing.T) {
+	m, _, sys := newIdmapTestMounter(t, kernelVersion{5, 11, 0})
+
+	err := m.IdmappedMount("/dev/idmap", "/mnt/idmap", "/mnt/idmapped", "/proc/42/ns/user", 0)
+	require.Equal(t, ErrUnsupported, err)
+	require.Empty(t, sys.calls)
+}
@@ -0,0 +1,111 @@
+package mount
+
+// The Mounter keeps an index of mountpoints to the source they are tracked
+// under, so that lookups by path do not scan the whole table. The index is
+// kept in the shards of the paths, next to the paths map. Loads and reloads
+// replace the table, CustomLoad and CustomReload with the copies they were
+// handed, which is why the index is rebuilt after each of them.
+
+// addPath indexes path as a mountpoint of source with m locked.
+func (m *Mounter) addPath(path, source string) {
+	s := m.pathShard(path)
+	s.Lock()
+	defer s.Unlock()
+	for _, src := range s.targets[path] {
+		if src == source {
+			return
+		}
+	}
+	if s.targets == nil {
+		s.targets = make(map[string][]string)
+	}
+	s.targets[path] = append(s.targets[path], source)
+}
+
+// deletePath removes path as a mountpoint of source from the index with m
+// locked.
+func (m *Mounter) deletePath(path, source string) {
+	s := m.pathShard(path)
+	s.Lock()
+	defer s.Unlock()
+	sources := s.targets[path]
+	for i, src := range sources {
+		if src != source {
+			continue
+		}
+		if len(sources) == 1 {
+			delete(s.targets, path)
+			return
+		}
+		s.targets[path] = append(sources[:i:i], sources[i+1:]...)
+		return
+	}
+}
+
+// target returns the source mounted at path with m locked.
+func (m *Mounter) target(path string) (string, bool) {
+	s := m.pathShard(path)
+	s.Lock()
+	defer s.Unlock()
+	sources := s.targets[path]
+	if len(sources) == 0 {
+		return "", false
+	}
+	return sources[0], true
+}
+
+// eachTargetLocked calls fn for every indexed mountpoint and the sources
+// mounted there with m locked. The shard of the path is locked while fn is
+// called.
+func (m *Mounter) eachTargetLocked(fn func(path string, sources []string)) {
+	for i := range m.pathShards {
+		s := &m.pathShards[i]
+		s.Lock()
+		for path, sources := range s.targets {
+			fn(path, sources)
+		}
+		s.Unlock()
+	}
+}
+
+// targetPaths returns the indexed mountpoints.
+func (m *Mounter) targetPaths() []string {
+	m.RLock()
+	defer m.RUnlock()
+	var paths []string
+	m.eachTargetLocked(func(path string, sources []string) {
+		paths = append(paths, path)
+	})
+	return paths
+}
+
+// reindex rebuilds the index from the device map.
+func (m *Mounter) reindex() {
+	m.Lock()
+	defer m.Unlock()
+	m.reindexLocked()
+}
+
+// reindexLocked rebuilds the index from the device map with m locked for
+// writing. If several sources are mounted at a path, the one in the paths
+// map comes first.
+func (m *Mounter) reindexLocked() {
+	for i := range m.pathShards {
+		m.pathShards[i].targets = nil
+	}
+	m.eachDeviceLocked(func(source string, info *Info) {
+		info.Lock()
+		defer info.Unlock()
+		for _, p := range info.Mountpoint {
+			s := m.pathShard(p.Path)
+			if s.targets == nil {
+				s.targets = make(map[string][]string)
+			}
+			if s.paths[p.Path] == source {
+				s.targets[p.Path] = append([]string{source}, s.targets[p.Path]...)
+			} else {
+				s.targets[p.Path] = append(s.targets[p.Path], source)
+			}
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package mount
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/libopenstorage/openstorage/pkg/keylock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetIndex(t *testing.T) {
+	m := newTestTable()
+	// dev3 tracks a mountpoint of dev2, as load does for target devices.
+	m.setTestInfo("dev3", &Info{Device: "dev3", Mountpoint: []*PathInfo{{Path: "/mnt/dev2"}}})
+	m.setTestPath("/mnt/dev2", "dev2")
+	m.reindex()
+
+	dev, ok := m.HasTarget("/mnt/dev2")
+	require.True(t, ok)
+	require.Equal(t, "dev2", dev, "Expected the paths map to pick the source")
+
+	m.deletePath("/mnt/dev2", "dev2")
+	dev, ok = m.HasTarget("/mnt/dev2")
+	require.True(t, ok)
+	require.Equal(t, "dev3", dev, "Expected the path to move to the remaining source")
+
+	m.deletePath("/mnt/dev1/a", "dev2")
+	src, err := m.GetSourcePath("/mnt/dev1/a")
+	require.NoError(t, err)
+	require.Equal(t, "dev1", src, "Deleting for another source must not change the index")
+}
+
+// newBenchTable returns a Mounter tracking devices devices with a mountpoint
+// each.
+func newBenchTable(b *testing.B, devices int) *Mounter {
+	m := &Mounter{kl: keylock.New()}
+	m.setOptions(nil)
+	for i := 0; i < devices; i++ {
+		dev := fmt.Sprintf("/dev/bench%d", i)
+		m.setTestInfo(dev, &Info{
+			Device:     dev,
+			Mountpoint: []*PathInfo{{Path: fmt.Sprintf("/mnt/bench%d", i)}},
+		})
+	}
+	m.reindex()
+	return m
+}
+
+func BenchmarkHasTarget(b *testing.B) {
+	for _, devices := range []int{10, 1000} {
+		b.Run(fmt.Sprintf("devices=%d", devices), func(b *testing.B) {
+			m := newBenchTable(b, devices)
+			var n int64
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := atomic.AddInt64(&n, 1) % int64(devices)
+					if _, ok := m.HasTarget(fmt.Sprintf("/mnt/bench%d", i)); !ok {
+						b.Fatal("Expected a target")
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkConcurrentMountUnmount(b *testing.B) {
+	for _, devices := range []int{10, 1000} {
+		b.Run(fmt.Sprintf("devices=%d", devices), func(b *testing.B) {
+			m := newBenchTable(b, devices)
+			m.mountImpl = newTestMountImpl()
+			m.fsops = newTestFsOps()
+			var n int64
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := atomic.AddInt64(&n, 1)
+					dev := fmt.Sprintf("/dev/concurrent%d", i)
+					path := fmt.Sprintf("/mnt/concurrent%d", i)
+					if err := m.mount(0, dev, dev, path, "ext4", 0, "", 0); err != nil {
+						b.Fatal(err)
+					}
+					if err := m.Unmount(dev, path, 0, 0, nil); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkReadsWithWriter(b *testing.B) {
+	m := newBenchTable(b, 1000)
+	m.mountImpl = newTestMountImpl()
+	m.fsops = newTestFsOps()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			dev := fmt.Sprintf("/dev/writer%d", i)
+			path := fmt.Sprintf("/mnt/writer%d", i)
+			if err := m.mount(0, dev, dev, path, "ext4", 0, "", 0); err != nil {
+				b.Error(err)
+				return
+			}
+			if err := m.Unmount(dev, path, 0, 0, nil); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	}()
+	devs := make([]string, 1000)
+	paths := make([]string, 1000)
+	for i := range devs {
+		devs[i] = fmt.Sprintf("/dev/bench%d", i)
+		paths[i] = fmt.Sprintf("/mnt/bench%d", i)
+	}
+	var n int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := atomic.AddInt64(&n, 1) % 1000
+			m.HasTarget(paths[i])
+			m.Mounts(devs[i])
+			if _, err := m.Exists(devs[i], paths[i]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.StopTimer()
+	close(done)
+	<-stopped
+}
@@ -0,0 +1,118 @@
+package mount
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// kernelVersion is the major, minor and patch version of a kernel.
+type kernelVersion struct {
+	major, minor, patch int
+}
+
+func (v kernelVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+}
+
+// atLeast returns true if v is o or later.
+func (v kernelVersion) atLeast(o kernelVersion) bool {
+	if v.major != o.major {
+		return v.major > o.major
+	}
+	if v.minor != o.minor {
+		return v.minor > o.minor
+	}
+	return v.patch >= o.patch
+}
+
+// parseKernelRelease parses a kernel release such as 5.15.0-91-generic.
+func parseKernelRelease(release string) (kernelVersion, error) {
+	numbers := strings.SplitN(release, ".", 3)
+	if len(numbers) < 2 {
+		return kernelVersion{}, fmt.Errorf("invalid kernel release %q: %w", release, ErrEinval)
+	}
+	var v [3]int
+	for i, n := range numbers {
+		// The patch version may be followed by a suffix.
+		if end := strings.IndexFunc(n, func(r rune) bool { return r < '0' || r > '9' }); end >= 0 {
+			n = n[:end]
+		}
+		var err error
+		if v[i], err = strconv.Atoi(n); err != nil {
+			return kernelVersion{}, fmt.Errorf("invalid kernel release %q: %w", release, ErrEinval)
+		}
+	}
+	return kernelVersion{v[0], v[1], v[2]}, nil
+}
+
+// Kernel features of KernelSupports.
+const (
+	// KernelFeatureLazytime is the lazytime mount option.
+	KernelFeatureLazytime = "lazytime"
+	// KernelFeatureNosymfollow is the nosymfollow mount option.
+	KernelFeatureNosymfollow = "nosymfollow"
+	// KernelFeatureNewMountAPI is open_tree, move_mount and fsopen.
+	KernelFeatureNewMountAPI = "newmountapi"
+	// KernelFeatureIdmap is idmapped mounts with mount_setattr.
+	KernelFeatureIdmap = "idmap"
+)
+
+// kernelFeatures are the Linux versions introducing the kernel features.
+var kernelFeatures = map[string]kernelVersion{
+	KernelFeatureLazytime:    {4, 0, 0},
+	KernelFeatureNewMountAPI: {5, 2, 0},
+	KernelFeatureNosymfollow: {5, 10, 0},
+	KernelFeatureIdmap:       {5, 12, 0},
+}
+
+// kernelFlag is a mount flag of a kernel feature.
+type kernelFlag struct {
+	feature string
+	flag    uintptr
+}
+
+// withKernelVersion sets the kernel version check, used by tests.
+func withKernelVersion(version func() (kernelVersion, error)) MounterOption {
+	return func(m *Mounter) {
+		m.kernelVersion = version
+	}
+}
+
+// kernel returns the version of the running kernel, detected once.
+func (m *Mounter) kernel() (kernelVersion, error) {
+	m.kernelOnce.Do(func() {
+		m.kernelV, m.kernelErr = m.kernelVersion()
+	})
+	return m.kernelV, m.kernelErr
+}
+
+// KernelSupports returns true if the running kernel has feature, one of the
+// KernelFeature constants. It returns false if the kernel version is unknown,
+// as on other platforms than Linux.
+func (m *Mounter) KernelSupports(feature string) bool {
+	since, ok := kernelFeatures[feature]
+	if !ok {
+		return false
+	}
+	v, err := m.kernel()
+	return err == nil && v.atLeast(since)
+}
+
+// dropUnsupportedFlags clears the kernelFlags in flags that the running kernel
+// does not support, and would fail the mount with EINVAL, with a warning.
+func (m *Mounter) dropUnsupportedFlags(flags uintptr) uintptr {
+	for _, f := range kernelFlags {
+		if flags&f.flag == 0 || m.KernelSupports(f.feature) {
+			continue
+		}
+		if v, err := m.kernel(); err != nil {
+			m.logger.Warnf("Ignoring mount option %s, the kernel version is unknown: %v", f.feature, err)
+		} else {
+			m.logger.Warnf("Ignoring mount option %s, it needs kernel %s and the kernel is %s",
+				f.feature, kernelFeatures[f.feature], v)
+		}
+		flags &^= f.flag
+	}
+	return flags
+}
@@ -0,0 +1,87 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseKernelRelease(t *testing.T) {
+	for release, expected := range map[string]kernelVersion{
+		"5.15.0-91-generic":        {5, 15, 0},
+		"6.1.55+":                  {6, 1, 55},
+		"4.18.0-513.el8.x86_64":    {4, 18, 0},
+		"5.10":                     {5, 10, 0},
+		"6.18.44-fc-v130":          {6, 18, 44},
+		"3.10.0-1160.el7.x86_64":   {3, 10, 0},
+		"5.4.0-1103-aws-fips-1.0a": {5, 4, 0},
+	} {
+		v, err := parseKernelRelease(release)
+		require.NoError(t, err, release)
+		require.Equal(t, expected, v, release)
+	}
+	for _, release := range []string{"", "5", "linux-5.10", "5.x.1"} {
+		_, err := parseKernelRelease(release)
+		require.True(t, errors.Is(err, ErrEinval), "Expected %q to be rejected", release)
+	}
+
+	require.True(t, kernelVersion{5, 10, 0}.atLeast(kernelVersion{5, 10, 0}))
+	require.True(t, kernelVersion{6, 0, 0}.atLeast(kernelVersion{5, 10, 0}))
+	require.False(t, kernelVersion{5, 4, 200}.atLeast(kernelVersion{5, 10, 0}))
+
+	_, err := hostKernelVersion()
+	require.NoError(t, err)
+}
+
+// newKernelTestMounter returns a test Mounter on a kernel of version v, or
+// of an unknown version if v is nil.
+func newKernelTestMounter(t *testing.T, v *kernelVersion) (*deviceMounter, *testMountImpl, *int) {
+	calls := 0
+	m, mi := newTestMounter(t, withKernelVersion(func() (kernelVersion, error) {
+		calls++
+		if v == nil {
+			return kernelVersion{}, errors.New("uname failed")
+		}
+		return *v, nil
+	}))
+	return m, mi, &calls
+}
+
+func TestKernelSupports(t *testing.T) {
+	m, _, calls := newKernelTestMounter(t, &kernelVersion{5, 10, 0})
+	require.True(t, m.KernelSupports(KernelFeatureLazytime))
+	require.True(t, m.KernelSupports(KernelFeatureNewMountAPI))
+	require.True(t, m.KernelSupports(KernelFeatureNosymfollow))
+	require.False(t, m.KernelSupports(KernelFeatureIdmap))
+	require.False(t, m.KernelSupports("unknown"))
+	require.Equal(t, 1, *calls, "Expected the kernel version to be detected once")
+
+	m, _, _ = newKernelTestMounter(t, &kernelVersion{6, 1, 0})
+	require.True(t, m.KernelSupports(KernelFeatureIdmap))
+	m, _, _ = newKernelTestMounter(t, nil)
+	require.False(t, m.KernelSupports(KernelFeatureLazytime))
+}
+
+func TestMountDropsUnsupportedFlags(t *testing.T) {
+	o := MountOptions{Device: "/dev/k", Path: "/mnt/k", Fs: "ext4", Flags: unix.MS_LAZYTIME | syscall.MS_NOSUID, NoSymFollow: true}
+
+	m, mi, _ := newKernelTestMounter(t, &kernelVersion{5, 4, 0})
+	require.NoError(t, m.MountWithOptions(o))
+	require.Equal(t, uintptr(unix.MS_LAZYTIME|syscall.MS_NOSUID), mi.lastCall().flags)
+	require.Equal(t, uintptr(unix.MS_LAZYTIME|syscall.MS_NOSUID), m.Inspect("/dev/k")[0].Flags)
+
+	m, mi, _ = newKernelTestMounter(t, &kernelVersion{5, 10, 0})
+	require.NoError(t, m.MountWithOptions(o))
+	require.Equal(t, uintptr(unix.MS_LAZYTIME|unix.MS_NOSYMFOLLOW|syscall.MS_NOSUID), mi.lastCall().flags)
+
+	// Version gated flags are dropped if the version is unknown.
+	m, mi, _ = newKernelTestMounter(t, nil)
+	require.NoError(t, m.MountWithOptions(o))
+	require.Equal(t, uintptr(syscall.MS_NOSUID), mi.lastCall().flags)
+}
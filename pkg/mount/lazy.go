@@ -0,0 +1,48 @@
+package mount
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// DetachedMount is a mountpoint that was lazily detached by Unmount
+// because it was busy. The filesystem lingers until it is no longer used.
+type DetachedMount struct {
+	Device     string
+	Path       string
+	DetachedAt time.Time
+}
+
+// DetachedMounts returns the mountpoints lazily detached by Unmount with
+// options.OptionsUnmountLazyFallback, oldest first.
+func (m *Mounter) DetachedMounts() []DetachedMount {
+	m.RLock()
+	defer m.RUnlock()
+	return append([]DetachedMount(nil), m.detached...)
+}
+
+// unmountLazyFallback unmounts path, and detaches it lazily if it is busy.
+// It returns true if it was detached lazily.
+func (m *Mounter) unmountLazyFallback(path string, flags, timeout int) (bool, error) {
+	err := m.mountImpl.Unmount(path, flags, timeout)
+	if err == nil || !errors.Is(err, syscall.EBUSY) || mntDetach == 0 || flags&mntDetach != 0 {
+		return false, err
+	}
+	m.logger.Warnf("%s is busy, detaching it lazily: %v", path, err)
+	if err := m.mountImpl.Unmount(path, flags|mntDetach, timeout); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// addDetachedMount records that device was lazily detached from path.
+func (m *Mounter) addDetachedMount(device, path string) {
+	m.Lock()
+	defer m.Unlock()
+	m.detached = append(m.detached, DetachedMount{
+		Device:     device,
+		Path:       path,
+		DetachedAt: m.clock.Now(),
+	})
+}
@@ -0,0 +1,50 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/libopenstorage/openstorage/pkg/options"
+	"github.com/stretchr/testify/require"
+)
+
+// busyMountImpl fails unmounts with EBUSY unless they are lazy, and records
+// the unmount flags.
+type busyMountImpl struct {
+	*testMountImpl
+	flags []int
+}
+
+func (b *busyMountImpl) Unmount(target string, flags int, timeout int) error {
+	b.flags = append(b.flags, flags)
+	if flags&syscall.MNT_DETACH == 0 {
+		return syscall.EBUSY
+	}
+	return b.testMountImpl.Unmount(target, flags, timeout)
+}
+
+func TestUnmountLazyFallback(t *testing.T) {
+	clk := newTestClock()
+	mi := &busyMountImpl{testMountImpl: newTestMountImpl()}
+	m, err := NewDeviceMounter(nil, mi, nil, "", withFsOps(newTestFsOps()), withDeviceCheck(nil), WithClock(clk))
+	require.NoError(t, err)
+	require.NoError(t, m.Mount(0, "/dev/busy", "/mnt/a", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/dev/busy", "/mnt/b", "ext4", 0, "", 0, nil))
+
+	err = m.Unmount("/dev/busy", "/mnt/a", 0, 0, nil)
+	require.True(t, errors.Is(err, syscall.EBUSY), "Expected no fallback without the option")
+	require.Equal(t, 2, m.HasMounts("/dev/busy"))
+	require.Empty(t, m.DetachedMounts())
+
+	mi.flags = nil
+	require.NoError(t, m.Unmount("/dev/busy", "/mnt/a", 0, 0,
+		map[string]string{options.OptionsUnmountLazyFallback: "true"}))
+	require.Equal(t, []int{0, syscall.MNT_DETACH}, mi.flags, "Expected one lazy retry")
+	require.Equal(t, []string{"/mnt/b"}, m.Mounts("/dev/busy"))
+	require.Equal(t, []DetachedMount{{Device: "/dev/busy", Path: "/mnt/a", DetachedAt: clk.Now()}},
+		m.DetachedMounts())
+}
@@ -0,0 +1,58 @@
+package mount
+
+import (
+	"fmt"
+	"time"
+)
+
+// WithMaxConcurrentMounts limits the number of mounts in progress in the
+// MountImpl to n. Further mounts wait for one to complete, for at most their
+// timeout in seconds if it is positive, and then fail with ErrMountTimeout.
+// Table bookkeeping is not limited. A limit of zero or less is no limit.
+func WithMaxConcurrentMounts(n int) MounterOption {
+	return func(m *Mounter) {
+		if n <= 0 {
+			m.mountSlots = nil
+			return
+		}
+		m.mountSlots = make(chan struct{}, n)
+	}
+}
+
+// backendMount calls the MountImpl once a mount slot is available.
+func (m *Mounter) backendMount(
+	source, target, fstype string,
+	flags uintptr,
+	data string,
+	timeout int,
+) error {
+	if m.mountSlots != nil {
+		if err := m.acquireMountSlot(timeout); err != nil {
+			return err
+		}
+		defer func() { <-m.mountSlots }()
+	}
+	return m.mountImpl.Mount(source, target, fstype, flags, data, timeout)
+}
+
+// acquireMountSlot waits for a mount slot, for at most timeout seconds if it
+// is positive.
+func (m *Mounter) acquireMountSlot(timeout int) error {
+	select {
+	case m.mountSlots <- struct{}{}:
+		return nil
+	default:
+	}
+	if timeout <= 0 {
+		m.mountSlots <- struct{}{}
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(timeout) * time.Second)
+	defer timer.Stop()
+	select {
+	case m.mountSlots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("no mount slot available after %ds: %w", timeout, ErrMountTimeout)
+	}
+}
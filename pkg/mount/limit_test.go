@@ -0,0 +1,90 @@
+package mount
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// blockingMountImpl blocks mounts until release is closed and records the
+// highest number of mounts in progress.
+type blockingMountImpl struct {
+	*testMountImpl
+	release  chan struct{}
+	inFlight int32
+	max      int32
+}
+
+func (b *blockingMountImpl) Mount(source, target, fstype string, flags uintptr, data string, timeout int) error {
+	n := atomic.AddInt32(&b.inFlight, 1)
+	for {
+		max := atomic.LoadInt32(&b.max)
+		if n <= max || atomic.CompareAndSwapInt32(&b.max, max, n) {
+			break
+		}
+	}
+	<-b.release
+	atomic.AddInt32(&b.inFlight, -1)
+	return b.testMountImpl.Mount(source, target, fstype, flags, data, timeout)
+}
+
+func newBlockingMounter(t *testing.T, limit int) (*deviceMounter, *blockingMountImpl) {
+	mi := &blockingMountImpl{testMountImpl: newTestMountImpl(), release: make(chan struct{})}
+	m, err := NewDeviceMounter(nil, mi, nil, "",
+		withFsOps(newTestFsOps()), withDeviceCheck(nil), WithMaxConcurrentMounts(limit))
+	require.NoError(t, err)
+	return m, mi
+}
+
+func TestMaxConcurrentMounts(t *testing.T) {
+	m, mi := newBlockingMounter(t, 2)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 6)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dev := fmt.Sprintf("/dev/limit%d", i)
+			errs <- m.Mount(0, dev, "/mnt/limit"+dev, "nfs", 0, "", 0, nil)
+		}(i)
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&mi.inFlight) == 2 },
+		5*time.Second, time.Millisecond)
+	// Give the queued mounts a chance to get in.
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, int32(2), atomic.LoadInt32(&mi.inFlight))
+
+	close(mi.release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&mi.max), "Expected at most 2 mounts in flight")
+	require.Len(t, m.GetSourcePaths(), 6)
+}
+
+func TestMaxConcurrentMountsTimeout(t *testing.T) {
+	m, mi := newBlockingMounter(t, 1)
+
+	done := make(chan error)
+	go func() {
+		done <- m.Mount(0, "/dev/limit0", "/mnt/limit0", "nfs", 0, "", 0, nil)
+	}()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&mi.inFlight) == 1 },
+		5*time.Second, time.Millisecond)
+
+	err := m.Mount(0, "/dev/limit1", "/mnt/limit1", "nfs", 0, "", 1, nil)
+	require.True(t, errors.Is(err, ErrMountTimeout), "got %v", err)
+	require.Equal(t, 0, m.HasMounts("/dev/limit1"))
+
+	close(mi.release)
+	require.NoError(t, <-done)
+	require.NoError(t, m.Mount(0, "/dev/limit1", "/mnt/limit1", "nfs", 0, "", 1, nil))
+}
@@ -0,0 +1,84 @@
+package mount
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// MountEntry describes a mountpoint of a tracked device.
+type MountEntry struct {
+	// Source is the key the device is tracked under.
+	Source    string
+	Device    string
+	Minor     int
+	Fs        string
+	Root      string
+	Path      string
+	Flags     uintptr
+	ReadOnly  bool
+	MountedAt time.Time
+}
+
+// List returns all tracked mountpoints sorted by source and path.
+func (m *Mounter) List() []MountEntry {
+	return m.listFiltered(func(*Info, *PathInfo) bool { return true })
+}
+
+// MountsByMinor returns the mountpoints of the devices recorded with minor.
+func (m *Mounter) MountsByMinor(minor int) []MountEntry {
+	return m.listFiltered(func(info *Info, _ *PathInfo) bool {
+		return info.Minor == minor
+	})
+}
+
+// MountsByFs returns the mountpoints of the devices with filesystem fs,
+// compared case-insensitively. Variants of a filesystem such as nfs and nfs4
+// are distinct.
+func (m *Mounter) MountsByFs(fs string) []MountEntry {
+	return m.listFiltered(func(info *Info, _ *PathInfo) bool {
+		return strings.EqualFold(info.Fs, fs)
+	})
+}
+
+// MountsOlderThan returns the mountpoints mounted through the Mounter more
+// than d ago. Mounts loaded from the mount table have no mount time and are
+// never returned.
+func (m *Mounter) MountsOlderThan(d time.Duration) []MountEntry {
+	cutoff := m.clock.Now().Add(-d)
+	return m.listFiltered(func(_ *Info, p *PathInfo) bool {
+		return !p.MountedAt.IsZero() && p.MountedAt.Before(cutoff)
+	})
+}
+
+// listFiltered returns the mountpoints for which keep returns true.
+func (m *Mounter) listFiltered(keep func(*Info, *PathInfo) bool) []MountEntry {
+	entries := make([]MountEntry, 0)
+	for source, info := range m.devices() {
+		info.Lock()
+		for _, p := range info.Mountpoint {
+			if !keep(info, p) {
+				continue
+			}
+			entries = append(entries, MountEntry{
+				Source:    source,
+				Device:    info.Device,
+				Minor:     info.Minor,
+				Fs:        info.Fs,
+				Root:      p.Root,
+				Path:      p.Path,
+				Flags:     p.Flags,
+				ReadOnly:  p.ReadOnly,
+				MountedAt: p.MountedAt,
+			})
+		}
+		info.Unlock()
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Source != entries[j].Source {
+			return entries[i].Source < entries[j].Source
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
@@ -0,0 +1,99 @@
+package mount
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountsOlderThan(t *testing.T) {
+	clk := newTestClock()
+	m, _ := newTestMounter(t, WithClock(clk))
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	for _, p := range paths {
+		require.NoError(t, os.Mkdir(p, 0755))
+	}
+	start := clk.Now()
+
+	require.NoError(t, m.Mount(0, "/dev/sda", paths[0], "ext4", 0, "", 0, nil))
+	clk.Advance(time.Hour)
+	require.NoError(t, m.Mount(0, "/dev/sdb", paths[1], "xfs", 0, "", 0, nil))
+	require.Equal(t, start, m.Inspect("/dev/sda")[0].MountedAt)
+
+	require.Equal(t, []MountEntry{
+		{Source: "/dev/sda", Device: "/dev/sda", Fs: "ext4", Path: paths[0], MountedAt: start},
+		{Source: "/dev/sdb", Device: "/dev/sdb", Fs: "xfs", Path: paths[1], MountedAt: start.Add(time.Hour)},
+	}, m.List())
+
+	clk.Advance(30 * time.Minute)
+	old := m.MountsOlderThan(time.Hour)
+	require.Len(t, old, 1)
+	require.Equal(t, paths[0], old[0].Path)
+	require.Len(t, m.MountsOlderThan(time.Minute), 2)
+	require.Empty(t, m.MountsOlderThan(2*time.Hour))
+}
+
+func TestMountsOlderThanLoaded(t *testing.T) {
+	m := newTestTable()
+	require.Len(t, m.List(), 3)
+	require.Empty(t, m.MountsOlderThan(0), "Expected loaded mounts without a mount time to be skipped")
+}
+
+func TestMountsByMinor(t *testing.T) {
+	m := newTestTable()
+	m.testInfo("dev1").Minor = 7
+	m.testInfo("dev2").Minor = 7
+	m.setTestInfo("dev3", &Info{
+		Device:     "dev3",
+		Minor:      8,
+		Fs:         "ext4",
+		Mountpoint: []*PathInfo{{Path: "/mnt/dev3"}},
+	})
+
+	entries := m.MountsByMinor(7)
+	require.Len(t, entries, 3)
+	for _, e := range entries {
+		require.Equal(t, 7, e.Minor)
+		require.Contains(t, []string{"dev1", "dev2"}, e.Source)
+	}
+	require.Equal(t, []MountEntry{
+		{Source: "dev3", Device: "dev3", Minor: 8, Fs: "ext4", Path: "/mnt/dev3"},
+	}, m.MountsByMinor(8))
+	require.Empty(t, m.MountsByMinor(9))
+}
+
+func TestMountsByFs(t *testing.T) {
+	m := newTestTable()
+	m.setTestInfo("nfs1", &Info{
+		Device:     "nfs1",
+		Fs:         "nfs",
+		Mountpoint: []*PathInfo{{Path: "/mnt/nfs1"}},
+	})
+	m.setTestInfo("nfs2", &Info{
+		Device:     "nfs2",
+		Fs:         "nfs4",
+		Mountpoint: []*PathInfo{{Path: "/mnt/nfs2"}},
+	})
+	m.setTestInfo("dev3", &Info{
+		Device:     "dev3",
+		Fs:         "EXT4",
+		Mountpoint: []*PathInfo{{Path: "/mnt/dev3"}},
+	})
+
+	paths := func(entries []MountEntry) []string {
+		var p []string
+		for _, e := range entries {
+			p = append(p, e.Path)
+		}
+		return p
+	}
+	require.Equal(t, []string{"/mnt/dev1/a", "/mnt/dev1/b", "/mnt/dev3"}, paths(m.MountsByFs("ext4")))
+	require.Equal(t, []string{"/mnt/dev2"}, paths(m.MountsByFs("XFS")))
+	require.Equal(t, []string{"/mnt/nfs1"}, paths(m.MountsByFs("nfs")))
+	require.Equal(t, []string{"/mnt/nfs2"}, paths(m.MountsByFs("nfs4")))
+	require.Empty(t, m.MountsByFs("tmpfs"))
+}
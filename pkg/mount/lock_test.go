@@ -0,0 +1,145 @@
+package mount
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestConcurrentMountUnmount mounts and unmounts paths of a few shared
+// devices from many goroutines while others read the table. Run with -race.
+func TestConcurrentMountUnmount(t *testing.T) {
+	const (
+		devices = 4
+		workers = 16
+		rounds  = 50
+	)
+	m, _ := newTestMounter(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			dev := fmt.Sprintf("/dev/stress%d", w%devices)
+			path := fmt.Sprintf("/mnt/stress%d", w)
+			for i := 0; i < rounds; i++ {
+				if err := m.Mount(0, dev, path, "ext4", 0, "", 0, nil); err != nil {
+					errs <- err
+					return
+				}
+				if err := m.Unmount(dev, path, 0, 0, nil); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func(r int) {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				m.HasTarget(fmt.Sprintf("/mnt/stress%d", r))
+				m.Mounts(fmt.Sprintf("/dev/stress%d", r))
+				m.List()
+				_ = m.String()
+				_ = m.Dump()
+				require.NoError(t, m.Save(ioutil.Discard))
+			}
+		}(r)
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	require.Empty(t, m.List(), "Expected all mounts to be removed")
+	require.Empty(t, m.targetPaths(), "Expected the index to be empty")
+}
+
+// TestStringConcurrentWithMounts calls String in a tight loop while another
+// goroutine mounts and unmounts. Run with -race.
+func TestStringConcurrentWithMounts(t *testing.T) {
+	m, _ := newTestMounter(t)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			path := fmt.Sprintf("/mnt/string%d", i%4)
+			if err := m.Mount(0, "/dev/string", path, "ext4", 0, "", 0, nil); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := m.Unmount("/dev/string", path, 0, 0, nil); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			require.Equal(t, "Mounter with 0 devices and 0 mountpoints", m.String())
+			return
+		default:
+			require.Contains(t, m.String(), "Mounter with")
+		}
+	}
+}
+
+// TestConcurrentMountSameDevice mounts a new device at many paths at once.
+// All the mounts must be tracked under one Info. Run with -race.
+func TestConcurrentMountSameDevice(t *testing.T) {
+	const (
+		workers = 16
+		rounds  = 20
+	)
+	m, _ := newTestMounter(t)
+
+	for i := 0; i < rounds; i++ {
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				<-start
+				path := fmt.Sprintf("/mnt/same%d", w)
+				require.NoError(t, m.Mount(0, "/dev/same", path, "ext4", 0, "", 0, nil))
+			}(w)
+		}
+		close(start)
+		wg.Wait()
+
+		require.Equal(t, workers, m.HasMounts("/dev/same"))
+		info := m.testInfo("/dev/same")
+		for w := 0; w < workers; w++ {
+			dev, ok := m.HasTarget(fmt.Sprintf("/mnt/same%d", w))
+			require.True(t, ok)
+			require.Equal(t, "/dev/same", dev)
+		}
+		info.Lock()
+		require.Len(t, info.Mountpoint, workers, "Expected all mounts in one Info")
+		info.Unlock()
+
+		for w := 0; w < workers; w++ {
+			require.NoError(t, m.Unmount("/dev/same", fmt.Sprintf("/mnt/same%d", w), 0, 0, nil))
+		}
+		require.Equal(t, 0, m.HasMounts("/dev/same"))
+	}
+}
@@ -0,0 +1,132 @@
+package mount
+
+import (
+	"sync"
+	"time"
+
+	"github.com/libopenstorage/openstorage/pkg/keylock"
+)
+
+// LockStats are the counters of the acquisitions of a key of the Mounter's
+// keylock, a path or a device.
+type LockStats struct {
+	// Acquisitions is the number of times the key was acquired.
+	Acquisitions uint64
+	// Contended is the number of acquisitions that found the key held or
+	// waited on.
+	Contended uint64
+	// WaitTime is the total time spent waiting for the key.
+	WaitTime time.Duration
+}
+
+// WithLockMetrics instruments the keylock serializing the operations on paths
+// and devices, so that contended paths can be found. hook, if not nil, is
+// called after every acquisition with the key and the time waited for it.
+// Wait times are measured with the clock of the Mounter. The counters are
+// returned by LockStats. Device keys are prefixed with "device:".
+func WithLockMetrics(hook func(key string, wait time.Duration)) MounterOption {
+	return func(m *Mounter) {
+		m.kl = newInstrumentedKeyLock(m.kl, hook, func() time.Time {
+			return m.clock.Now()
+		})
+	}
+}
+
+// LockStats returns the counters of the keylock by key since the previous
+// call, or nil if the Mounter was not created WithLockMetrics. The counters
+// are dropped once returned, so that keys of paths no longer used are not
+// kept. LockStats should be called periodically when many paths are
+// mounted.
+func (m *Mounter) LockStats() map[string]LockStats {
+	ikl, ok := m.kl.(*instrumentedKeyLock)
+	if !ok {
+		return nil
+	}
+	return ikl.snapshot()
+}
+
+// instrumentedKeyLock is a keylock.KeyLock counting the acquisitions of its
+// keys and the time waited for them.
+type instrumentedKeyLock struct {
+	keylock.KeyLock
+	hook func(key string, wait time.Duration)
+	now  func() time.Time
+
+	mu sync.Mutex
+	// stats are the counters since the last snapshot.
+	stats map[string]*LockStats
+	// inflight are the holders and waiters of each key.
+	inflight map[string]int
+	// held are the keys of the handles not released yet.
+	held map[keylock.LockHandle]string
+}
+
+func newInstrumentedKeyLock(
+	kl keylock.KeyLock,
+	hook func(key string, wait time.Duration),
+	now func() time.Time,
+) *instrumentedKeyLock {
+	return &instrumentedKeyLock{
+		KeyLock:  kl,
+		hook:     hook,
+		now:      now,
+		stats:    make(map[string]*LockStats),
+		inflight: make(map[string]int),
+		held:     make(map[keylock.LockHandle]string),
+	}
+}
+
+// Acquire acquires id and records the wait.
+func (k *instrumentedKeyLock) Acquire(id string) keylock.LockHandle {
+	k.mu.Lock()
+	contended := k.inflight[id] > 0
+	k.inflight[id]++
+	start := k.now()
+	k.mu.Unlock()
+
+	h := k.KeyLock.Acquire(id)
+	wait := k.now().Sub(start)
+
+	k.mu.Lock()
+	k.held[h] = id
+	s, ok := k.stats[id]
+	if !ok {
+		s = &LockStats{}
+		k.stats[id] = s
+	}
+	s.Acquisitions++
+	if contended {
+		s.Contended++
+	}
+	s.WaitTime += wait
+	k.mu.Unlock()
+	if k.hook != nil {
+		k.hook(id, wait)
+	}
+	return h
+}
+
+// Release releases the key of h.
+func (k *instrumentedKeyLock) Release(h *keylock.LockHandle) error {
+	k.mu.Lock()
+	if id, ok := k.held[*h]; ok {
+		delete(k.held, *h)
+		if k.inflight[id]--; k.inflight[id] == 0 {
+			delete(k.inflight, id)
+		}
+	}
+	k.mu.Unlock()
+	return k.KeyLock.Release(h)
+}
+
+// snapshot returns the counters since the last snapshot and resets them.
+func (k *instrumentedKeyLock) snapshot() map[string]LockStats {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	stats := make(map[string]LockStats, len(k.stats))
+	for id, s := range k.stats {
+		stats[id] = *s
+	}
+	k.stats = make(map[string]*LockStats)
+	return stats
+}
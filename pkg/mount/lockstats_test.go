@@ -0,0 +1,61 @@
+package mount
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockStats(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		waits = make(map[string]int)
+	)
+	hook := func(key string, wait time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		waits[key]++
+	}
+	clk := newTestClock()
+	m, _ := newTestMounter(t, WithLockMetrics(hook), WithClock(clk))
+	require.NoError(t, m.Mount(0, "/dev/dev", "/mnt/dev", "ext4", 0, "", 0, nil))
+
+	stats := m.LockStats()
+	require.Equal(t, uint64(1), stats["/mnt/dev"].Acquisitions)
+	require.Equal(t, uint64(0), stats["/mnt/dev"].Contended)
+	require.Equal(t, uint64(1), stats[deviceLockKey("/dev/dev")].Acquisitions)
+	require.Equal(t, 1, waits["/mnt/dev"])
+	require.Empty(t, m.LockStats(), "Expected the counters to be dropped once returned")
+
+	// Hold the path so that the unmount waits for it.
+	h := m.kl.Acquire("/mnt/dev")
+	done := make(chan error)
+	go func() {
+		done <- m.Unmount("/dev/dev", "/mnt/dev", 0, 0, nil)
+	}()
+	require.Eventually(t, func() bool {
+		ikl := m.kl.(*instrumentedKeyLock)
+		ikl.mu.Lock()
+		defer ikl.mu.Unlock()
+		return ikl.inflight["/mnt/dev"] == 2
+	}, time.Second, time.Millisecond)
+	clk.Advance(10 * time.Millisecond)
+	require.NoError(t, m.kl.Release(&h))
+	require.NoError(t, <-done)
+
+	stats = m.LockStats()
+	path := stats["/mnt/dev"]
+	require.Equal(t, uint64(2), path.Acquisitions)
+	require.Equal(t, uint64(1), path.Contended)
+	require.Equal(t, 10*time.Millisecond, path.WaitTime)
+	require.Len(t, stats, 2, "Expected only the keys acquired since the last call")
+	require.Equal(t, uint64(1), stats[deviceLockKey("/dev/dev")].Acquisitions)
+}
+
+func TestLockStatsDisabled(t *testing.T) {
+	m, _ := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/dev/dev", "/mnt/dev", "ext4", 0, "", 0, nil))
+	require.Nil(t, m.LockStats())
+}
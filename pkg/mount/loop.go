@@ -0,0 +1,67 @@
+package mount
+
+import (
+	"fmt"
+)
+
+// loopDevices attaches image files to loop block devices.
+type loopDevices interface {
+	// Attach binds image to a free loop device and returns the device path.
+	Attach(image string, readOnly bool) (string, error)
+	// Detach releases the loop device.
+	Detach(device string) error
+}
+
+// withLoopDevices sets the loop device implementation, used by tests.
+func withLoopDevices(loop loopDevices) MounterOption {
+	return func(m *Mounter) {
+		m.loop = loop
+	}
+}
+
+// LoopMount attaches imagePath to a free loop device and mounts it at target
+// with filesystem fs. The mount is tracked under imagePath and the loop device
+// is recorded in its Info, so that Unmount of the last mountpoint detaches it.
+// If imagePath is already attached, its loop device is reused. ErrEinval is
+// returned for a writable mount of a read-only filesystem such as squashfs.
+func (m *Mounter) LoopMount(imagePath, target, fs string, readOnly bool, timeout int) error {
+	if fs == squashfsType && !readOnly {
+		return fmt.Errorf("%s is read-only: %w", fs, ErrEinval)
+	}
+	var flags uintptr
+	if readOnly {
+		flags |= msRdonly
+	}
+	// The loop device is attached under the device lock of imagePath, which
+	// Unmount takes to detach it.
+	attach := func(info *Info) (*lockedSource, error) {
+		if info.LoopDevice != "" {
+			return &lockedSource{devPath: info.LoopDevice}, nil
+		}
+		dev, err := m.loop.Attach(imagePath, readOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to attach %s to a loop device: %w", imagePath, err)
+		}
+		return &lockedSource{
+			devPath: dev,
+			record:  func(info *Info) { info.LoopDevice = dev },
+			release: func() {
+				if e := m.loop.Detach(dev); e != nil {
+					m.logger.Warnf("Failed to detach loop device %s: %v", dev, e)
+				}
+			},
+		}, nil
+	}
+	return m.mount(0, "", imagePath, target, fs, flags, "", timeout, withLockedSource(attach))
+}
+
+// detachLoop detaches the loop device of info, if it has one.
+func (m *Mounter) detachLoop(info *Info) error {
+	if info == nil || info.LoopDevice == "" {
+		return nil
+	}
+	if err := m.loop.Detach(info.LoopDevice); err != nil {
+		return fmt.Errorf("failed to detach loop device %s: %w", info.LoopDevice, err)
+	}
+	return nil
+}
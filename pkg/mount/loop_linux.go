@@ -0,0 +1,71 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	loopControlPath = "/dev/loop-control"
+	// loopAttachRetries bounds the retries when another process grabs the
+	// free loop device between LOOP_CTL_GET_FREE and LOOP_SET_FD.
+	loopAttachRetries = 5
+)
+
+// defaultLoopDevices attaches loop devices through /dev/loop-control.
+var defaultLoopDevices loopDevices = ioctlLoopDevices{}
+
+// ioctlLoopDevices implements loopDevices with the loop ioctls.
+type ioctlLoopDevices struct{}
+
+func (ioctlLoopDevices) Attach(image string, readOnly bool) (string, error) {
+	flag := os.O_RDWR
+	if readOnly {
+		flag = os.O_RDONLY
+	}
+	img, err := os.OpenFile(image, flag, 0)
+	if err != nil {
+		return "", err
+	}
+	defer img.Close()
+
+	ctl, err := os.OpenFile(loopControlPath, os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer ctl.Close()
+
+	for i := 0; ; i++ {
+		n, err := unix.IoctlRetInt(int(ctl.Fd()), unix.LOOP_CTL_GET_FREE)
+		if err != nil {
+			return "", err
+		}
+		device := fmt.Sprintf("/dev/loop%d", n)
+		loop, err := os.OpenFile(device, flag, 0)
+		if err != nil {
+			return "", err
+		}
+		err = unix.IoctlSetInt(int(loop.Fd()), unix.LOOP_SET_FD, int(img.Fd()))
+		loop.Close()
+		if err == nil {
+			return device, nil
+		}
+		if err != unix.EBUSY || i >= loopAttachRetries {
+			return "", err
+		}
+	}
+}
+
+func (ioctlLoopDevices) Detach(device string) error {
+	loop, err := os.OpenFile(device, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer loop.Close()
+	return unix.IoctlSetInt(int(loop.Fd()), unix.LOOP_CLR_FD, 0)
+}
@@ -0,0 +1,99 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIoctlLoopDevices(t *testing.T) {
+	if _, err := os.Stat(loopControlPath); err != nil {
+		t.Skipf("%s not available: %v", loopControlPath, err)
+	}
+	image := filepath.Join(t.TempDir(), "disk.img")
+	require.NoError(t, ioutil.WriteFile(image, make([]byte, 1<<20), 0644))
+
+	loop := ioctlLoopDevices{}
+	device, err := loop.Attach(image, true)
+	if os.IsPermission(err) {
+		t.Skipf("Cannot attach loop devices: %v", err)
+	}
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(device, "/dev/loop"), device)
+
+	backing, err := ioutil.ReadFile(filepath.Join("/sys/block",
+		filepath.Base(device), "loop", "backing_file"))
+	require.NoError(t, err)
+	require.Equal(t, image, strings.TrimSpace(string(backing)))
+
+	require.NoError(t, loop.Detach(device))
+}
+
+func TestLoopMountExt4(t *testing.T) {
+	if _, err := os.Stat(loopControlPath); err != nil {
+		t.Skipf("%s not available: %v", loopControlPath, err)
+	}
+	mkfs, err := exec.LookPath("mkfs.ext4")
+	if err != nil {
+		t.Skip("mkfs.ext4 not available")
+	}
+	dir := t.TempDir()
+	image := filepath.Join(dir, "disk.img")
+	target := filepath.Join(dir, "mnt")
+	require.NoError(t, ioutil.WriteFile(image, make([]byte, 8<<20), 0644))
+	require.NoError(t, os.Mkdir(target, 0755))
+	out, err := exec.Command(mkfs, "-q", "-F", image).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	m, err := NewDeviceMounter(nil, &DefaultMounter{}, nil, "", withFsOps(newTestFsOps()))
+	require.NoError(t, err)
+	require.NoError(t, m.LoopMount(image, target, "ext4", false, 0))
+	device := m.testInfo(image).LoopDevice
+	require.NotEmpty(t, device)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(target, "file"), []byte("data"), 0644))
+
+	require.NoError(t, m.Unmount(image, target, 0, 0, nil))
+	_, err = os.Stat(filepath.Join("/sys/block", filepath.Base(device), "loop"))
+	require.True(t, os.IsNotExist(err), "Expected %s to be detached", device)
+}
+
+func TestSquashfsMountImage(t *testing.T) {
+	if _, err := os.Stat(loopControlPath); err != nil {
+		t.Skipf("%s not available: %v", loopControlPath, err)
+	}
+	mksquashfs, err := exec.LookPath("mksquashfs")
+	if err != nil {
+		t.Skip("mksquashfs not available")
+	}
+	dir := t.TempDir()
+	content := filepath.Join(dir, "content")
+	image := filepath.Join(dir, "content.sqfs")
+	target := filepath.Join(dir, "mnt")
+	require.NoError(t, os.Mkdir(content, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(content, "file"), []byte("data"), 0644))
+	require.NoError(t, os.Mkdir(target, 0755))
+	out, err := exec.Command(mksquashfs, content, image, "-noappend", "-quiet").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	m, err := NewDeviceMounter(nil, &DefaultMounter{}, nil, "", withFsOps(newTestFsOps()))
+	require.NoError(t, err)
+	require.NoError(t, m.SquashfsMount(image, target, 0))
+	device := m.testInfo(image).LoopDevice
+	require.NotEmpty(t, device)
+	data, err := ioutil.ReadFile(filepath.Join(target, "file"))
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+	require.Error(t, ioutil.WriteFile(filepath.Join(target, "new"), nil, 0644))
+
+	require.NoError(t, m.Unmount(image, target, 0, 0, nil))
+	_, err = os.Stat(filepath.Join("/sys/block", filepath.Base(device), "loop"))
+	require.True(t, os.IsNotExist(err), "Expected %s to be detached", device)
+}
@@ -0,0 +1,118 @@
+package mount
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testLoopDevices is a loopDevices that hands out fake device names.
+type testLoopDevices struct {
+	sync.Mutex
+	next     int
+	attached map[string]string
+	detached []string
+}
+
+func newTestLoopDevices() *testLoopDevices {
+	return &testLoopDevices{attached: make(map[string]string)}
+}
+
+func (f *testLoopDevices) Attach(image string, readOnly bool) (string, error) {
+	f.Lock()
+	defer f.Unlock()
+	device := fmt.Sprintf("/dev/loop%d", f.next)
+	f.next++
+	f.attached[device] = image
+	return device, nil
+}
+
+func (f *testLoopDevices) Detach(device string) error {
+	f.Lock()
+	defer f.Unlock()
+	if _, ok := f.attached[device]; !ok {
+		return ErrEnoent
+	}
+	delete(f.attached, device)
+	f.detached = append(f.detached, device)
+	return nil
+}
+
+func TestLoopMount(t *testing.T) {
+	loop := newTestLoopDevices()
+	m, mi := newTestMounter(t, withLoopDevices(loop))
+	dir := t.TempDir()
+	image := filepath.Join(dir, "disk.img")
+	target1 := filepath.Join(dir, "mnt1")
+	target2 := filepath.Join(dir, "mnt2")
+	require.NoError(t, os.Mkdir(target1, 0755))
+	require.NoError(t, os.Mkdir(target2, 0755))
+
+	require.NoError(t, m.LoopMount(image, target1, "ext4", true, 0))
+	call := mi.lastCall()
+	require.Equal(t, "/dev/loop0", call.source)
+	require.Equal(t, "ext4", call.fstype)
+	require.Equal(t, uintptr(msRdonly), call.flags)
+	require.Equal(t, "/dev/loop0", m.testInfo(image).LoopDevice)
+
+	// A second mount of the same image reuses the loop device.
+	require.NoError(t, m.LoopMount(image, target2, "ext4", true, 0))
+	require.Equal(t, "/dev/loop0", mi.lastCall().source)
+	require.Len(t, loop.attached, 1)
+
+	require.NoError(t, m.Unmount(image, target1, 0, 0, nil))
+	require.Empty(t, loop.detached, "Expected the loop device to stay attached")
+	require.NoError(t, m.Unmount(image, target2, 0, 0, nil))
+	require.Equal(t, []string{"/dev/loop0"}, loop.detached)
+	require.Equal(t, 0, m.HasMounts(image))
+}
+
+func TestLoopMountFailure(t *testing.T) {
+	loop := newTestLoopDevices()
+	m, mi := newTestMounter(t, withLoopDevices(loop))
+	mi.mountErr = errors.New("bad superblock")
+	dir := t.TempDir()
+	image := filepath.Join(dir, "disk.img")
+
+	err := m.LoopMount(image, dir, "ext4", false, 0)
+	require.Error(t, err)
+	require.Equal(t, uintptr(0), mi.lastCall().flags)
+	require.Equal(t, []string{"/dev/loop0"}, loop.detached,
+		"Expected the loop device to be detached on mount failure")
+	require.Empty(t, loop.attached)
+}
+
+// unmountObserver unmounts every mount as soon as it is reported.
+type unmountObserver struct {
+	m    *Mounter
+	errs []error
+}
+
+func (o *unmountObserver) OnMount(device, path, fs string) {
+	o.errs = append(o.errs, o.m.Unmount(device, path, 0, 0, nil))
+}
+
+func (o *unmountObserver) OnUnmount(device, path, fs string) {}
+
+func (o *unmountObserver) OnError(op, device, path, fs string, err error) {}
+
+func TestLoopMountImmediateUnmount(t *testing.T) {
+	loop := newTestLoopDevices()
+	o := &unmountObserver{}
+	m, _ := newTestMounter(t, withLoopDevices(loop), WithObservers(o))
+	o.m = &m.Mounter
+	dir := t.TempDir()
+	image := filepath.Join(dir, "disk.img")
+
+	// The last Unmount detaches the loop device as soon as the mount is
+	// visible.
+	require.NoError(t, m.LoopMount(image, dir, "ext4", true, 0))
+	require.Equal(t, []error{nil}, o.errs)
+	require.Empty(t, loop.attached, "Expected the loop device to be detached")
+	require.Equal(t, []string{"/dev/loop0"}, loop.detached)
+}
@@ -0,0 +1,83 @@
+package mount
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OptionsMatch returns true if path is mounted as desired. Only the Device,
+// Fs and Data set in desired are compared, along with Flags and ReadOnly. It
+// returns ErrEnoent if path is not tracked.
+func (m *Mounter) OptionsMatch(path string, desired MountOptions) (bool, error) {
+	diff, err := m.OptionsDiff(path, desired)
+	if err != nil {
+		return false, err
+	}
+	return len(diff) == 0, nil
+}
+
+// OptionsDiff returns a line per option of the mount at path that differs
+// from desired, as compared by OptionsMatch.
+func (m *Mounter) OptionsDiff(path string, desired MountOptions) ([]string, error) {
+	path = normalizeMountPath(path)
+	m.RLock()
+	defer m.RUnlock()
+
+	source, ok := m.target(path)
+	if !ok {
+		return nil, ErrEnoent
+	}
+	sh := m.deviceShard(source)
+	sh.Lock()
+	defer sh.Unlock()
+	info, ok := sh.mounts[source]
+	if !ok {
+		return nil, ErrEnoent
+	}
+	var current *PathInfo
+	for _, p := range info.Mountpoint {
+		if p.Path == path {
+			current = p
+			break
+		}
+	}
+	if current == nil {
+		return nil, ErrEnoent
+	}
+
+	var diff []string
+	if desired.Device != "" && desired.Device != source {
+		diff = append(diff, fmt.Sprintf("device: %q, desired %q", source, desired.Device))
+	}
+	if desired.Fs != "" && desired.Fs != info.Fs {
+		diff = append(diff, fmt.Sprintf("fs: %q, desired %q", info.Fs, desired.Fs))
+	}
+	// Read-only is compared on its own as MS_RDONLY does not always make a
+	// read-only mount, and MS_REMOUNT is not a property of the mount.
+	flags := desired.Flags
+	readOnly := desired.ReadOnly || flags&msRdonly != 0
+	ignored := uintptr(msRdonly | msRemount)
+	if current.Flags&^ignored != flags&^ignored {
+		diff = append(diff, fmt.Sprintf("flags: %s, desired %s",
+			FlagsToString(current.Flags&^ignored), FlagsToString(flags&^ignored)))
+	}
+	if current.ReadOnly != readOnly {
+		diff = append(diff, fmt.Sprintf("readonly: %t, desired %t", current.ReadOnly, readOnly))
+	}
+	if desired.Data != "" && !sameDataOptions(current.Data, desired.Data) {
+		diff = append(diff, fmt.Sprintf("data: %q, desired %q", current.Data, desired.Data))
+	}
+	return diff, nil
+}
+
+// sameDataOptions returns true if the comma separated options of a and b
+// are the same, in any order.
+func sameDataOptions(a, b string) bool {
+	split := func(data string) []string {
+		opts := strings.Split(data, ",")
+		sort.Strings(opts)
+		return opts
+	}
+	return strings.Join(split(a), ",") == strings.Join(split(b), ",")
+}
@@ -0,0 +1,78 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsMatch(t *testing.T) {
+	m, _ := newTestMounter(t)
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device:   "/dev/match",
+		Path:     "/mnt/match",
+		Fs:       "ext4",
+		Flags:    syscall.MS_NOSUID | syscall.MS_NODEV,
+		Data:     "discard,errors=remount-ro",
+		ReadOnly: true,
+	}))
+
+	for _, desired := range []MountOptions{
+		{Flags: syscall.MS_NOSUID | syscall.MS_NODEV, ReadOnly: true},
+		{Flags: syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_RDONLY},
+		{
+			Device:   "/dev/match",
+			Fs:       "ext4",
+			Flags:    syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_REMOUNT,
+			Data:     "errors=remount-ro,discard",
+			ReadOnly: true,
+		},
+	} {
+		match, err := m.OptionsMatch("/mnt/match/", desired)
+		require.NoError(t, err)
+		require.True(t, match, "Expected %+v to match", desired)
+	}
+
+	tests := []struct {
+		desired MountOptions
+		diff    []string
+	}{
+		{
+			desired: MountOptions{Flags: syscall.MS_NOSUID | syscall.MS_NODEV},
+			diff:    []string{"readonly: true, desired false"},
+		},
+		{
+			desired: MountOptions{Flags: syscall.MS_NOSUID, ReadOnly: true},
+			diff:    []string{"flags: rw,nosuid,nodev, desired rw,nosuid"},
+		},
+		{
+			desired: MountOptions{
+				Device:   "/dev/other",
+				Fs:       "xfs",
+				Flags:    syscall.MS_NOSUID | syscall.MS_NODEV,
+				Data:     "discard",
+				ReadOnly: true,
+			},
+			diff: []string{
+				`device: "/dev/match", desired "/dev/other"`,
+				`fs: "ext4", desired "xfs"`,
+				`data: "discard,errors=remount-ro", desired "discard"`,
+			},
+		},
+	}
+	for _, test := range tests {
+		match, err := m.OptionsMatch("/mnt/match", test.desired)
+		require.NoError(t, err)
+		require.False(t, match, "Expected %+v not to match", test.desired)
+		diff, err := m.OptionsDiff("/mnt/match", test.desired)
+		require.NoError(t, err)
+		require.Equal(t, test.diff, diff)
+	}
+
+	_, err := m.OptionsMatch("/mnt/missing", MountOptions{})
+	require.Equal(t, ErrEnoent, err)
+}
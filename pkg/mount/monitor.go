@@ -0,0 +1,83 @@
+package mount
+
+import (
+	"sort"
+	"time"
+
+	"github.com/libopenstorage/openstorage/pkg/sched"
+)
+
+// UsageThreshold is the usage below which WithUsageMonitor reports a mount.
+// Zero fields are not checked.
+type UsageThreshold struct {
+	MinAvailableBytes uint64
+	MinFreeInodes     uint64
+}
+
+// below returns true if usage is below t.
+func (t UsageThreshold) below(usage MountUsage) bool {
+	return usage.AvailableBytes < t.MinAvailableBytes || usage.FreeInodes < t.MinFreeInodes
+}
+
+// usageMonitor is the state of WithUsageMonitor. low is only used by the
+// checks, which run one at a time.
+type usageMonitor struct {
+	interval  time.Duration
+	threshold UsageThreshold
+	callback  func(path string, usage MountUsage)
+	// low are the mountpoints found below the threshold by the last check.
+	low map[string]bool
+}
+
+// WithUsageMonitor makes the Mounter check the usage of every tracked
+// mountpoint each interval, on its clock and scheduler, and call callback
+// when the usage of one drops below threshold. callback is called again
+// only after the usage went back above it. The checks stop on Close.
+func WithUsageMonitor(interval time.Duration, threshold UsageThreshold,
+	callback func(path string, usage MountUsage)) MounterOption {
+	return func(m *Mounter) {
+		m.usageMonitor = &usageMonitor{
+			interval:  interval,
+			threshold: threshold,
+			callback:  callback,
+			low:       make(map[string]bool),
+		}
+	}
+}
+
+// scheduleUsageCheck schedules the next usage check.
+func (m *Mounter) scheduleUsageCheck() {
+	err := m.scheduleAfter(func(sched.Interval) {
+		m.checkUsage()
+		m.scheduleUsageCheck()
+	}, m.usageMonitor.interval)
+	if err != nil && err != ErrClosed {
+		m.logger.Warnf("Failed to schedule the mount usage check: %v", err)
+	}
+}
+
+// checkUsage calls the usage callback for the mountpoints whose usage
+// dropped below the threshold since the last check.
+func (m *Mounter) checkUsage() {
+	paths := m.targetPaths()
+	sort.Strings(paths)
+
+	monitor := m.usageMonitor
+	low := make(map[string]bool, len(monitor.low))
+	for _, path := range paths {
+		usage, err := statfs(path)
+		if err != nil {
+			m.logger.Warnf("Failed to get the usage of %s: %v", path, err)
+			low[path] = monitor.low[path]
+			continue
+		}
+		if !monitor.threshold.below(usage) {
+			continue
+		}
+		low[path] = true
+		if !monitor.low[path] {
+			monitor.callback(path, usage)
+		}
+	}
+	monitor.low = low
+}
@@ -0,0 +1,64 @@
+package mount
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setStatfs makes statfs return the usage in usages.
+func setStatfs(t *testing.T, usages map[string]MountUsage) {
+	orig := statfs
+	statfs = func(path string) (MountUsage, error) {
+		usage, ok := usages[path]
+		if !ok {
+			return MountUsage{}, ErrEnoent
+		}
+		return usage, nil
+	}
+	t.Cleanup(func() { statfs = orig })
+}
+
+func TestUsageMonitor(t *testing.T) {
+	ok := MountUsage{AvailableBytes: 1000, FreeInodes: 100}
+	usages := map[string]MountUsage{"/mnt/a": ok, "/mnt/b": ok}
+	setStatfs(t, usages)
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	var reported []string
+	m, _ := newTestMounter(t, WithClock(clk), WithScheduler(s),
+		WithUsageMonitor(time.Minute, UsageThreshold{MinAvailableBytes: 100, MinFreeInodes: 10},
+			func(path string, usage MountUsage) {
+				require.Equal(t, usages[path], usage)
+				reported = append(reported, path)
+			}))
+	require.NoError(t, m.Mount(0, "/dev/a", "/mnt/a", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/dev/b", "/mnt/b", "ext4", 0, "", 0, nil))
+
+	s.Advance(time.Minute)
+	require.Empty(t, reported)
+
+	usages["/mnt/a"] = MountUsage{AvailableBytes: 50, FreeInodes: 100}
+	s.Advance(30 * time.Second)
+	require.Empty(t, reported, "Expected no check before the interval")
+	s.Advance(30 * time.Second)
+	require.Equal(t, []string{"/mnt/a"}, reported)
+	s.Advance(time.Minute)
+	require.Equal(t, []string{"/mnt/a"}, reported, "Expected a mount to be reported once while below")
+
+	usages["/mnt/b"] = MountUsage{AvailableBytes: 1000, FreeInodes: 5}
+	usages["/mnt/a"] = ok
+	s.Advance(time.Minute)
+	require.Equal(t, []string{"/mnt/a", "/mnt/b"}, reported)
+	usages["/mnt/a"] = MountUsage{AvailableBytes: 10, FreeInodes: 100}
+	s.Advance(time.Minute)
+	require.Equal(t, []string{"/mnt/a", "/mnt/b", "/mnt/a"}, reported,
+		"Expected a mount to be reported again after going back above")
+
+	require.NoError(t, m.Close())
+	require.Equal(t, 0, s.pending())
+	usages["/mnt/b"] = ok
+	s.Advance(time.Minute)
+	require.Len(t, reported, 3)
+}
@@ -1,6 +1,3 @@
-//go:build linux
-// +build linux
-
 package mount
 
 import (
@@ -13,13 +10,14 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/docker/docker/pkg/mount"
-	"github.com/libopenstorage/openstorage/pkg/chattr"
 	"github.com/libopenstorage/openstorage/pkg/keylock"
 	"github.com/libopenstorage/openstorage/pkg/options"
 	"github.com/libopenstorage/openstorage/pkg/sched"
@@ -73,6 +71,18 @@ type Manager interface {
 	RemoveMountPath(path string, opts map[string]string) error
 	// EmptyTrashDir removes all directories from the mounter trash directory
 	EmptyTrashDir() error
+	// ValidateMountpath returns an error if Mount would refuse path as a
+	// mountpoint, such as ErrMountpathNotAllowed, without mounting.
+	ValidateMountpath(path string) error
+	// Usage returns the space and inode usage of the filesystem mounted at
+	// path. ErrEnoent is returned if path is not a tracked mountpoint.
+	Usage(path string) (MountUsage, error)
+	// IsMountpoint returns true if path is a mountpoint in the kernel,
+	// regardless of the mount table.
+	IsMountpoint(path string) (bool, error)
+	// Close cancels the pending path removals. Mount, Unmount,
+	// RemoveMountPath and EmptyTrashDir return ErrClosed afterwards.
+	Close() error
 }
 
 // MountImpl backend implementation for Mount/Unmount calls
@@ -117,8 +127,110 @@ var (
 	// ErrMountpathNotAllowed is returned when the requested mountpath is not
 	// a part of the provided allowed mount paths
 	ErrMountpathNotAllowed = errors.New("Mountpath is not allowed")
+	// ErrMountpathDenied is returned when the requested mountpath is in one
+	// of the denied mount paths.
+	ErrMountpathDenied = errors.New("Mountpath is denied")
+	// ErrSubpathEscape is returned when a subpath resolves outside of its
+	// volume root.
+	ErrSubpathEscape = errors.New("Subpath is outside of the volume root")
+	// ErrMountTimeout is returned when a path does not reach the expected
+	// mount state in time.
+	ErrMountTimeout = errors.New("Timed out waiting for mountpoint")
+	// ErrStaleMount is returned when a mountpoint has a stale file handle or
+	// does not respond.
+	ErrStaleMount = errors.New("Mountpoint is stale")
+	// ErrBatchRolledBack is returned for the mounts of a batch that were
+	// rolled back or not attempted after another mount of the batch failed.
+	ErrBatchRolledBack = errors.New("Mount rolled back after batch failure")
+	// ErrClosed is returned for operations on a closed Mounter.
+	ErrClosed = errors.New("Mounter is closed")
+	// ErrEmptyFstabLine is returned by ParseFstabLine for blank lines and
+	// comments.
+	ErrEmptyFstabLine = errors.New("Empty fstab line")
+	// ErrMountLoopDetected is returned when the mounts under a path reach a
+	// mount again, as when A is mounted on B mounted on A.
+	ErrMountLoopDetected = errors.New("Mount loop detected")
+	// ErrTargetIsSymlink is returned when the mountpoint is a symlink.
+	ErrTargetIsSymlink = errors.New("Mountpath is a symlink")
+	// ErrDeviceNotFound is returned when the device to mount does not exist.
+	ErrDeviceNotFound = errors.New("Device does not exist")
+	// ErrNotBlockDevice is returned when the device to mount is not a block
+	// device.
+	ErrNotBlockDevice = errors.New("Device is not a block device")
+	// ErrNotReadOnly is returned when a read-only mount is found to be
+	// writeable.
+	ErrNotReadOnly = errors.New("Mountpath is not read-only")
+	// ErrAlreadyMounted is returned by mounts with FailIfMounted set when the
+	// device is already mounted at the path.
+	ErrAlreadyMounted = errors.New("Device is already mounted at mountpath")
+	// ErrFilesystemReadOnly is returned when a read-write mount cannot be
+	// written to because its filesystem is read-only.
+	ErrFilesystemReadOnly = errors.New("Filesystem is read-only")
+	// ErrNoFilesystem is returned when no filesystem is found on a device.
+	ErrNoFilesystem = errors.New("Device has no filesystem")
+	// ErrAmbiguousSource is returned by NewAuto when the mount type cannot be
+	// told from the source.
+	ErrAmbiguousSource = errors.New("Mount type cannot be detected from source")
+	// ErrMountpathBusy is returned when a mountpath cannot be removed as it
+	// is mounted on.
+	ErrMountpathBusy = errors.New("Mountpath is mounted on")
+	// ErrQuiesced is returned for mounts on a quiesced Mounter.
+	ErrQuiesced = errors.New("Mounter is quiesced")
+)
+
+const (
+	// OpMount is the MountError operation for a failed mount.
+	OpMount = "mount"
+	// OpUnmount is the MountError operation for a failed unmount.
+	OpUnmount = "unmount"
 )
 
+// MountError is returned by Mount and Unmount when the backend fails with a
+// syscall error. The errno can be inspected with errors.As or matched with
+// errors.Is.
+type MountError struct {
+	// Op is the failed operation, OpMount or OpUnmount.
+	Op string
+	// Device is the device or source of the mount.
+	Device string
+	// Path is the mountpoint.
+	Path string
+	// Fs is the filesystem type, if known.
+	Fs string
+	// Errno is the error returned by the backend.
+	Errno syscall.Errno
+}
+
+func (e MountError) Error() string {
+	if e.Op == OpUnmount {
+		return fmt.Sprintf("unmounting %s from %s: %v", e.Device, e.Path, e.Errno)
+	}
+	return fmt.Sprintf("mounting %s at %s: %v", e.Device, e.Path, e.Errno)
+}
+
+// Unwrap returns the underlying errno.
+func (e MountError) Unwrap() error {
+	return e.Errno
+}
+
+// newMountError returns a MountError if err is a syscall error, or err
+// annotated with the operation otherwise.
+func newMountError(op, device, path, fs string, err error) error {
+	if errno, ok := err.(syscall.Errno); ok {
+		return MountError{
+			Op:     op,
+			Device: device,
+			Path:   path,
+			Fs:     fs,
+			Errno:  errno,
+		}
+	}
+	if op == OpUnmount {
+		return fmt.Errorf("unmounting %s from %s: %w", device, path, err)
+	}
+	return fmt.Errorf("mounting %s at %s: %w", device, path, err)
+}
+
 // DeviceMap map device name to Info
 type DeviceMap map[string]*Info
 
@@ -129,6 +241,15 @@ type PathMap map[string]string
 type PathInfo struct {
 	Root string
 	Path string
+	// Flags and Data are the arguments the path was mounted with. They are
+	// only known for mounts made through the Mounter.
+	Flags uintptr
+	Data  string
+	// MountedAt is the time the mount through the Mounter succeeded. It is
+	// zero for mounts loaded from the mount table.
+	MountedAt time.Time
+	// ReadOnly is set if the path is mounted read-only.
+	ReadOnly bool
 }
 
 // Info per device
@@ -138,65 +259,265 @@ type Info struct {
 	Minor      int
 	Mountpoint []*PathInfo
 	Fs         string
+	// LoopDevice is the loop device backing Device, if it was attached by
+	// LoopMount. It is detached when the last mountpoint is unmounted.
+	LoopDevice string
+	// CryptName is the dm-crypt mapping opened by CryptMount, if any. It is
+	// closed when the last mountpoint is unmounted.
+	CryptName string
+	// SourceID is the UUID= or LABEL= identifier Device was resolved from,
+	// if it was mounted by one.
+	SourceID string
 }
 
 // Mounter implements Ops and keeps track of active mounts for volume drivers.
+//
+// Locks are always acquired in this order: the path lock in kl, then the
+// device lock in kl, then the Mounter lock, then the lock of a shard of the
+// device map, then the lock of a shard of the paths, then the lock of an Info
+// in the device map. The device lock is held while an Info is looked up or
+// created and used, so that concurrent mounts of a new device share one Info.
+// The Mounter and shard locks must never be acquired with an Info lock held.
+// Info fields read by the Mounter, such as Mountpoint, are only changed with
+// both the shard and Info locks held, or with the Mounter locked for writing.
+// allowedDirs is guarded by the Mounter lock.
 type Mounter struct {
-	sync.Mutex
-	mountImpl     MountImpl
-	mounts        DeviceMap
-	paths         PathMap
+	sync.RWMutex
+	mountImpl MountImpl
+	// deviceShards are the shards of the device map and pathShards the
+	// shards of the paths map and of the index of mountpoints.
+	deviceShards [tableShards]deviceShard
+	pathShards   [tableShards]pathShard
+	// loadedPaths is the number of entries of the paths map, updated
+	// atomically.
+	loadedPaths   int32
 	allowedDirs   []string
+	deniedDirs    []string
 	kl            keylock.KeyLock
 	trashLocation string
+	removeDelay   time.Duration
+	logger        logrus.FieldLogger
+	fsops         fsOps
+	loop          loopDevices
+	crypt         cryptDevices
+	idmap         idmapSyscalls
+	observers     []Observer
+	clock         Clock
+	scheduler     sched.Scheduler
+	// closed is set by Close. tasks are the pending scheduled tasks.
+	closed bool
+	tasks  map[*scheduledTask]struct{}
+	// quiesced is set by Quiesce and cleared by Unquiesce.
+	quiesced bool
+	// detached are the mountpoints lazily detached by Unmount.
+	detached []DetachedMount
+	// expiries are the automatic unmounts of the mounts made with a TTL.
+	expiries map[string]*mountExpiry
+	// deps are the directories backing the overlay mounted at each path.
+	deps map[string][]string
+	// usageMonitor is set by WithUsageMonitor.
+	usageMonitor *usageMonitor
+	// pendingRemovals are the paths whose scheduled removal has not run.
+	pendingRemovals map[string]PendingRemoval
+	// removalErrorHandler is called when a scheduled removal fails.
+	removalErrorHandler func(path string, err error)
+	// removalRetries is the number of retries of a failed scheduled removal,
+	// the first one after removalBackoff, doubled for every further retry.
+	removalRetries int
+	removalBackoff time.Duration
+	// checkDevice validates the device of a DeviceMount before mounting.
+	checkDevice func(device string) error
+	// kernelVersion returns the version of the running kernel. It is called
+	// once by kernel.
+	kernelVersion func() (kernelVersion, error)
+	kernelOnce    sync.Once
+	kernelV       kernelVersion
+	kernelErr     error
+	// mountSlots limits the mounts in progress in the MountImpl if not nil.
+	mountSlots chan struct{}
+	// allowSymlinkTargets makes Mount follow a mountpoint that is a symlink.
+	allowSymlinkTargets bool
+	// strictAllowedDirs makes an empty allowedDirs allow no mountpath.
+	strictAllowedDirs bool
+	// verifyReadOnly makes Mount check that read-only mounts are read-only.
+	verifyReadOnly bool
+	// ignoreUntrackedPath makes Unmount return nil instead of ErrEnoent
+	// when the device is tracked but not mounted at the requested path.
+	ignoreUntrackedPath bool
+	// strictMinor makes Mount fail with ErrEinval instead of warning when
+	// the minor of a tracked device changes.
+	strictMinor bool
+	// prepareMount validates and resolves the source of o before
+	// MountWithOptions mounts it, for the mount types that check their
+	// sources. It returns the options of the call to mount.
+	prepareMount func(o *MountOptions) ([]mountOption, error)
+}
+
+// fsOps are the filesystem operations performed on mountpoints that depend on
+// the platform.
+type fsOps interface {
+	// IsImmutable returns true if path is immutable or its state is unknown.
+	IsImmutable(path string) bool
+	// AddImmutable makes path immutable.
+	AddImmutable(path string) error
+	// RemoveImmutable makes path mutable.
+	RemoveImmutable(path string) error
+	// Chown changes the owner of path. An id of -1 is left unchanged.
+	Chown(path string, uid, gid int) error
+	// Chmod changes the mode of path.
+	Chmod(path string, mode os.FileMode) error
+	// ProbeWrite creates and removes a file in the directory dir.
+	ProbeWrite(dir string) error
+	// Relabel sets the SELinux label of path and of the files under it.
+	Relabel(path, label string) error
 }
 
 type findMountPoint func(source *mount.Info, destination *regexp.Regexp, mountInfo []*mount.Info) (bool, string, string)
 
-// DefaultMounter defaults to syscall implementation.
-type DefaultMounter struct {
+// MounterOption configures optional behavior of a Mounter.
+type MounterOption func(*Mounter)
+
+// WithRemoveDelay sets the delay after which RemoveMountPath removes a path
+// when OptionsWaitBeforeDelete is set. A zero delay removes the path
+// synchronously. Defaults to 30 seconds.
+func WithRemoveDelay(delay time.Duration) MounterOption {
+	return func(m *Mounter) {
+		if delay < 0 {
+			delay = 0
+		}
+		m.removeDelay = delay
+	}
 }
 
-// Mount default mount implementation is syscall.
-func (m *DefaultMounter) Mount(
-	source string,
-	target string,
-	fstype string,
-	flags uintptr,
-	data string,
-	timeout int,
-) error {
-	return syscall.Mount(source, target, fstype, flags, data)
+// WithLogger sets the logger used by the Mounter. Defaults to the logrus
+// standard logger.
+func WithLogger(logger logrus.FieldLogger) MounterOption {
+	return func(m *Mounter) {
+		if logger != nil {
+			m.logger = logger
+		}
+	}
 }
 
-// Unmount default unmount implementation is syscall.
-func (m *DefaultMounter) Unmount(target string, flags int, timeout int) error {
-	return syscall.Unmount(target, flags)
+// WithIgnoreUntrackedPathUnmount makes Unmount return nil rather than
+// ErrEnoent when the device is known but is not mounted at the requested
+// path. It is provided for callers that rely on the old behavior.
+func WithIgnoreUntrackedPathUnmount() MounterOption {
+	return func(m *Mounter) {
+		m.ignoreUntrackedPath = true
+	}
 }
 
-// String representation of Mounter
-func (m *Mounter) String() string {
-	s := struct {
-		mounts        DeviceMap
-		paths         PathMap
-		allowedDirs   []string
-		trashLocation string
-	}{
-		mounts:        m.mounts,
-		paths:         m.paths,
-		allowedDirs:   m.allowedDirs,
-		trashLocation: m.trashLocation,
+// WithStrictMinor makes Mount return ErrEinval when the minor passed for a
+// tracked device differs from the recorded one. By default the mismatch is
+// logged and the recorded minor is kept.
+func WithStrictMinor() MounterOption {
+	return func(m *Mounter) {
+		m.strictMinor = true
 	}
+}
 
-	return fmt.Sprintf("%#v", s)
+// withDeviceCheck sets the validation of devices before a DeviceMount, used
+// by tests. A nil check mounts any device.
+func withDeviceCheck(check func(device string) error) MounterOption {
+	return func(m *Mounter) {
+		m.checkDevice = check
+	}
 }
 
-// Inspect mount table for device
-func (m *Mounter) Inspect(sourcePath string) []*PathInfo {
+// withFsOps sets the platform filesystem operations, used by tests.
+func withFsOps(ops fsOps) MounterOption {
+	return func(m *Mounter) {
+		m.fsops = ops
+	}
+}
+
+// setOptions sets the defaults for optional fields and applies opts on top.
+func (m *Mounter) setOptions(opts []MounterOption) {
+	m.removeDelay = mountPathRemoveDelay
+	m.logger = logrus.StandardLogger()
+	m.fsops = defaultFsOps
+	m.loop = defaultLoopDevices
+	m.crypt = defaultCryptDevices
+	m.idmap = defaultIdmapSyscalls
+	m.clock = realClock{}
+	m.checkDevice = defaultDeviceCheck
+	m.kernelVersion = hostKernelVersion
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.usageMonitor != nil {
+		m.scheduleUsageCheck()
+	}
+}
+
+// String returns a summary of the mount table, cheap enough to be logged
+// periodically. Use Dump for the whole table.
+func (m *Mounter) String() string {
+	m.RLock()
+	defer m.RUnlock()
+
+	return fmt.Sprintf("Mounter with %d devices and %d mountpoints", m.deviceCountLocked(), m.totalMountsLocked())
+}
+
+// TotalMounts returns the number of mountpoints tracked across all devices.
+func (m *Mounter) TotalMounts() int {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.totalMountsLocked()
+}
+
+// totalMountsLocked returns the number of mountpoints with m locked.
+func (m *Mounter) totalMountsLocked() int {
+	mountpoints := 0
+	m.eachDeviceLocked(func(device string, info *Info) {
+		mountpoints += len(info.Mountpoint)
+	})
+	return mountpoints
+}
+
+// Dump returns the mount table with a line per device followed by a line per
+// mountpoint, sorted by source and path.
+func (m *Mounter) Dump() string {
 	m.Lock()
 	defer m.Unlock()
 
-	v, ok := m.mounts[sourcePath]
+	mounts, _ := m.tableLocked()
+	sources := make([]string, 0, len(mounts))
+	for source := range mounts {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	var b strings.Builder
+	for _, source := range sources {
+		info := mounts[source]
+		fmt.Fprintf(&b, "%s: device=%s fs=%s minor=%d\n", source, info.Device, info.Fs, info.Minor)
+		paths := make([]*PathInfo, len(info.Mountpoint))
+		copy(paths, info.Mountpoint)
+		sort.Slice(paths, func(i, j int) bool { return paths[i].Path < paths[j].Path })
+		for _, p := range paths {
+			fmt.Fprintf(&b, "\t%s root=%s options=%s readonly=%t", p.Path, p.Root, FlagsToString(p.Flags), p.ReadOnly)
+			if !p.MountedAt.IsZero() {
+				fmt.Fprintf(&b, " mounted=%s", p.MountedAt.Format(time.RFC3339))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// Inspect mount table for device. The device may also be given by its
+// SourceID.
+func (m *Mounter) Inspect(sourcePath string) []*PathInfo {
+	m.RLock()
+	defer m.RUnlock()
+
+	source := m.trackedSourceLocked(sourcePath)
+	sh := m.deviceShard(source)
+	sh.Lock()
+	defer sh.Unlock()
+	v, ok := sh.mounts[source]
 	if !ok {
 		return nil
 	}
@@ -205,10 +526,14 @@ func (m *Mounter) Inspect(sourcePath string) []*PathInfo {
 
 // Mounts returns  mount table for device
 func (m *Mounter) Mounts(sourcePath string) []string {
-	m.Lock()
-	defer m.Unlock()
-
-	v, ok := m.mounts[sourcePath]
+	m.RLock()
+	defer m.RUnlock()
+
+	source := m.trackedSourceLocked(sourcePath)
+	sh := m.deviceShard(source)
+	sh.Lock()
+	defer sh.Unlock()
+	v, ok := sh.mounts[source]
 	if !ok {
 		return nil
 	}
@@ -223,52 +548,97 @@ func (m *Mounter) Mounts(sourcePath string) []string {
 
 // GetSourcePaths returns all source paths from the mount table
 func (m *Mounter) GetSourcePaths() []string {
-	m.Lock()
-	defer m.Unlock()
+	m.RLock()
+	defer m.RUnlock()
 
-	sourcePaths := make([]string, len(m.mounts))
-	i := 0
-	for path := range m.mounts {
-		sourcePaths[i] = path
-		i++
-	}
+	sourcePaths := make([]string, 0, m.deviceCountLocked())
+	m.eachDeviceLocked(func(path string, info *Info) {
+		sourcePaths = append(sourcePaths, path)
+	})
+	return sourcePaths
+}
+
+// SourceFilter constrains the sources returned by GetSourcePathsFiltered.
+// Zero fields do not constrain.
+type SourceFilter struct {
+	// Fs is the filesystem type of the source.
+	Fs string
+	// DevicePrefix is a prefix of the source path.
+	DevicePrefix string
+	// MinMounts is the minimum number of mountpoints of the source.
+	MinMounts int
+}
+
+// GetSourcePathsFiltered returns the source paths from the mount table that
+// match filter.
+func (m *Mounter) GetSourcePathsFiltered(filter SourceFilter) []string {
+	m.RLock()
+	defer m.RUnlock()
+
+	sourcePaths := make([]string, 0)
+	m.eachDeviceLocked(func(path string, info *Info) {
+		if filter.Fs != "" && info.Fs != filter.Fs {
+			return
+		}
+		if !strings.HasPrefix(path, filter.DevicePrefix) {
+			return
+		}
+		if len(info.Mountpoint) < filter.MinMounts {
+			return
+		}
+		sourcePaths = append(sourcePaths, path)
+	})
 	return sourcePaths
 }
 
 // HasMounts determines returns the number of mounts for the device.
 func (m *Mounter) HasMounts(sourcePath string) int {
-	m.Lock()
-	defer m.Unlock()
-
-	v, ok := m.mounts[sourcePath]
+	m.RLock()
+	defer m.RUnlock()
+
+	source := m.trackedSourceLocked(sourcePath)
+	sh := m.deviceShard(source)
+	sh.Lock()
+	defer sh.Unlock()
+	v, ok := sh.mounts[source]
 	if !ok {
 		return 0
 	}
 	return len(v.Mountpoint)
 }
 
+// GetMinor returns the minor number recorded for the device, or ErrEnoent if
+// the device is not mounted.
+func (m *Mounter) GetMinor(sourcePath string) (int, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	v, ok := m.lookupLocked(m.trackedSourceLocked(sourcePath))
+	if !ok {
+		return 0, ErrEnoent
+	}
+	return v.Minor, nil
+}
+
 // HasTarget returns true/false based on the target provided
 func (m *Mounter) HasTarget(targetPath string) (string, bool) {
-	m.Lock()
-	defer m.Unlock()
+	m.RLock()
+	defer m.RUnlock()
 
-	for k, v := range m.mounts {
-		for _, p := range v.Mountpoint {
-			if p.Path == targetPath {
-				return k, true
-			}
-		}
-	}
-	return "", false
+	return m.target(targetPath)
 }
 
 // Exists scans mountpaths for specified device and returns true if path is one of the
 // mountpaths. ErrEnoent may be retuned if the device is not found
 func (m *Mounter) Exists(sourcePath string, path string) (bool, error) {
-	m.Lock()
-	defer m.Unlock()
-
-	v, ok := m.mounts[sourcePath]
+	m.RLock()
+	defer m.RUnlock()
+
+	source := m.trackedSourceLocked(sourcePath)
+	sh := m.deviceShard(source)
+	sh.Lock()
+	defer sh.Unlock()
+	v, ok := sh.mounts[source]
 	if !ok {
 		return false, ErrEnoent
 	}
@@ -280,14 +650,38 @@ func (m *Mounter) Exists(sourcePath string, path string) (bool, error) {
 	return false, nil
 }
 
+// VerifiedExists is Exists cross-checked with the kernel mount table: path
+// must also be a mountpoint in mountinfo, so that a mount removed behind the
+// Mounter's back is not reported. Exists only consults the table and is
+// cheaper for the hot paths.
+func (m *Mounter) VerifiedExists(sourcePath string, path string) (bool, error) {
+	exists, err := m.Exists(sourcePath, path)
+	if err != nil || !exists {
+		return exists, err
+	}
+	mounted, err := inMountTable(normalizeMountPath(path))
+	if err != nil {
+		return false, err
+	}
+	if !mounted {
+		m.logger.Warnf("%s is tracked as a mountpoint of %s but is not in the mount table",
+			path, sourcePath)
+	}
+	return mounted, nil
+}
+
 // GetRootPath scans mounts for a specified mountPath and return the
 // rootPath if found or returns an ErrEnoent
 func (m *Mounter) GetRootPath(mountPath string) (string, error) {
-	m.Lock()
-	defer m.Unlock()
-
-	for _, v := range m.mounts {
-		for _, p := range v.Mountpoint {
+	m.RLock()
+	defer m.RUnlock()
+
+	source, _ := m.target(mountPath)
+	sh := m.deviceShard(source)
+	sh.Lock()
+	defer sh.Unlock()
+	if info, ok := sh.mounts[source]; ok {
+		for _, p := range info.Mountpoint {
 			if p.Path == mountPath {
 				return p.Root, nil
 			}
@@ -299,19 +693,39 @@ func (m *Mounter) GetRootPath(mountPath string) (string, error) {
 // GetSourcePath scans mount for a specified mountPath and returns the sourcePath
 // if found or returnes an ErrEnoent
 func (m *Mounter) GetSourcePath(mountPath string) (string, error) {
-	m.Lock()
-	defer m.Unlock()
+	m.RLock()
+	defer m.RUnlock()
 
-	for k, v := range m.mounts {
-		for _, p := range v.Mountpoint {
-			if p.Path == mountPath {
-				return k, nil
-			}
-		}
+	if source, ok := m.target(mountPath); ok {
+		return source, nil
 	}
 	return "", ErrEnoent
 }
 
+// FindMountForPath returns the source and the deepest tracked mountpoint that
+// filePath is in, such as the volume mounted at /data/vol1 for
+// /data/vol1/sub/file. Symlinks are not resolved. ErrEnoent is returned if no
+// tracked mountpoint contains filePath.
+func (m *Mounter) FindMountForPath(filePath string) (string, string, error) {
+	if filePath == "" {
+		return "", "", ErrEnoent
+	}
+	m.RLock()
+	defer m.RUnlock()
+
+	p := filepath.Clean(filePath)
+	for {
+		if source, ok := m.target(p); ok {
+			return source, p, nil
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return "", "", ErrEnoent
+		}
+		p = parent
+	}
+}
+
 func normalizeMountPath(mountPath string) string {
 	if len(mountPath) > 1 && strings.HasSuffix(mountPath, "/") {
 		return mountPath[:len(mountPath)-1]
@@ -319,70 +733,269 @@ func normalizeMountPath(mountPath string) string {
 	return mountPath
 }
 
-func (m *Mounter) maybeRemoveDevice(device string) {
-	m.Lock()
-	defer m.Unlock()
-	if info, ok := m.mounts[device]; ok {
+// checkMinor compares minor to the minor recorded in info, locked. Zero
+// minors are not compared, as callers not knowing the minor pass zero.
+func (m *Mounter) checkMinor(info *Info, minor int) error {
+	if minor == 0 || info.Minor == 0 || minor == info.Minor {
+		return nil
+	}
+	m.logger.Warnf("%s Existing mountpoint has minor %d, ignoring minor %d",
+		info.Device, info.Minor, minor)
+	if m.strictMinor {
+		return ErrEinval
+	}
+	return nil
+}
+
+// deviceLockKey returns the key of the device lock in kl, which must not
+// collide with the paths locked in kl.
+func deviceLockKey(device string) string {
+	return "device:" + device
+}
+
+func (m *Mounter) maybeRemoveDevice(device string) *Info {
+	m.RLock()
+	defer m.RUnlock()
+	sh := m.deviceShard(device)
+	sh.Lock()
+	defer sh.Unlock()
+	if info, ok := sh.mounts[device]; ok {
+		info.Lock()
+		empty := len(info.Mountpoint) == 0
+		info.Unlock()
 		// If the device has no more mountpoints, remove it from the map
-		if len(info.Mountpoint) == 0 {
-			delete(m.mounts, device)
+		if empty {
+			m.removeDeviceLocked(sh, device)
+			return info
 		}
 	}
+	return nil
+}
+
+// removeDeviceLocked removes device, which has no mountpoints left, from the
+// table with m and sh, the shard of device, locked. Entries of the paths map
+// still referencing device are orphans, left by a failure to keep the maps
+// consistent, and are removed as well.
+func (m *Mounter) removeDeviceLocked(sh *deviceShard, device string) {
+	logger := m.logger.WithField("device", device)
+	logger.Debug("Removing device with no mountpoints")
+	delete(sh.mounts, device)
+	if atomic.LoadInt32(&m.loadedPaths) == 0 {
+		return
+	}
+	m.eachLoadedPathLocked(func(path, source string) string {
+		if source != device {
+			return source
+		}
+		logger.Warnf("Removing orphaned path %s of the removed device", path)
+		return ""
+	})
 }
 
-// reload from newM
+// addMountpoint records p as a mountpoint of device, tracked in info.
+func (m *Mounter) addMountpoint(device string, info *Info, p *PathInfo) {
+	m.RLock()
+	defer m.RUnlock()
+	sh := m.deviceShard(device)
+	sh.Lock()
+	// The device may have been removed or replaced while info was unlocked.
+	if current, ok := sh.mounts[device]; ok {
+		info = current
+	} else {
+		sh.set(device, info)
+	}
+	info.Lock()
+	info.Mountpoint = append(info.Mountpoint, p)
+	info.Unlock()
+	sh.Unlock()
+	m.addPath(p.Path, device)
+}
+
+// updateMountpoint records that path of device, tracked in info, was
+// remounted with flags and data.
+func (m *Mounter) updateMountpoint(device string, info *Info, path string, flags uintptr, data string) {
+	m.RLock()
+	defer m.RUnlock()
+	sh := m.deviceShard(device)
+	sh.Lock()
+	defer sh.Unlock()
+	info.Lock()
+	defer info.Unlock()
+	for _, p := range info.Mountpoint {
+		if p.Path == path {
+			p.Flags = flags
+			p.Data = data
+			p.ReadOnly = flags&msRdonly != 0
+			return
+		}
+	}
+}
+
+// removeMountpoint removes path from the mountpoints of device tracked in
+// info. It returns info if it was removed from the table as its last
+// mountpoint is gone.
+func (m *Mounter) removeMountpoint(device string, info *Info, path string) *Info {
+	m.RLock()
+	// The expiries and deps of path are only removed with m locked for
+	// writing if there are any, so that unmounts do not wait on each other.
+	_, hasExpiry := m.expiries[path]
+	_, hasDeps := m.deps[path]
+	defer func() {
+		m.RUnlock()
+		if hasExpiry || hasDeps {
+			m.Lock()
+			delete(m.expiries, path)
+			delete(m.deps, path)
+			m.Unlock()
+		}
+	}()
+	m.deletePath(path, device)
+	m.deleteLoadedPath(path, device)
+	sh := m.deviceShard(device)
+	sh.Lock()
+	defer sh.Unlock()
+	info.Lock()
+	for i, p := range info.Mountpoint {
+		if p.Path == path {
+			info.Mountpoint[i] = info.Mountpoint[len(info.Mountpoint)-1]
+			info.Mountpoint = info.Mountpoint[0 : len(info.Mountpoint)-1]
+			break
+		}
+	}
+	empty := len(info.Mountpoint) == 0
+	info.Unlock()
+	if empty && sh.mounts[device] == info {
+		m.removeDeviceLocked(sh, device)
+		return info
+	}
+	return nil
+}
+
+// devices returns a copy of the device map, so that Info locks can be taken
+// one at a time without holding the Mounter lock.
+func (m *Mounter) devices() DeviceMap {
+	m.RLock()
+	defer m.RUnlock()
+	devices := make(DeviceMap)
+	m.eachDeviceLocked(func(source string, info *Info) {
+		devices[source] = info
+	})
+	return devices
+}
+
+// reload updates the mountpoints of device to newM, the mounts of device
+// found in the mount table. Paths that are still mounted keep their PathInfo,
+// with its flags, data and mount time, and the Info of device is kept, so
+// that only the mounts that appeared or vanished outside the Mounter change.
 func (m *Mounter) reload(device string, newM *Info) error {
 	m.Lock()
 	defer m.Unlock()
 
+	defer m.reindexLocked()
+
+	oldM, ok := m.lookupLocked(device)
 	// New mountable has no mounts, delete old mounts.
 	if newM == nil {
-		delete(m.mounts, device)
+		if ok {
+			oldM.Lock()
+			for _, p := range oldM.Mountpoint {
+				m.deleteLoadedPath(p.Path, device)
+			}
+			oldM.Unlock()
+		}
+		m.deleteLocked(device)
 		return nil
 	}
 
 	// Old mountable had no mounts, copy over new mounts.
-	oldM, ok := m.mounts[device]
 	if !ok {
-		m.mounts[device] = newM
+		m.storeLocked(device, newM)
+		for _, p := range newM.Mountpoint {
+			m.setLoadedSourceLocked(p.Path, device)
+		}
 		return nil
 	}
 
-	// Overwrite old mount entries into new mount table, preserving refcnt.
+	oldM.Lock()
+	defer oldM.Unlock()
+	found := make(map[string]*PathInfo, len(newM.Mountpoint))
+	for _, newP := range newM.Mountpoint {
+		found[newP.Path] = newP
+	}
+	mountpoints := make([]*PathInfo, 0, len(newM.Mountpoint))
 	for _, oldP := range oldM.Mountpoint {
-		for j, newP := range newM.Mountpoint {
-			if newP.Path == oldP.Path {
-				newM.Mountpoint[j] = oldP
-				break
-			}
+		newP, ok := found[oldP.Path]
+		if !ok {
+			m.logger.WithField("device", device).Debugf("Removing vanished mountpoint %s", oldP.Path)
+			m.deleteLoadedPath(oldP.Path, device)
+			continue
 		}
+		// The kernel knows if the path was remounted outside the Mounter.
+		oldP.ReadOnly = newP.ReadOnly
+		mountpoints = append(mountpoints, oldP)
+		delete(found, oldP.Path)
+	}
+	for _, newP := range newM.Mountpoint {
+		if _, ok := found[newP.Path]; !ok {
+			continue
+		}
+		m.logger.WithField("device", device).Debugf("Adding discovered mountpoint %s", newP.Path)
+		mountpoints = append(mountpoints, newP)
+		if _, ok := m.loadedSourceLocked(newP.Path); !ok {
+			m.setLoadedSourceLocked(newP.Path, device)
+		}
+	}
+	oldM.Mountpoint = mountpoints
+	oldM.Minor = newM.Minor
+	if oldM.Fs == "" {
+		oldM.Fs = newM.Fs
 	}
-
-	// Purge old mounts.
-	m.mounts[device] = newM
 	return nil
 }
 
+// loadPrefix is an identifier passed to load with the device its symlink
+// resolves to, if any.
+type loadPrefix struct {
+	re           *regexp.Regexp
+	targetDevice string
+	targetRe     *regexp.Regexp
+}
+
+// load adds the mounts of the mount table found by fmp for any of prefixes.
+// The mount table is read once and the symlinks of prefixes are resolved once,
+// however many identifiers are loaded, so that loading hundreds of volumes at
+// startup stays cheap.
 func (m *Mounter) load(prefixes []*regexp.Regexp, fmp findMountPoint) error {
-	info, err := GetMounts()
+	infos, err := mountTable()
 	if err != nil {
 		return err
 	}
-	for _, v := range info {
+	lps := make([]loadPrefix, 0, len(prefixes))
+	for _, devPrefix := range prefixes {
+		lp := loadPrefix{re: devPrefix}
+		if lp.targetDevice = getTargetDevice(devPrefix.String()); lp.targetDevice != "" {
+			lp.targetRe = regexp.MustCompile(regexp.QuoteMeta(lp.targetDevice))
+		}
+		lps = append(lps, lp)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	for _, v := range infos {
 		var (
 			sourcePath, devicePath, targetDevice string
 			foundPrefix, foundTarget             bool
 		)
-		for _, devPrefix := range prefixes {
-			foundPrefix, sourcePath, devicePath = fmp(v, devPrefix, info)
-			targetDevice = getTargetDevice(devPrefix.String())
-			if !foundPrefix && targetDevice != "" {
-				foundTarget, _, _ = fmp(v, regexp.MustCompile(regexp.QuoteMeta(targetDevice)), info)
+		for _, lp := range lps {
+			foundPrefix, sourcePath, devicePath = fmp(v, lp.re, infos)
+			targetDevice = lp.targetDevice
+			if !foundPrefix && lp.targetRe != nil {
+				foundTarget, _, _ = fmp(v, lp.targetRe, infos)
 				// We could not find a mountpoint for devPrefix (/dev/mapper/vg-lvm1) but found
 				// one for its target device (/dev/dm-0). Change the sourcePath to devPrefix
 				// as fmp might have returned an incorrect or empty sourcePath
-				sourcePath = devPrefix.String()
-				devicePath = devPrefix.String()
+				sourcePath = lp.re.String()
+				devicePath = lp.re.String()
 			}
 
 			if foundPrefix || foundTarget {
@@ -393,46 +1006,48 @@ func (m *Mounter) load(prefixes []*regexp.Regexp, fmp findMountPoint) error {
 			continue
 		}
 
-		addMountTableEntry := func(mountSourcePath, deviceSourcePath string, updatePaths bool) {
-			mount, ok := m.mounts[mountSourcePath]
-			if !ok {
-				mount = &Info{
-					Device:     deviceSourcePath,
-					Fs:         v.Fstype,
-					Minor:      v.Minor,
-					Mountpoint: make([]*PathInfo, 0),
-				}
-				m.mounts[mountSourcePath] = mount
-			}
-			// Allow Load to be called multiple times.
-			for _, p := range mount.Mountpoint {
-
-				if p.Path == v.Mountpoint {
-					// No need of updating Mountpoint
-					return
-				}
-			}
-			pi := &PathInfo{
-				Root: normalizeMountPath(v.Root),
-				Path: normalizeMountPath(v.Mountpoint),
-			}
-			mount.Mountpoint = append(mount.Mountpoint, pi)
-			if updatePaths {
-				m.paths[v.Mountpoint] = mountSourcePath
-			}
-		}
 		// Only update the paths map with the device with which load was called.
-		addMountTableEntry(sourcePath, devicePath, true /*updatePaths*/)
+		m.addLoadedMountpoint(v, sourcePath, devicePath, true /*updatePaths*/)
 
 		// Add a mountpoint entry for the target device as well.
 		if targetDevice == "" {
 			continue
 		}
-		addMountTableEntry(targetDevice, targetDevice, false /*updatePaths*/)
+		m.addLoadedMountpoint(v, targetDevice, targetDevice, false /*updatePaths*/)
 	}
+	m.reindexLocked()
 	return nil
 }
 
+// addLoadedMountpoint adds the mount table entry v as a mountpoint of
+// mountSourcePath with m locked.
+func (m *Mounter) addLoadedMountpoint(v *mount.Info, mountSourcePath, deviceSourcePath string, updatePaths bool) {
+	info, _ := m.loadOrStoreLocked(mountSourcePath, &Info{
+		Device:     deviceSourcePath,
+		Fs:         v.Fstype,
+		Minor:      v.Minor,
+		Mountpoint: make([]*PathInfo, 0),
+	})
+	info.Lock()
+	defer info.Unlock()
+	// Allow Load to be called multiple times.
+	for _, p := range info.Mountpoint {
+		if p.Path == v.Mountpoint {
+			// No need of updating Mountpoint
+			return
+		}
+	}
+	pi := &PathInfo{
+		Root:     normalizeMountPath(v.Root),
+		Path:     normalizeMountPath(v.Mountpoint),
+		ReadOnly: hasMountOption(v.Opts, "ro"),
+	}
+	info.Mountpoint = append(info.Mountpoint, pi)
+	if updatePaths {
+		m.setLoadedSourceLocked(v.Mountpoint, mountSourcePath)
+	}
+}
+
 // Mount new mountpoint for specified device.
 func (m *Mounter) Mount(
 	minor int,
@@ -442,67 +1057,177 @@ func (m *Mounter) Mount(
 	timeout int,
 	opts map[string]string,
 ) error {
-	// device gets overwritten if opts specifies fuse mount with
-	// options.OptionsDeviceFuseMount.
-	device := devPath
+	return m.mount(minor, devPath, mountDevice(devPath, opts), path, fs, flags, data, timeout)
+}
+
+// mountDevice returns the device devPath is tracked under. It gets
+// overwritten if opts specifies fuse mount with
+// options.OptionsDeviceFuseMount.
+func mountDevice(devPath string, opts map[string]string) string {
 	if value, ok := opts[options.OptionsDeviceFuseMount]; ok {
 		// fuse mounts show-up with this key as device.
-		device = value
+		return value
 	}
+	return devPath
+}
 
-	path = normalizeMountPath(path)
-	if len(m.allowedDirs) > 0 {
-		foundPrefix := false
-		for _, allowedDir := range m.allowedDirs {
-			if strings.Contains(path, allowedDir) {
-				foundPrefix = true
-				break
-			}
-		}
-		if !foundPrefix {
-			return ErrMountpathNotAllowed
-		}
+// checkMount returns the error a mount of device at path, normalized, fails
+// with before anything is done to the device or the path: ErrClosed,
+// ErrQuiesced, a disallowed path or another device mounted at path.
+func (m *Mounter) checkMount(device, path string) error {
+	if m.isClosed() {
+		return ErrClosed
+	}
+	if m.isQuiesced() {
+		return ErrQuiesced
+	}
+	if err := m.validateMountpath(path); err != nil {
+		return err
 	}
-	dev, ok := m.HasTarget(path)
-	if ok && dev != device {
-		logrus.Warnf("cannot mount %q,  device %q is mounted at %q", device, dev, path)
+	return m.checkTarget(device, path)
+}
+
+// checkTarget returns ErrExist if a device other than device is mounted at
+// path.
+func (m *Mounter) checkTarget(device, path string) error {
+	if dev, ok := m.HasTarget(path); ok && dev != device {
+		m.logger.Warnf("cannot mount %q,  device %q is mounted at %q", device, dev, path)
 		return ErrExist
 	}
-	m.Lock()
-	info, ok := m.mounts[device]
-	if !ok {
-		info = &Info{
-			Device:     device,
-			Mountpoint: make([]*PathInfo, 0),
-			Minor:      minor,
-			Fs:         fs,
-		}
+	return nil
+}
+
+// mount mounts devPath at path and records the mountpoint under device in
+// the mount table. hooks are run in order after the kernel mount.
+func (m *Mounter) mount(
+	minor int,
+	devPath, device, path, fs string,
+	flags uintptr,
+	data string,
+	timeout int,
+	opts ...mountOption,
+) (err error) {
+	var call mountCall
+	for _, o := range opts {
+		o(&call)
+	}
+	flags = m.dropUnsupportedFlags(flags)
+	backend := m.backendMount
+	if call.backend != nil {
+		backend = call.backend
+	}
+	recordedData := data
+	if call.redact != nil {
+		recordedData = call.redact(data)
+	}
+	// Registered first to run after all the locks are released.
+	defer func() {
+		m.notify(OpMount, device, path, fs, err)
+	}()
+	path = normalizeMountPath(path)
+	if err := m.checkMount(device, path); err != nil {
+		return err
+	}
+	// Serialize operations on path before checking for its mounts again.
+	h := m.kl.Acquire(path)
+	defer m.kl.Release(&h)
+
+	if err := m.checkTarget(device, path); err != nil {
+		return err
+	}
+	// Serialize operations on device so that its Info is not created twice
+	// or removed while it is used.
+	dh := m.kl.Acquire(deviceLockKey(device))
+	defer m.kl.Release(&dh)
+
+	info := &Info{
+		Device:     device,
+		Mountpoint: make([]*PathInfo, 0),
+		Minor:      minor,
+		Fs:         fs,
+	}
+	if fs == "" && isBindMount(flags) {
+		info.Fs = bindFs
+	}
+	m.RLock()
+	info, _ = m.loadOrStoreLocked(device, info)
+	m.RUnlock()
+	// Registered before the Info lock is taken to run after it is released.
+	if call.sourceID != "" {
+		defer func() {
+			if err == nil {
+				info.Lock()
+				info.SourceID = call.sourceID
+				info.Unlock()
+			}
+		}()
 	}
-	m.mounts[device] = info
-	m.Unlock()
 	info.Lock()
-	defer info.Unlock()
+	// The Info lock is released before the mountpoint is recorded, which
+	// needs the Mounter lock.
+	infoLocked := true
+	defer func() {
+		if infoLocked {
+			info.Unlock()
+		}
+	}()
 
 	// Validate input params
-	// FS check is not needed if it is a bind mount
-	if !strings.HasPrefix(info.Fs, fs) && (flags&syscall.MS_BIND) != syscall.MS_BIND {
-		logrus.Warnf("%s Existing mountpoint has fs %q cannot change to %q",
+	// FS check is not needed if it is a bind mount, or a remount of a bind
+	// source
+	bindRemount := flags&msRemount != 0 && info.Fs == bindFs
+	if !strings.HasPrefix(info.Fs, fs) && !isBindMount(flags) && !bindRemount {
+		m.logger.Warnf("%s Existing mountpoint has fs %q cannot change to %q",
 			device, info.Fs, fs)
 		return ErrEinval
 	}
+	if err := m.checkMinor(info, minor); err != nil {
+		return err
+	}
+	// The source is recorded with the new mountpoint, so that the Unmount
+	// of the last mountpoint finds it, or released if none is added.
+	var record func(info *Info)
+	if call.source != nil {
+		src, err := call.source(info)
+		if err != nil {
+			return err
+		}
+		devPath, record = src.devPath, src.record
+		if src.release != nil {
+			defer func() {
+				if record != nil {
+					src.release()
+				}
+			}()
+		}
+	}
 
-	// Try to find the mountpoint. If it already exists, do nothing
+	// Try to find the mountpoint. If it already exists, do nothing unless
+	// it is remounted.
 	for _, p := range info.Mountpoint {
-		if p.Path == path {
-			logrus.Infof("%q mountpoint for device %q already exists",
-				device, path)
+		if p.Path != path {
+			continue
+		}
+		if flags&msRemount == 0 {
+			m.logger.Infof("%q mountpoint for device %q already exists",
+				path, device)
+			if call.failIfMounted {
+				return ErrAlreadyMounted
+			}
 			return nil
 		}
+		if err := backend(devPath, path, fs, flags, data, timeout); err != nil {
+			return newMountError(OpMount, devPath, path, fs, err)
+		}
+		info.Unlock()
+		infoLocked = false
+		m.updateMountpoint(device, info, path, flags, recordedData)
+		if err := m.checkReadOnly(path, flags); err != nil {
+			return newMountError(OpMount, devPath, path, fs, err)
+		}
+		return nil
 	}
 
-	h := m.kl.Acquire(path)
-	defer m.kl.Release(&h)
-
 	// Record previous state of the path
 	pathWasReadOnly := m.isPathSetImmutable(path)
 	var (
@@ -512,7 +1237,7 @@ func (m *Mounter) Mount(
 
 	if err := m.makeMountpathReadOnly(path); err != nil {
 		if strings.Contains(err.Error(), "Inappropriate ioctl for device") {
-			logrus.Warnf("failed to make %s readonly. Err: %v", path, err)
+			m.logger.Warnf("failed to make %s readonly. Err: %v", path, err)
 			// If we cannot chattr the original mount path, we bind mount it to
 			// a path in osd mount path and then chattr it
 			if bindMountPath, err = m.bindMountOriginalPath(path); err != nil {
@@ -520,16 +1245,35 @@ func (m *Mounter) Mount(
 			}
 			isBindMounted = true
 		} else {
-			return fmt.Errorf("failed to make %s readonly. Err: %v", path, err)
+			return fmt.Errorf("failed to make %s readonly. Err: %w", path, err)
 		}
 	}
 
 	// The device is not mounted at path, mount it and add to its mountpoints.
-	if err := m.mountImpl.Mount(devPath, path, fs, flags, data, timeout); err != nil {
+	mountErr := backend(devPath, path, fs, flags, data, timeout)
+	if mountErr == nil && call.remountReadOnly {
+		mountErr = m.remountReadOnly(devPath, path, fs, flags, data, timeout, backend)
+	}
+	if mountErr == nil {
+		mountErr = m.checkReadOnly(path, flags)
+		for _, hook := range call.hooks {
+			if mountErr != nil {
+				break
+			}
+			mountErr = hook(path)
+		}
+		if mountErr != nil {
+			if e := m.mountImpl.Unmount(path, 0, timeout); e != nil {
+				m.logger.Warnf("Failed to unmount %s after post-mount failure: %v", path, e)
+			}
+		}
+	}
+	if mountErr != nil {
+		err = newMountError(OpMount, devPath, path, fs, mountErr)
 		// Rollback only if was writeable
 		if !pathWasReadOnly {
 			if e := m.makeMountpathWriteable(path); e != nil {
-				return fmt.Errorf("failed to make %v writeable during rollback. Err: %v Mount err: %v",
+				return fmt.Errorf("failed to make %v writeable during rollback. Err: %v Mount err: %w",
 					path, e, err)
 			}
 			if isBindMounted {
@@ -542,7 +1286,22 @@ func (m *Mounter) Mount(
 		return err
 	}
 
-	info.Mountpoint = append(info.Mountpoint, &PathInfo{Path: path})
+	if record != nil {
+		record(info)
+		record = nil
+	}
+	info.Unlock()
+	infoLocked = false
+	m.addMountpoint(device, info, &PathInfo{
+		Path:      path,
+		Flags:     flags,
+		Data:      recordedData,
+		MountedAt: m.clock.Now(),
+		ReadOnly:  isReadOnlyFlags(flags),
+	})
+	if call.added != nil {
+		*call.added = true
+	}
 
 	return nil
 }
@@ -550,116 +1309,202 @@ func (m *Mounter) Mount(
 func (m *Mounter) bindMountOriginalPath(path string) (string, error) {
 	bindMountPath := filepath.Join(volume.MountBase, bindMountPrefix, uuid.New())
 	if err := os.MkdirAll(bindMountPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create bind mount directory %v. Err: %v",
+		return "", fmt.Errorf("failed to create bind mount directory %v. Err: %w",
 			bindMountPath, err)
 	}
 
 	// Create a bind mount in osd mount path from the original mount path which
 	// we can chattr instead of the original path
-	if err := m.mountImpl.Mount(bindMountPath, path, "", syscall.MS_BIND, "", 0); err != nil {
+	if err := m.mountImpl.Mount(bindMountPath, path, "", msBind, "", 0); err != nil {
 		if e := os.Remove(bindMountPath); e != nil {
-			logrus.Warnf("Failed to remove the bind mount dir %v. Err: %v Mount err: %v",
+			m.logger.Warnf("Failed to remove the bind mount dir %v. Err: %v Mount err: %v",
 				bindMountPath, e, err)
 		}
-		return "", fmt.Errorf("failed to bind mount %v to %v. Err: %v", path, bindMountPath, err)
+		return "", fmt.Errorf("failed to bind mount %v to %v. Err: %w", path, bindMountPath, err)
 	}
-	logrus.Infof("Successfully bind mounted path [%v] on [%v]", bindMountPath, path)
+	m.logger.Infof("Successfully bind mounted path [%v] on [%v]", bindMountPath, path)
 
 	if err := m.makeMountpathReadOnly(path); err != nil {
 		if cleanupErr := m.cleanupBindMount(path, bindMountPath, err); cleanupErr != nil {
-			logrus.Warnf(cleanupErr.Error())
+			m.logger.Warnf(cleanupErr.Error())
 		}
-		return "", fmt.Errorf("failed to make %s readonly after bind mounting. Err: %v",
+		return "", fmt.Errorf("failed to make %s readonly after bind mounting. Err: %w",
 			path, err)
 	}
 	return bindMountPath, nil
 }
 
 func (m *Mounter) cleanupBindMount(path, bindMountPath string, err error) error {
-	if e := m.mountImpl.Unmount(path, syscall.MS_BIND, 0); e != nil {
-		return fmt.Errorf("failed to unmount bind mounted path %s. Err: %v Mount err: %v",
+	if e := m.mountImpl.Unmount(path, msBind, 0); e != nil {
+		return fmt.Errorf("failed to unmount bind mounted path %s. Err: %v Mount err: %w",
 			path, e, err)
 	}
 	if e := os.Remove(bindMountPath); e != nil {
-		return fmt.Errorf("failed to remove the bind mount dir %v. Err: %v Mount err: %v",
+		return fmt.Errorf("failed to remove the bind mount dir %v. Err: %v Mount err: %w",
 			bindMountPath, e, err)
 	}
 	return nil
 }
 
 // Unmount device at mountpoint and from the matrix.
-// ErrEnoent is returned if the device or mountpoint for the device is not found.
+// ErrEnoent is returned if the device is not found or if the device is not
+// mounted at path, unless WithIgnoreUntrackedPathUnmount is set in which case
+// the latter returns nil. With options.OptionsUnmountChildren, the tracked
+// mounts under path are unmounted first. With
+// options.OptionsUnmountLazyFallback, a busy path is detached lazily and
+// recorded in DetachedMounts. devPath may also be the UUID= or LABEL=
+// SourceID the device was mounted by.
 func (m *Mounter) Unmount(
 	devPath string,
 	path string,
 	flags int,
 	timeout int,
 	opts map[string]string,
-) error {
-	m.Lock()
+) (err error) {
 	// device gets overwritten if opts specifies fuse mount with
 	// options.OptionsDeviceFuseMount.
 	device := devPath
 	path = normalizeMountPath(path)
+	fs := ""
+	// Registered first to run after all the locks are released.
+	defer func() {
+		m.notify(OpUnmount, device, path, fs, err)
+	}()
 	if value, ok := opts[options.OptionsDeviceFuseMount]; ok {
 		// fuse mounts show-up with this key as device.
 		device = value
 	}
-	info, ok := m.mounts[device]
+	if m.isClosed() {
+		return ErrClosed
+	}
+	device = m.trackedSource(device)
+	if options.IsBoolOptionSet(opts, options.OptionsUnmountChildren) {
+		if err := m.unmountChildren(path, flags, timeout); err != nil {
+			return err
+		}
+	}
+	logger := m.logger.WithFields(logrus.Fields{
+		"device": device,
+		"path":   path,
+	})
+	// Serialize operations on path. The lock is released before removing
+	// the path, which takes it again.
+	h := m.kl.Acquire(path)
+	dh := m.kl.Acquire(deviceLockKey(device))
+	pathLocked := true
+	defer func() {
+		if pathLocked {
+			m.kl.Release(&dh)
+			m.kl.Release(&h)
+		}
+	}()
+
+	m.RLock()
+	info, ok := m.lookupLocked(device)
 	if !ok {
-		logrus.Warnf("Unable to unmount device %q path %q: %v",
+		logger.Warnf("Unable to unmount device %q path %q: %v",
 			devPath, path, ErrEnoent.Error())
-		logrus.Infof("Found %v mounts in mounter's cache: ", len(m.mounts))
-		logrus.Infof("Mounter has the following mountpoints: ")
-		for dev, info := range m.mounts {
-			logrus.Infof("For Device %v: Info: %v", dev, info)
-			if info == nil {
-				continue
-			}
-			for _, path := range info.Mountpoint {
-				logrus.Infof("\t Mountpath: %v Rootpath: %v", path.Path, path.Root)
-			}
-		}
-		m.Unlock()
+		m.logger.Infof("Found %v mounts in mounter's cache: ", m.deviceCountLocked())
+		m.logger.Infof("Mounter has the following mountpoints: ")
+		m.eachTargetLocked(func(mountpoint string, sources []string) {
+			m.logger.Infof("\t Mountpath: %v Devices: %v", mountpoint, sources)
+		})
+		m.RUnlock()
 		return ErrEnoent
 	}
-	m.Unlock()
+	m.RUnlock()
 	info.Lock()
-	defer info.Unlock()
-	for i, p := range info.Mountpoint {
-		if p.Path != path {
-			continue
-		}
-		err := m.mountImpl.Unmount(path, flags, timeout)
-		if err != nil {
-			return err
+	fs = info.Fs
+	found := false
+	for _, p := range info.Mountpoint {
+		if p.Path == path {
+			found = true
+			break
 		}
-		// Blow away this mountpoint.
-		info.Mountpoint[i] = info.Mountpoint[len(info.Mountpoint)-1]
-		info.Mountpoint = info.Mountpoint[0 : len(info.Mountpoint)-1]
-		m.maybeRemoveDevice(device)
-		if options.IsBoolOptionSet(opts, options.OptionsDeleteAfterUnmount) {
-			m.RemoveMountPath(path, opts)
+	}
+	if !found {
+		info.Unlock()
+		logger.Warnf("Device %q is not mounted at path %q", device, path)
+		if m.ignoreUntrackedPath {
+			return nil
 		}
+		return ErrEnoent
+	}
+	detached := false
+	if options.IsBoolOptionSet(opts, options.OptionsUnmountLazyFallback) {
+		detached, err = m.unmountLazyFallback(path, flags, timeout)
+	} else {
+		err = m.mountImpl.Unmount(path, flags, timeout)
+	}
+	info.Unlock()
+	if err != nil {
+		logger.Warnf("Failed to unmount device %q from path %q: %v", device, path, err)
+		return newMountError(OpUnmount, device, path, fs, err)
+	}
+	// Blow away this mountpoint.
+	removed := m.removeMountpoint(device, info, path)
+	if detached {
+		m.addDetachedMount(device, path)
+	}
+	m.kl.Release(&dh)
+	m.kl.Release(&h)
+	pathLocked = false
+	if options.IsBoolOptionSet(opts, options.OptionsDeleteAfterUnmount) {
+		m.RemoveMountPath(path, opts)
+	}
 
+	// A bind mounted source is a directory, with no device to release.
+	if removed != nil && removed.Fs == bindFs {
 		return nil
 	}
-	logrus.Warnf("Device %q is not mounted at path %q", device, path)
-	return ErrEnoent
+	if err := m.closeCrypt(removed); err != nil {
+		return err
+	}
+	return m.detachLoop(removed)
 }
 
+// UnmountByPath unmounts path as Unmount does for the source tracked at path,
+// and then removes path if removePath is set. ErrEnoent is returned if path is
+// not tracked.
+func (m *Mounter) UnmountByPath(path string, flags, timeout int, removePath bool) error {
+	device, ok := m.HasTarget(normalizeMountPath(path))
+	if !ok {
+		return ErrEnoent
+	}
+	var opts map[string]string
+	if removePath {
+		opts = map[string]string{options.OptionsDeleteAfterUnmount: "true"}
+	}
+	return m.Unmount(device, path, flags, timeout, opts)
+}
+
+// removeDir removes an empty mount path directory, replaced by tests.
+var removeDir = os.Remove
+
+// removeMountPath removes path unless it is mounted on.
 func (m *Mounter) removeMountPath(path string) error {
+	if err := m.tryRemoveMountPath(path); err != ErrMountpathBusy {
+		return err
+	}
+	return nil
+}
+
+// tryRemoveMountPath makes path writeable and removes it, or returns
+// ErrMountpathBusy if it is mounted on.
+func (m *Mounter) tryRemoveMountPath(path string) error {
+	// Mount locks the normalized path.
+	path = normalizeMountPath(path)
 	h := m.kl.Acquire(path)
 	defer m.kl.Release(&h)
 
 	if devicePath, mounted := m.HasTarget(path); !mounted {
 		if err := m.makeMountpathWriteable(path); err != nil {
-			logrus.Warnf("Failed to make path: %v writeable. Err: %v", path, err)
+			m.logger.Warnf("Failed to make path: %v writeable. Err: %v", path, err)
 			return err
 		}
 	} else {
-		logrus.Infof("Not making %v writeable as %v is mounted on it", path, devicePath)
-		return nil
+		m.logger.Infof("Not making %v writeable as %v is mounted on it", path, devicePath)
+		return ErrMountpathBusy
 	}
 
 	var bindMountPath string
@@ -670,23 +1515,33 @@ func (m *Mounter) removeMountPath(path string) error {
 	if devicePath, mounted := bindMounter.HasTarget(path); mounted {
 		bindMountPath, err = bindMounter.GetRootPath(path)
 		if err := m.mountImpl.Unmount(path, 0, 0); err != nil {
-			return fmt.Errorf("failed to unmount bind mount %v. Err: %v", devicePath, err)
+			return fmt.Errorf("failed to unmount bind mount %v. Err: %w", devicePath, err)
 		}
 	}
 
 	if _, err := os.Stat(path); err == nil {
-		logrus.Infof("Removing mount path directory: %v", path)
-		if err = os.Remove(path); err != nil {
-			logrus.Warnf("Failed to remove path: %v Err: %v", path, err)
+		// Check again right before removing the path, it may have been
+		// mounted on outside of this Mounter since the first check.
+		if devicePath, mounted := m.HasTarget(path); mounted {
+			m.logger.Infof("Not removing %v as %v is mounted on it", path, devicePath)
+			return ErrMountpathBusy
+		}
+		if mounted, _ := IsMountpoint(path); mounted {
+			m.logger.Infof("Not removing %v as it is a mountpoint", path)
+			return ErrMountpathBusy
+		}
+		m.logger.Infof("Removing mount path directory: %v", path)
+		if err = removeDir(path); err != nil {
+			m.logger.Warnf("Failed to remove path: %v Err: %v", path, err)
 			return err
 		}
 	}
 
 	if bindMountPath != "" {
 		if _, err := os.Stat(bindMountPath); err == nil {
-			logrus.Infof("Removing bind mount path source: %v", bindMountPath)
+			m.logger.Infof("Removing bind mount path source: %v", bindMountPath)
 			if err = os.Remove(bindMountPath); err != nil {
-				logrus.Warnf("Failed to remove bind mount path: %v Err: %v",
+				m.logger.Warnf("Failed to remove bind mount path: %v Err: %v",
 					bindMountPath, err)
 				return err
 			}
@@ -695,41 +1550,46 @@ func (m *Mounter) removeMountPath(path string) error {
 	return nil
 }
 
-// RemoveMountPath makes the path writeable and removes it after a fixed delay
+// RemoveMountPathNow makes mountPath writeable and removes it right away,
+// whatever the remove delay, and returns the error of the removal. It returns
+// ErrMountpathBusy if mountPath is mounted on, and nil if it does not exist.
+func (m *Mounter) RemoveMountPathNow(mountPath string) error {
+	if m.isClosed() {
+		return ErrClosed
+	}
+	if _, err := os.Stat(mountPath); os.IsNotExist(err) {
+		return nil
+	}
+	return m.tryRemoveMountPath(mountPath)
+}
+
+// RemoveMountPath makes the path writeable and removes it. If
+// OptionsWaitBeforeDelete is set the removal is deferred by the configured
+// remove delay.
 func (m *Mounter) RemoveMountPath(mountPath string, opts map[string]string) error {
+	if m.isClosed() {
+		return ErrClosed
+	}
 	if _, err := os.Stat(mountPath); err == nil {
-		if options.IsBoolOptionSet(opts, options.OptionsWaitBeforeDelete) {
+		if options.IsBoolOptionSet(opts, options.OptionsWaitBeforeDelete) && m.removeDelay > 0 {
 			hasher := md5.New()
 			hasher.Write([]byte(mountPath))
 			symlinkName := hex.EncodeToString(hasher.Sum(nil))
 			symlinkPath := path.Join(m.trashLocation, symlinkName)
 			if p, err := filepath.EvalSymlinks(symlinkPath); err == nil && p == mountPath {
 				// we already scheduled the removal for this mountPath
-				logrus.Infof("RemoveMountPath is called where symlink still exists on: %v", symlinkPath)
+				m.logger.Infof("RemoveMountPath is called where symlink still exists on: %v", symlinkPath)
 				return nil
 			}
 
 			if err = os.Symlink(mountPath, symlinkPath); err != nil {
 				if !os.IsExist(err) {
-					logrus.Errorf("Error creating sym link %s => %s. Err: %v", symlinkPath, mountPath, err)
+					m.logger.Errorf("Error creating sym link %s => %s. Err: %v", symlinkPath, mountPath, err)
 				}
 			}
 
-			if _, err = sched.Instance().Schedule(
-				func(sched.Interval) {
-					logrus.Infof("[RemoveMountPath] Scheduled removing mount path %v ", mountPath)
-					if err = m.removeMountPath(mountPath); err != nil {
-						return
-					}
-
-					if err = os.Remove(symlinkPath); err != nil {
-						return
-					}
-				},
-				sched.Periodic(time.Second),
-				time.Now().Add(mountPathRemoveDelay),
-				true /* run only once */); err != nil {
-				logrus.Errorf("Failed to schedule task to remove path:%v. Err: %v", mountPath, err)
+			if err = m.scheduleRemoval(mountPath, symlinkPath, 0, m.removeDelay); err != nil {
+				m.logger.Errorf("Failed to schedule task to remove path:%v. Err: %v", mountPath, err)
 				return err
 			}
 		} else {
@@ -740,27 +1600,34 @@ func (m *Mounter) RemoveMountPath(mountPath string, opts map[string]string) erro
 	return nil
 }
 
+// EmptyTrashDir removes all directories from the mounter trash directory
+// after the configured remove delay.
 func (m *Mounter) EmptyTrashDir() error {
+	if m.isClosed() {
+		return ErrClosed
+	}
 	files, err := ioutil.ReadDir(m.trashLocation)
 	if err != nil {
-		logrus.Errorf("failed to read trash dir: %s. Err: %v", m.trashLocation, err)
+		m.logger.Errorf("failed to read trash dir: %s. Err: %v", m.trashLocation, err)
 		return err
 	}
 
-	if _, err := sched.Instance().Schedule(
-		func(sched.Interval) {
-			for _, file := range files {
-				logrus.Infof("[EmptyTrashDir] Scheduled removing file %v in trash location %v", file.Name(), m.trashLocation)
-				e := m.removeSoftlinkAndTarget(path.Join(m.trashLocation, file.Name()))
-				if e != nil {
-					logrus.Errorf("failed to remove link: %s. Err: %v", path.Join(m.trashLocation, file.Name()), e)
-				}
+	emptyTrash := func(sched.Interval) {
+		for _, file := range files {
+			m.logger.Infof("[EmptyTrashDir] Scheduled removing file %v in trash location %v", file.Name(), m.trashLocation)
+			e := m.removeSoftlinkAndTarget(path.Join(m.trashLocation, file.Name()))
+			if e != nil {
+				m.logger.Errorf("failed to remove link: %s. Err: %v", path.Join(m.trashLocation, file.Name()), e)
 			}
-		},
-		sched.Periodic(time.Second),
-		time.Now().Add(mountPathRemoveDelay),
-		true /* run only once */); err != nil {
-		logrus.Errorf("Failed to cleanup of trash dir. Err: %v", err)
+		}
+	}
+	if m.removeDelay == 0 {
+		emptyTrash(sched.Periodic(time.Second))
+		return nil
+	}
+
+	if err := m.schedule(emptyTrash); err != nil {
+		m.logger.Errorf("Failed to cleanup of trash dir. Err: %v", err)
 		return err
 	}
 
@@ -789,17 +1656,17 @@ func (m *Mounter) removeSoftlinkAndTarget(link string) error {
 // isPathSetImmutable returns true on error in getting path info or if path
 // is immutable .
 func (m *Mounter) isPathSetImmutable(mountpath string) bool {
-	return chattr.IsImmutable(mountpath)
+	return m.fsops.IsImmutable(mountpath)
 }
 
 // makeMountpathReadOnly makes given mountpath read-only
 func (m *Mounter) makeMountpathReadOnly(mountpath string) error {
-	return chattr.AddImmutable(mountpath)
+	return m.fsops.AddImmutable(mountpath)
 }
 
 // makeMountpathWriteable makes given mountpath writeable
 func (m *Mounter) makeMountpathWriteable(mountpath string) error {
-	return chattr.RemoveImmutable(mountpath)
+	return m.fsops.RemoveImmutable(mountpath)
 }
 
 // New returns a new Mount Manager
@@ -810,6 +1677,7 @@ func New(
 	customMounter CustomMounter,
 	allowedDirs []string,
 	trashLocation string,
+	opts ...MounterOption,
 ) (Manager, error) {
 
 	if mountImpl == nil {
@@ -818,39 +1686,57 @@ func New(
 
 	switch mounterType {
 	case DeviceMount:
-		return NewDeviceMounter(identifiers, mountImpl, allowedDirs, trashLocation)
+		return NewDeviceMounter(identifiers, mountImpl, allowedDirs, trashLocation, opts...)
 	case NFSMount:
-		return NewNFSMounter(identifiers, mountImpl, allowedDirs, trashLocation)
+		return NewNFSMounter(identifiers, mountImpl, allowedDirs, trashLocation, opts...)
 	case BindMount:
-		return NewBindMounter(identifiers, mountImpl, allowedDirs, trashLocation)
+		return NewBindMounter(identifiers, mountImpl, allowedDirs, trashLocation, opts...)
 	case CustomMount:
-		return NewCustomMounter(identifiers, mountImpl, customMounter, allowedDirs)
+		return NewCustomMounter(identifiers, mountImpl, customMounter, allowedDirs, opts...)
 	case RawMount:
-		return NewRawBindMounter(identifiers, mountImpl, allowedDirs, trashLocation)
+		return NewRawBindMounter(identifiers, mountImpl, allowedDirs, trashLocation, opts...)
 	}
 	return nil, ErrUnsupported
 }
 
-// GetMounts is a wrapper over mount.GetMounts(). It is mainly used to add a switch
-// to enable device mounter tests.
-func GetMounts() ([]*mount.Info, error) {
-	if os.Getenv(testDeviceEnv) != "" {
-		return testGetMounts()
+// NewAuto returns a Mount Manager for source, picking the mount type from
+// its form: "host:/export" is mounted with NFS and "/dev/..." as a device.
+// Other sources return ErrAmbiguousSource.
+func NewAuto(
+	source string,
+	mountImpl MountImpl,
+	allowedDirs []string,
+	opts ...MounterOption,
+) (Manager, error) {
+	if strings.HasPrefix(source, "/dev/") {
+		identifiers := []*regexp.Regexp{regexp.MustCompile(regexp.QuoteMeta(source))}
+		return New(DeviceMount, mountImpl, identifiers, nil, allowedDirs, "", opts...)
+	}
+	if host, ok := nfsSourceHost(source); ok {
+		server := regexp.MustCompile("^" + regexp.QuoteMeta(host) + "$")
+		return New(NFSMount, mountImpl, []*regexp.Regexp{server}, nil, allowedDirs, "", opts...)
 	}
-	return parseMountTable()
+	return nil, fmt.Errorf("%q: %w", source, ErrAmbiguousSource)
 }
 
-var (
-	// testMounts is a global test list of mount table entries
-	testMounts []*mount.Info
-)
-
-// testGetMounts is only used in tests to get the test list of mount table
-// entries
-func testGetMounts() ([]*mount.Info, error) {
-	var err error
-	if len(testMounts) == 0 {
-		testMounts, err = parseMountTable()
+// nfsSourceHost returns the server of an NFS source of the form host:/export
+// or [ipv6]:/export.
+func nfsSourceHost(source string) (string, bool) {
+	i := strings.Index(source, ":/")
+	if i <= 0 {
+		return "", false
+	}
+	host := source[:i]
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	if host == "" || strings.ContainsAny(host, "/[]") {
+		return "", false
 	}
-	return testMounts, err
+	return host, true
+}
+
+// isBindMount returns true if flags request a bind mount.
+func isBindMount(flags uintptr) bool {
+	return msBind != 0 && flags&msBind == msBind
 }
@@ -0,0 +1,67 @@
+//go:build darwin
+// +build darwin
+
+package mount
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultMounter defaults to the mount(2) and unmount(2) system calls.
+type DefaultMounter struct {
+}
+
+// Mount calls mount(2). macOS mount(2) takes no source argument, the source
+// must be encoded in data as expected by the filesystem.
+func (m *DefaultMounter) Mount(
+	source string,
+	target string,
+	fstype string,
+	flags uintptr,
+	data string,
+	timeout int,
+) error {
+	var dataPtr unsafe.Pointer
+	if len(data) > 0 {
+		p, err := unix.BytePtrFromString(data)
+		if err != nil {
+			return err
+		}
+		dataPtr = unsafe.Pointer(p)
+	}
+	return unix.Mount(fstype, target, int(flags), dataPtr)
+}
+
+// Unmount calls unmount(2).
+func (m *DefaultMounter) Unmount(target string, flags int, timeout int) error {
+	return unix.Unmount(target, flags)
+}
+
+// statDev returns the device of the file described by fi.
+func statDev(fi os.FileInfo) (uint64, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
+
+// statfsUsage returns the usage of the filesystem of path.
+func statfsUsage(path string) (MountUsage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return MountUsage{}, err
+	}
+	bsize := uint64(st.Bsize)
+	return MountUsage{
+		TotalBytes:     st.Blocks * bsize,
+		FreeBytes:      st.Bfree * bsize,
+		AvailableBytes: st.Bavail * bsize,
+		TotalInodes:    st.Files,
+		FreeInodes:     st.Ffree,
+	}, nil
+}
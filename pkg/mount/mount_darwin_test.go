@@ -0,0 +1,37 @@
+//go:build darwin
+// +build darwin
+
+package mount
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDarwinBookkeeping(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mount_darwin_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	mi := newTestMountImpl()
+	m, err := New(DeviceMount, mi, nil, nil, []string{}, "")
+	require.NoError(t, err, "Unexpected error on New")
+
+	target := filepath.Join(dir, "target")
+	require.NoError(t, os.MkdirAll(target, 0755))
+	require.NoError(t, m.Mount(1, "/dev/disk4", target, "apfs", 0, "", 0, nil))
+	require.Equal(t, "/dev/disk4", mi.mounted[target])
+	require.Equal(t, 1, m.HasMounts("/dev/disk4"))
+	require.Equal(t, []string{target}, m.Mounts("/dev/disk4"))
+	src, err := m.GetSourcePath(target)
+	require.NoError(t, err)
+	require.Equal(t, "/dev/disk4", src)
+
+	require.NoError(t, m.Unmount("/dev/disk4", target, 0, 0, nil))
+	require.Equal(t, 0, m.HasMounts("/dev/disk4"))
+	require.Empty(t, mi.mounted)
+}
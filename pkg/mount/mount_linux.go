@@ -0,0 +1,219 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+
+	"github.com/docker/docker/pkg/mount"
+	"github.com/libopenstorage/openstorage/pkg/chattr"
+	osdexec "github.com/libopenstorage/openstorage/pkg/exec"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// msBind is the flag requesting a bind mount.
+const msBind = syscall.MS_BIND
+
+// msRdonly is the flag requesting a read-only mount.
+const msRdonly = syscall.MS_RDONLY
+
+// msRemount is the flag requesting a change of an existing mount.
+const msRemount = syscall.MS_REMOUNT
+
+// msMove is the flag requesting an existing mount to be moved.
+const msMove = syscall.MS_MOVE
+
+// msRec is the flag making a bind mount recursive.
+const msRec = syscall.MS_REC
+
+// mntDetach is the unmount flag requesting a lazy unmount.
+const mntDetach = syscall.MNT_DETACH
+
+// msNosymfollow is the flag making the mount not follow symlinks.
+const msNosymfollow = unix.MS_NOSYMFOLLOW
+
+// kernelFlags are the mount flags only supported by recent kernels.
+var kernelFlags = []kernelFlag{
+	{feature: KernelFeatureLazytime, flag: unix.MS_LAZYTIME},
+	{feature: KernelFeatureNosymfollow, flag: unix.MS_NOSYMFOLLOW},
+}
+
+// hostKernelVersion returns the version of the running kernel from uname.
+func hostKernelVersion() (kernelVersion, error) {
+	var uts syscall.Utsname
+	if err := syscall.Uname(&uts); err != nil {
+		return kernelVersion{}, err
+	}
+	release := make([]byte, 0, len(uts.Release))
+	for _, c := range uts.Release {
+		if c == 0 {
+			break
+		}
+		release = append(release, byte(c))
+	}
+	return parseKernelRelease(string(release))
+}
+
+// mountFlagOptions are the mount option keywords that map to mount flags.
+var mountFlagOptions = []mountFlagOption{
+	{name: "ro", flag: syscall.MS_RDONLY},
+	{name: "rw", flag: syscall.MS_RDONLY, clear: true},
+	{name: "nosuid", flag: syscall.MS_NOSUID},
+	{name: "suid", flag: syscall.MS_NOSUID, clear: true},
+	{name: "nodev", flag: syscall.MS_NODEV},
+	{name: "dev", flag: syscall.MS_NODEV, clear: true},
+	{name: "noexec", flag: syscall.MS_NOEXEC},
+	{name: "exec", flag: syscall.MS_NOEXEC, clear: true},
+	{name: "sync", flag: syscall.MS_SYNCHRONOUS},
+	{name: "async", flag: syscall.MS_SYNCHRONOUS, clear: true},
+	{name: "remount", flag: syscall.MS_REMOUNT},
+	{name: "mand", flag: syscall.MS_MANDLOCK},
+	{name: "nomand", flag: syscall.MS_MANDLOCK, clear: true},
+	{name: "dirsync", flag: syscall.MS_DIRSYNC},
+	{name: "noatime", flag: syscall.MS_NOATIME},
+	{name: "atime", flag: syscall.MS_NOATIME, clear: true},
+	{name: "nodiratime", flag: syscall.MS_NODIRATIME},
+	{name: "diratime", flag: syscall.MS_NODIRATIME, clear: true},
+	// rbind comes first for FlagsToString to prefer it over bind.
+	{name: "rbind", flag: syscall.MS_BIND | syscall.MS_REC},
+	{name: "bind", flag: syscall.MS_BIND},
+	{name: "relatime", flag: syscall.MS_RELATIME},
+	{name: "norelatime", flag: syscall.MS_RELATIME, clear: true},
+	{name: "strictatime", flag: syscall.MS_STRICTATIME},
+	{name: "silent", flag: syscall.MS_SILENT},
+	{name: "loud", flag: syscall.MS_SILENT, clear: true},
+	{name: "lazytime", flag: unix.MS_LAZYTIME},
+	{name: "nolazytime", flag: unix.MS_LAZYTIME, clear: true},
+	{name: "nosymfollow", flag: unix.MS_NOSYMFOLLOW},
+	{name: "symfollow", flag: unix.MS_NOSYMFOLLOW, clear: true},
+}
+
+// defaultDeviceCheck makes DeviceMount mount only block devices.
+var defaultDeviceCheck = checkBlockDevice
+
+// defaultFsOps changes the immutable attribute with chattr.
+var defaultFsOps fsOps = newChattrFsOps(exec.LookPath)
+
+// DefaultMounter defaults to syscall implementation.
+type DefaultMounter struct {
+}
+
+// Mount default mount implementation is syscall.
+func (m *DefaultMounter) Mount(
+	source string,
+	target string,
+	fstype string,
+	flags uintptr,
+	data string,
+	timeout int,
+) error {
+	return syscall.Mount(source, target, fstype, flags, data)
+}
+
+// Unmount default unmount implementation is syscall.
+func (m *DefaultMounter) Unmount(target string, flags int, timeout int) error {
+	return syscall.Unmount(target, flags)
+}
+
+// chattrFsOps implements fsOps with the chattr and lsattr binaries. If
+// chattr is not installed, as on minimal images, paths are left mutable
+// rather than failing every mount.
+type chattrFsOps struct {
+	osOwnerOps
+	osWriteProbe
+	xattrLabeler
+	// lookPath finds the chattr binary, replaced by tests.
+	lookPath func(file string) (string, error)
+	once     sync.Once
+	missing  bool
+}
+
+func newChattrFsOps(lookPath func(file string) (string, error)) *chattrFsOps {
+	return &chattrFsOps{lookPath: lookPath}
+}
+
+// available returns true if chattr is installed. It is looked up once.
+func (c *chattrFsOps) available() bool {
+	c.once.Do(func() {
+		if _, err := c.lookPath(osdexec.Which("chattr")); err != nil {
+			c.missing = true
+			logrus.Warnf("chattr is not available, mount paths will not be made immutable: %v", err)
+		}
+	})
+	return !c.missing
+}
+
+func (c *chattrFsOps) IsImmutable(path string) bool {
+	if !c.available() {
+		return false
+	}
+	return chattr.IsImmutable(path)
+}
+
+func (c *chattrFsOps) AddImmutable(path string) error {
+	if !c.available() {
+		return nil
+	}
+	return chattr.AddImmutable(path)
+}
+
+func (c *chattrFsOps) RemoveImmutable(path string) error {
+	if !c.available() {
+		return nil
+	}
+	return chattr.RemoveImmutable(path)
+}
+
+// GetMounts is a wrapper over mount.GetMounts(). It is mainly used to add a switch
+// to enable device mounter tests.
+func GetMounts() ([]*mount.Info, error) {
+	if os.Getenv(testDeviceEnv) != "" {
+		return testGetMounts()
+	}
+	return parseMountTable()
+}
+
+var (
+	// testMounts is a global test list of mount table entries
+	testMounts []*mount.Info
+)
+
+// testGetMounts is only used in tests to get the test list of mount table
+// entries
+func testGetMounts() ([]*mount.Info, error) {
+	var err error
+	if len(testMounts) == 0 {
+		testMounts, err = parseMountTable()
+	}
+	return testMounts, err
+}
+
+// statDev returns the device of the file described by fi.
+func statDev(fi os.FileInfo) (uint64, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
+
+// statfsUsage returns the usage of the filesystem of path.
+func statfsUsage(path string) (MountUsage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return MountUsage{}, err
+	}
+	bsize := uint64(st.Bsize)
+	return MountUsage{
+		TotalBytes:     st.Blocks * bsize,
+		FreeBytes:      st.Bfree * bsize,
+		AvailableBytes: st.Bavail * bsize,
+		TotalInodes:    st.Files,
+		FreeInodes:     st.Ffree,
+	}, nil
+}
@@ -0,0 +1,39 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChattrMissing(t *testing.T) {
+	lookups := 0
+	fsops := newChattrFsOps(func(file string) (string, error) {
+		lookups++
+		return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+	})
+	path := t.TempDir()
+
+	require.False(t, fsops.IsImmutable(path))
+	require.NoError(t, fsops.AddImmutable(path))
+	require.NoError(t, fsops.RemoveImmutable(path))
+	require.Equal(t, 1, lookups, "Expected chattr to be looked up once")
+
+	mi := newTestMountImpl()
+	m, err := NewDeviceMounter(nil, mi, nil, "", withFsOps(fsops), withDeviceCheck(nil))
+	require.NoError(t, err)
+	target := filepath.Join(path, "mnt")
+	require.NoError(t, os.Mkdir(target, 0755))
+	require.NoError(t, m.Mount(0, "/dev/chattr", target, "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Unmount("/dev/chattr", target, 0, 0, nil))
+	require.NoError(t, m.RemoveMountPath(target, nil))
+	_, err = os.Stat(target)
+	require.True(t, os.IsNotExist(err), "Expected %v to be removed", target)
+	require.Equal(t, 1, lookups)
+}
@@ -1,13 +1,21 @@
+//go:build linux
+// +build linux
+
 package mount
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
 	"sync"
 	"syscall"
 	"testing"
+	"time"
 
+	"github.com/docker/docker/pkg/mount"
+	"github.com/libopenstorage/openstorage/pkg/options"
+	"github.com/libopenstorage/openstorage/pkg/sched"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 )
@@ -224,6 +232,250 @@ func exists(t *testing.T, source, dest string) {
 	require.NoError(t, err, "Failed in unmount")
 }
 
+func TestRemoveMountPathNoDelay(t *testing.T) {
+	require.NoError(t, os.MkdirAll(trashLocation, 0755))
+	bm, err := New(BindMount, nil, []*regexp.Regexp{regexp.MustCompile("")}, nil, []string{}, trashLocation,
+		WithRemoveDelay(0))
+	require.NoError(t, err, "Failed to setup test")
+
+	cleandir(dest)
+	opts := map[string]string{options.OptionsWaitBeforeDelete: "true"}
+	require.NoError(t, bm.RemoveMountPath(dest, opts), "RemoveMountPath")
+	_, err = os.Stat(dest)
+	require.True(t, os.IsNotExist(err), "Expected %v to be removed synchronously", dest)
+}
+
+func TestRemoveMountPathWithDelay(t *testing.T) {
+	if sched.Instance() == nil {
+		sched.Init(time.Second)
+	}
+	require.NoError(t, os.MkdirAll(trashLocation, 0755))
+	bm, err := New(BindMount, nil, []*regexp.Regexp{regexp.MustCompile("")}, nil, []string{}, trashLocation,
+		WithRemoveDelay(time.Second))
+	require.NoError(t, err, "Failed to setup test")
+
+	cleandir(dest)
+	opts := map[string]string{options.OptionsWaitBeforeDelete: "true"}
+	require.NoError(t, bm.RemoveMountPath(dest, opts), "RemoveMountPath")
+	_, err = os.Stat(dest)
+	require.NoError(t, err, "Expected %v to exist until the delay elapses", dest)
+
+	// The pending removal is dropped once the removal ran, which also orders
+	// it before the tests replacing removeDir.
+	require.Eventually(t, func() bool {
+		return len(bm.(*bindMounter).PendingRemovals()) == 0
+	}, 10*time.Second, 100*time.Millisecond, "Expected %v to be removed after the delay", dest)
+	_, err = os.Stat(dest)
+	require.True(t, os.IsNotExist(err), "Expected %v to be removed", dest)
+}
+
+func TestUnmountLogFields(t *testing.T) {
+	hook := &logHook{}
+	logger := logrus.New()
+	logger.AddHook(hook)
+
+	bm, err := New(BindMount, newTestMountImpl(), nil, nil, []string{}, "", WithLogger(logger))
+	require.NoError(t, err, "Failed to setup test")
+
+	cleandir(source)
+	cleandir(dest)
+	require.NoError(t, bm.Mount(0, source, dest, "", syscall.MS_BIND, "", 0, nil), "Failed in mount")
+	defer bm.Unmount(source, dest, 0, 0, nil)
+
+	other := dest + "_other"
+	require.Error(t, bm.Unmount(source, other, 0, 0, nil), "Unmount of untracked path should fail")
+	e := hook.find(fmt.Sprintf("Device %q is not mounted at path %q", source, other))
+	require.NotNil(t, e, "Expected a warning for the untracked path")
+	require.Equal(t, logrus.WarnLevel, e.Level)
+	require.Equal(t, source, e.Data["device"], "Unexpected device field")
+	require.Equal(t, other, e.Data["path"], "Unexpected path field")
+
+	require.Error(t, bm.Unmount(dummyDevice, dest, 0, 0, nil), "Unmount of untracked device should fail")
+	e = hook.find(fmt.Sprintf("Unable to unmount device %q path %q: %v", dummyDevice, dest, ErrEnoent))
+	require.NotNil(t, e, "Expected a warning for the untracked device")
+	require.Equal(t, dummyDevice, e.Data["device"], "Unexpected device field")
+	require.Equal(t, dest, e.Data["path"], "Unexpected path field")
+}
+
+func TestUnmountUntrackedPath(t *testing.T) {
+	for _, ignore := range []bool{false, true} {
+		var opts []MounterOption
+		if ignore {
+			opts = append(opts, WithIgnoreUntrackedPathUnmount())
+		}
+		bm, err := New(BindMount, newTestMountImpl(), nil, nil, []string{}, "", opts...)
+		require.NoError(t, err, "Failed to setup test")
+
+		cleandir(source)
+		cleandir(dest)
+		require.NoError(t, bm.Mount(0, source, dest, "", syscall.MS_BIND, "", 0, nil), "Failed in mount")
+
+		err = bm.Unmount(source, dest+"_other", 0, 0, nil)
+		if ignore {
+			require.NoError(t, err, "Expected nil with WithIgnoreUntrackedPathUnmount")
+		} else {
+			require.Equal(t, ErrEnoent, err, "Expected ErrEnoent for a path not mounted on the device")
+		}
+		require.Equal(t, 1, bm.HasMounts(source), "Tracked mount must not be affected")
+		require.NoError(t, bm.Unmount(source, dest, 0, 0, nil), "Failed in unmount")
+	}
+}
+
+func TestUnmountByPath(t *testing.T) {
+	m, mi := newTestMounter(t, WithRemoveDelay(0))
+	target := t.TempDir() + "/mnt"
+	require.NoError(t, os.Mkdir(target, 0755))
+	require.NoError(t, m.Mount(0, "/dev/bypath", target, "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/dev/bypath", "/mnt/bypath", "ext4", 0, "", 0, nil))
+
+	require.NoError(t, m.UnmountByPath("/mnt/bypath/", 0, 0, false))
+	require.Equal(t, []string{"/mnt/bypath"}, mi.unmounted)
+	require.Equal(t, []string{target}, m.Mounts("/dev/bypath"))
+
+	require.NoError(t, m.UnmountByPath(target, 0, 0, true))
+	require.Equal(t, 0, m.HasMounts("/dev/bypath"))
+	require.Eventually(t, func() bool {
+		return len(m.PendingRemovals()) == 0
+	}, 5*time.Second, 10*time.Millisecond, "Expected the path to be removed")
+	_, err := os.Stat(target)
+	require.True(t, os.IsNotExist(err), "Expected the path to be removed")
+
+	require.Equal(t, ErrEnoent, m.UnmountByPath("/mnt/untracked", 0, 0, false))
+	require.Len(t, mi.unmounted, 2)
+}
+
+func TestRemoveMountPathNow(t *testing.T) {
+	ops := newTestFsOps()
+	m, _ := newTestMounter(t, withFsOps(ops))
+	dir := t.TempDir()
+	path := dir + "/mnt"
+	require.NoError(t, os.Mkdir(path, 0755))
+	ops.immutable[path] = true
+
+	require.NoError(t, m.RemoveMountPathNow(path))
+	_, err := os.Stat(path)
+	require.True(t, os.IsNotExist(err), "Expected %v to be removed", path)
+	require.False(t, ops.IsImmutable(path), "Expected %v to be made writeable", path)
+	require.NoError(t, m.RemoveMountPathNow(path), "Expected a missing path to be ignored")
+
+	// A mount target is not removed.
+	require.NoError(t, os.Mkdir(path, 0755))
+	require.NoError(t, m.Mount(0, "/dev/now", path, "ext4", 0, "", 0, nil))
+	require.Equal(t, ErrMountpathBusy, m.RemoveMountPathNow(path))
+	_, err = os.Stat(path)
+	require.NoError(t, err, "Expected the mount target to be kept")
+	require.True(t, ops.IsImmutable(path), "Expected the mount target to stay immutable")
+	require.NoError(t, m.Unmount("/dev/now", path, 0, 0, nil))
+
+	removeErr := errors.New("remove failed")
+	orig := removeDir
+	removeDir = func(string) error { return removeErr }
+	defer func() { removeDir = orig }()
+	require.Equal(t, removeErr, m.RemoveMountPathNow(path))
+}
+
+func TestMountMinorMismatch(t *testing.T) {
+	hook := &logHook{}
+	logger := logrus.New()
+	logger.AddHook(hook)
+	m, _ := newTestMounter(t, WithLogger(logger))
+	require.NoError(t, m.Mount(5, "/dev/minor", "/mnt/minor1", "ext4", 0, "", 0, nil))
+
+	require.NoError(t, m.Mount(6, "/dev/minor", "/mnt/minor2", "ext4", 0, "", 0, nil))
+	e := hook.find("/dev/minor Existing mountpoint has minor 5, ignoring minor 6")
+	require.NotNil(t, e, "Expected a warning for the mismatched minor")
+	require.Equal(t, logrus.WarnLevel, e.Level)
+	minor, err := m.GetMinor("/dev/minor")
+	require.NoError(t, err)
+	require.Equal(t, 5, minor, "Expected the recorded minor to be kept")
+
+	// Zero is an unknown minor.
+	hook.entries = nil
+	require.NoError(t, m.Mount(0, "/dev/minor", "/mnt/minor3", "ext4", 0, "", 0, nil))
+	require.Nil(t, hook.find("/dev/minor Existing mountpoint has minor 5, ignoring minor 0"))
+
+	m, mi := newTestMounter(t, WithStrictMinor())
+	require.NoError(t, m.Mount(5, "/dev/minor", "/mnt/minor1", "ext4", 0, "", 0, nil))
+	require.Equal(t, ErrEinval, m.Mount(6, "/dev/minor", "/mnt/minor2", "ext4", 0, "", 0, nil))
+	require.Len(t, mi.calls, 1)
+	require.Equal(t, 1, m.HasMounts("/dev/minor"))
+	require.NoError(t, m.Mount(5, "/dev/minor", "/mnt/minor2", "ext4", 0, "", 0, nil))
+}
+
+func TestWrappedErrors(t *testing.T) {
+	mi := newTestMountImpl()
+	bm, err := New(BindMount, mi, nil, nil, []string{}, "")
+	require.NoError(t, err, "Failed to setup test")
+
+	cleandir(source)
+	cleandir(dest)
+	other := source + "_other"
+	cleandir(other)
+
+	mi.mountErr = syscall.EBUSY
+	err = bm.Mount(0, source, dest, "", syscall.MS_BIND, "", 0, nil)
+	require.Error(t, err, "Expected mount to fail")
+	require.True(t, errors.Is(err, syscall.EBUSY), "Expected errno to be preserved: %v", err)
+	require.Contains(t, err.Error(), dest, "Expected path in error")
+
+	mi.mountErr = nil
+	require.NoError(t, bm.Mount(0, source, dest, "", syscall.MS_BIND, "", 0, nil), "Failed in mount")
+	err = bm.Mount(0, other, dest, "", syscall.MS_BIND, "", 0, nil)
+	require.True(t, errors.Is(err, ErrExist), "Expected ErrExist: %v", err)
+
+	mi.unmountErr = syscall.EINVAL
+	err = bm.Unmount(source, dest, 0, 0, nil)
+	require.True(t, errors.Is(err, syscall.EINVAL), "Expected errno to be preserved: %v", err)
+	mi.unmountErr = nil
+	require.NoError(t, bm.Unmount(source, dest, 0, 0, nil), "Failed in unmount")
+
+	err = bm.Unmount(source, dest, 0, 0, nil)
+	require.True(t, errors.Is(err, ErrEnoent), "Expected ErrEnoent: %v", err)
+
+	restricted, err := New(BindMount, mi, nil, nil, []string{"/var/lib/osd"}, "")
+	require.NoError(t, err, "Failed to setup test")
+	err = restricted.Mount(0, source, dest, "", syscall.MS_BIND, "", 0, nil)
+	require.True(t, errors.Is(err, ErrMountpathNotAllowed), "Expected ErrMountpathNotAllowed: %v", err)
+}
+
+func TestMountErrorErrno(t *testing.T) {
+	mi := newTestMountImpl()
+	bm, err := New(BindMount, mi, nil, nil, []string{}, "")
+	require.NoError(t, err, "Failed to setup test")
+
+	cleandir(source)
+	cleandir(dest)
+	for _, errno := range []syscall.Errno{syscall.EBUSY, syscall.ENODEV, syscall.ENOSPC} {
+		mi.mountErr = errno
+		err = bm.Mount(0, source, dest, "ext4", syscall.MS_BIND, "", 0, nil)
+		var mountErr MountError
+		require.True(t, errors.As(err, &mountErr), "Expected a MountError: %v", err)
+		require.Equal(t, OpMount, mountErr.Op)
+		require.Equal(t, source, mountErr.Device)
+		require.Equal(t, dest, mountErr.Path)
+		require.Equal(t, "ext4", mountErr.Fs)
+		require.Equal(t, errno, mountErr.Errno)
+		require.True(t, errors.Is(err, errno), "Expected errors.Is to match %v", errno)
+	}
+
+	mi.mountErr = errors.New("not a syscall error")
+	err = bm.Mount(0, source, dest, "", syscall.MS_BIND, "", 0, nil)
+	require.False(t, errors.As(err, &MountError{}), "Unexpected MountError: %v", err)
+	require.True(t, errors.Is(err, mi.mountErr), "Expected the backend error to be wrapped")
+
+	mi.mountErr = nil
+	require.NoError(t, bm.Mount(0, source, dest, "", syscall.MS_BIND, "", 0, nil), "Failed in mount")
+	mi.unmountErr = syscall.EBUSY
+	err = bm.Unmount(source, dest, 0, 0, nil)
+	var mountErr MountError
+	require.True(t, errors.As(err, &mountErr), "Expected a MountError: %v", err)
+	require.Equal(t, OpUnmount, mountErr.Op)
+	require.Equal(t, syscall.EBUSY, mountErr.Errno)
+
+	mi.unmountErr = nil
+	require.NoError(t, bm.Unmount(source, dest, 0, 0, nil), "Failed in unmount")
+}
+
 func shutdown(t *testing.T, source, dest string) {
 	os.RemoveAll(dest)
 	os.RemoveAll(source)
@@ -245,3 +497,64 @@ func makeFile(pathname string) error {
 
 	return nil
 }
+
+func TestNewAuto(t *testing.T) {
+	m, err := NewAuto("/dev/sdz", newTestMountImpl(), nil)
+	require.NoError(t, err)
+	require.IsType(t, &deviceMounter{}, m)
+
+	for _, source := range []string{"server:/export", "10.0.0.1:/export/a", "[fd00::1]:/export"} {
+		m, err = NewAuto(source, newTestMountImpl(), nil)
+		require.NoError(t, err, source)
+		require.IsType(t, &nfsMounter{}, m, source)
+	}
+
+	m, err = NewAuto("10.0.0.1:/export", newTestMountImpl(), nil)
+	require.NoError(t, err)
+	nm := m.(*nfsMounter)
+	require.True(t, nm.serverExists("10.0.0.1"))
+	for _, server := range []string{"10.0.0.12", "110.0.0.1", "10a0b0c1"} {
+		require.False(t, nm.serverExists(server), "Expected %s not to match the server of the source", server)
+	}
+
+	for _, source := range []string{"", "tmpfs", "/var/lib/osd/image", ":/export", "server:export", "[]:/export"} {
+		_, err = NewAuto(source, newTestMountImpl(), nil)
+		require.True(t, errors.Is(err, ErrAmbiguousSource), "%q: got %v", source, err)
+	}
+}
+
+func TestNFSSourceHost(t *testing.T) {
+	host, ok := nfsSourceHost("[fd00::1]:/export")
+	require.True(t, ok)
+	require.Equal(t, "fd00::1", host)
+	host, ok = nfsSourceHost("nas.local:/a:/b")
+	require.True(t, ok)
+	require.Equal(t, "nas.local", host)
+}
+
+func TestVerifiedExists(t *testing.T) {
+	m, _ := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/dev/verified", "/mnt/verified", "ext4", 0, "", 0, nil))
+	setLoadFixture(t, []*mount.Info{
+		{Source: "/dev/verified", Mountpoint: "/mnt/verified", Fstype: "ext4"},
+	})
+
+	exists, err := m.VerifiedExists("/dev/verified", "/mnt/verified")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	// The mount is removed behind the Mounter's back.
+	setLoadFixture(t, nil)
+	exists, err = m.Exists("/dev/verified", "/mnt/verified")
+	require.NoError(t, err)
+	require.True(t, exists, "Expected Exists to trust the table")
+	exists, err = m.VerifiedExists("/dev/verified", "/mnt/verified")
+	require.NoError(t, err)
+	require.False(t, exists, "Expected VerifiedExists to check the mount table")
+
+	exists, err = m.VerifiedExists("/dev/verified", "/mnt/other")
+	require.NoError(t, err)
+	require.False(t, exists)
+	_, err = m.VerifiedExists("/dev/other", "/mnt/verified")
+	require.Equal(t, ErrEnoent, err)
+}
@@ -0,0 +1,141 @@
+//go:build windows || darwin
+// +build windows darwin
+
+package mount
+
+import (
+	"os"
+
+	"github.com/docker/docker/pkg/mount"
+)
+
+// msBind is zero as bind mounts are specific to Linux.
+const msBind = 0
+
+// msRdonly is MNT_RDONLY on Darwin and unused on Windows.
+const msRdonly = 0x1
+
+// msRemount is zero as it is only used together with msBind.
+const msRemount = 0
+
+// msMove is zero as moving mounts is specific to Linux.
+const msMove = 0
+
+// msRec is zero as it is only used together with msBind.
+const msRec = 0
+
+// mntDetach is zero as lazy unmounts are specific to Linux.
+const mntDetach = 0
+
+// msNosymfollow is zero as it is specific to Linux.
+const msNosymfollow = 0
+
+// kernelFlags is empty as no flag depends on the kernel version.
+var kernelFlags []kernelFlag
+
+// hostKernelVersion returns ErrUnsupported as only Linux versions are
+// compared.
+func hostKernelVersion() (kernelVersion, error) {
+	return kernelVersion{}, ErrUnsupported
+}
+
+// mountFlagOptions only maps read-only options outside Linux.
+var mountFlagOptions = []mountFlagOption{
+	{name: "ro", flag: msRdonly},
+	{name: "rw", flag: msRdonly, clear: true},
+}
+
+// defaultDeviceCheck is nil as devices are not validated outside Linux.
+var defaultDeviceCheck func(device string) error
+
+// defaultFsOps is a no-op as there is no FS_IMMUTABLE_FL outside Linux.
+var defaultFsOps fsOps = noopFsOps{}
+
+// noopFsOps implements fsOps without changing any attributes.
+type noopFsOps struct {
+	osOwnerOps
+	osWriteProbe
+}
+
+// Relabel returns ErrUnsupported as SELinux is specific to Linux.
+func (noopFsOps) Relabel(path, label string) error {
+	return ErrUnsupported
+}
+
+func (noopFsOps) IsImmutable(path string) bool {
+	return false
+}
+
+func (noopFsOps) AddImmutable(path string) error {
+	return nil
+}
+
+func (noopFsOps) RemoveImmutable(path string) error {
+	return nil
+}
+
+// GetMounts returns an empty mount table as there is no mountinfo outside
+// Linux. Mounters start out empty and track only the mounts made through them.
+func GetMounts() ([]*mount.Info, error) {
+	return []*mount.Info{}, nil
+}
+
+// defaultLoopDevices fails as loop devices are specific to Linux.
+var defaultLoopDevices loopDevices = unsupportedLoopDevices{}
+
+// unsupportedLoopDevices implements loopDevices by returning ErrUnsupported.
+type unsupportedLoopDevices struct{}
+
+func (unsupportedLoopDevices) Attach(image string, readOnly bool) (string, error) {
+	return "", ErrUnsupported
+}
+
+func (unsupportedLoopDevices) Detach(device string) error {
+	return ErrUnsupported
+}
+
+// defaultCryptDevices fails as dm-crypt is specific to Linux.
+var defaultCryptDevices cryptDevices = unsupportedCryptDevices{}
+
+// unsupportedCryptDevices implements cryptDevices by returning ErrUnsupported.
+type unsupportedCryptDevices struct{}
+
+func (unsupportedCryptDevices) Open(device, name string, key []byte) error {
+	return ErrUnsupported
+}
+
+func (unsupportedCryptDevices) Close(name string) error {
+	return ErrUnsupported
+}
+
+// defaultIdmapSyscalls fails as idmapped mounts are specific to Linux.
+var defaultIdmapSyscalls idmapSyscalls = unsupportedIdmapSyscalls{}
+
+// unsupportedIdmapSyscalls implements idmapSyscalls by returning
+// ErrUnsupported.
+type unsupportedIdmapSyscalls struct{}
+
+func (unsupportedIdmapSyscalls) OpenTree(path string) (int, error) {
+	return -1, ErrUnsupported
+}
+
+func (unsupportedIdmapSyscalls) SetIdmap(fd, usernsFd int) error {
+	return ErrUnsupported
+}
+
+func (unsupportedIdmapSyscalls) MoveMount(fd int, target string) error {
+	return ErrUnsupported
+}
+
+func (unsupportedIdmapSyscalls) Open(path string) (int, error) {
+	return -1, ErrUnsupported
+}
+
+func (unsupportedIdmapSyscalls) Close(fd int) error {
+	return ErrUnsupported
+}
+
+// openSubpath fails as bind mounts are specific to Linux.
+func openSubpath(root, path string) (*os.File, error) {
+	return nil, ErrUnsupported
+}
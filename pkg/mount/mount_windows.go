@@ -0,0 +1,39 @@
+//go:build windows
+// +build windows
+
+package mount
+
+import (
+	"os"
+)
+
+// DefaultMounter is a stub on Windows, every call returns ErrUnsupported.
+type DefaultMounter struct {
+}
+
+// Mount is not supported on Windows.
+func (m *DefaultMounter) Mount(
+	source string,
+	target string,
+	fstype string,
+	flags uintptr,
+	data string,
+	timeout int,
+) error {
+	return ErrUnsupported
+}
+
+// Unmount is not supported on Windows.
+func (m *DefaultMounter) Unmount(target string, flags int, timeout int) error {
+	return ErrUnsupported
+}
+
+// statDev fails as Windows files have no device number.
+func statDev(fi os.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// statfsUsage is not supported on Windows.
+func statfsUsage(path string) (MountUsage, error) {
+	return MountUsage{}, ErrUnsupported
+}
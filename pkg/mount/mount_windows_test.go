@@ -0,0 +1,37 @@
+//go:build windows
+// +build windows
+
+package mount
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowsMounter(t *testing.T) {
+	m, err := New(DeviceMount, nil, nil, nil, []string{}, "")
+	require.NoError(t, err, "Unexpected error on New")
+
+	dm := m.(*deviceMounter)
+	dm.setTestInfo("dev1", &Info{
+		Device:     "dev1",
+		Fs:         "ntfs",
+		Mountpoint: []*PathInfo{{Path: `C:\mnt\dev1`}},
+	})
+	dm.reindex()
+
+	require.Equal(t, 1, m.HasMounts("dev1"))
+	require.Equal(t, []string{`C:\mnt\dev1`}, m.Mounts("dev1"))
+	dev, ok := m.HasTarget(`C:\mnt\dev1`)
+	require.True(t, ok, "Expected a target mountpoint")
+	require.Equal(t, "dev1", dev)
+
+	err = m.Mount(0, "dev2", `C:\mnt\dev2`, "ntfs", 0, "", 0, nil)
+	require.True(t, errors.Is(err, ErrUnsupported), "Expected ErrUnsupported: %v", err)
+	err = m.Unmount("dev1", `C:\mnt\dev1`, 0, 0, nil)
+	require.True(t, errors.Is(err, ErrUnsupported), "Expected ErrUnsupported: %v", err)
+	require.Equal(t, 1, m.HasMounts("dev1"), "Failed calls must not change the table")
+	require.Equal(t, 0, m.HasMounts("dev2"), "Failed calls must not change the table")
+}
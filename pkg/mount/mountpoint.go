@@ -0,0 +1,48 @@
+package mount
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// IsMountpoint returns true if path is a mountpoint in the kernel, regardless
+// of the mounts tracked by any Mounter. path is a mountpoint if its device
+// differs from the device of its parent directory. Bind mounts and mounts of
+// the parent's filesystem share the parent's device, for these the mount
+// table is consulted.
+func IsMountpoint(path string) (bool, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return false, err
+	}
+	if path == filepath.Dir(path) {
+		return true, nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	parent, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return false, err
+	}
+	dev, ok := statDev(fi)
+	parentDev, parentOk := statDev(parent)
+	if !ok || !parentOk {
+		return false, ErrUnsupported
+	}
+	if dev != parentDev {
+		return true, nil
+	}
+	return inMountTable(path)
+}
+
+// IsMountpoint returns true if path is a mountpoint in the kernel. Unlike
+// HasTarget it does not consult the mounts tracked by m.
+func (m *Mounter) IsMountpoint(path string) (bool, error) {
+	return IsMountpoint(path)
+}
@@ -0,0 +1,59 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsMountpoint(t *testing.T) {
+	isMount, err := IsMountpoint("/")
+	require.NoError(t, err)
+	require.True(t, isMount)
+	isMount, err = IsMountpoint("/proc")
+	require.NoError(t, err)
+	require.True(t, isMount)
+
+	dir := t.TempDir()
+	isMount, err = IsMountpoint(dir)
+	require.NoError(t, err)
+	require.False(t, isMount)
+
+	_, err = IsMountpoint(filepath.Join(dir, "missing"))
+	require.True(t, os.IsNotExist(err), "Unexpected error %v", err)
+}
+
+func TestIsMountpointRealMounts(t *testing.T) {
+	dir := t.TempDir()
+	tmpfs := filepath.Join(dir, "tmpfs")
+	bind := filepath.Join(dir, "bind")
+	link := filepath.Join(dir, "link")
+	require.NoError(t, os.Mkdir(tmpfs, 0755))
+	require.NoError(t, os.Mkdir(bind, 0755))
+	require.NoError(t, os.Symlink(tmpfs, link))
+
+	if err := syscall.Mount("tmpfs", tmpfs, "tmpfs", 0, ""); err != nil {
+		t.Skipf("Cannot mount: %v", err)
+	}
+	defer syscall.Unmount(tmpfs, 0)
+	isMount, err := IsMountpoint(tmpfs)
+	require.NoError(t, err)
+	require.True(t, isMount)
+	isMount, err = IsMountpoint(link)
+	require.NoError(t, err)
+	require.True(t, isMount, "Expected the symlink target to be checked")
+
+	// A bind mount of the parent's filesystem has the parent's st_dev.
+	require.NoError(t, syscall.Mount(dir, bind, "", syscall.MS_BIND, ""))
+	defer syscall.Unmount(bind, 0)
+	m, _ := newTestMounter(t)
+	isMount, err = m.IsMountpoint(bind)
+	require.NoError(t, err)
+	require.True(t, isMount)
+}
@@ -0,0 +1,382 @@
+package mounttest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/libopenstorage/openstorage/pkg/mount"
+	"github.com/libopenstorage/openstorage/pkg/options"
+)
+
+// Manager methods for which FakeManager errors can be programmed.
+const (
+	MethodLoad              = "Load"
+	MethodReload            = "Reload"
+	MethodMount             = "Mount"
+	MethodUnmount           = "Unmount"
+	MethodRemoveMountPath   = "RemoveMountPath"
+	MethodEmptyTrashDir     = "EmptyTrashDir"
+	MethodClose             = "Close"
+	MethodValidateMountpath = "ValidateMountpath"
+)
+
+// FakeManager is a mount.Manager that tracks mounts purely in memory. Unlike
+// a mount.Mounter backed by FakeMounter, it never changes file attributes and
+// RemoveMountPath only records the path instead of scheduling a removal. It
+// is safe for concurrent use.
+type FakeManager struct {
+	sync.Mutex
+	mounts  mount.DeviceMap
+	errs    map[string]error
+	removed []string
+	usage   map[string]mount.MountUsage
+	trashed int
+	closed  bool
+}
+
+var _ mount.Manager = &FakeManager{}
+
+// NewFakeManager returns a FakeManager with no mounts.
+func NewFakeManager() *FakeManager {
+	return &FakeManager{
+		mounts: make(mount.DeviceMap),
+		errs:   make(map[string]error),
+		usage:  make(map[string]mount.MountUsage),
+	}
+}
+
+// SetError makes every call of method fail with err. A nil err clears it.
+func (f *FakeManager) SetError(method string, err error) {
+	f.Lock()
+	defer f.Unlock()
+	setErr(f.errs, method, err)
+}
+
+// RemovedPaths returns the paths passed to RemoveMountPath, directly or
+// through Unmount with options.OptionsDeleteAfterUnmount.
+func (f *FakeManager) RemovedPaths() []string {
+	f.Lock()
+	defer f.Unlock()
+	return append([]string(nil), f.removed...)
+}
+
+// EmptyTrashDirCount returns the number of successful EmptyTrashDir calls.
+func (f *FakeManager) EmptyTrashDirCount() int {
+	f.Lock()
+	defer f.Unlock()
+	return f.trashed
+}
+
+// String representation of the mount table.
+func (f *FakeManager) String() string {
+	f.Lock()
+	defer f.Unlock()
+	devices := make([]string, 0, len(f.mounts))
+	for device := range f.mounts {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+	var b strings.Builder
+	for _, device := range devices {
+		info := f.mounts[device]
+		fmt.Fprintf(&b, "%s (%s):", device, info.Fs)
+		for _, p := range info.Mountpoint {
+			fmt.Fprintf(&b, " %s", p.Path)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Reload returns the error programmed for MethodReload.
+func (f *FakeManager) Reload(source string) error {
+	f.Lock()
+	defer f.Unlock()
+	return f.errs[MethodReload]
+}
+
+// Load returns the error programmed for MethodLoad.
+func (f *FakeManager) Load(source []*regexp.Regexp) error {
+	f.Lock()
+	defer f.Unlock()
+	return f.errs[MethodLoad]
+}
+
+// Inspect returns copies of the mountpoints of source.
+func (f *FakeManager) Inspect(source string) []*mount.PathInfo {
+	f.Lock()
+	defer f.Unlock()
+	info, ok := f.mounts[source]
+	if !ok {
+		return nil
+	}
+	paths := make([]*mount.PathInfo, 0, len(info.Mountpoint))
+	for _, p := range info.Mountpoint {
+		c := *p
+		paths = append(paths, &c)
+	}
+	return paths
+}
+
+// Mounts returns the paths source is mounted at.
+func (f *FakeManager) Mounts(source string) []string {
+	f.Lock()
+	defer f.Unlock()
+	info, ok := f.mounts[source]
+	if !ok {
+		return nil
+	}
+	paths := make([]string, 0, len(info.Mountpoint))
+	for _, p := range info.Mountpoint {
+		paths = append(paths, p.Path)
+	}
+	return paths
+}
+
+// HasMounts returns the number of mountpoints of source.
+func (f *FakeManager) HasMounts(source string) int {
+	f.Lock()
+	defer f.Unlock()
+	info, ok := f.mounts[source]
+	if !ok {
+		return 0
+	}
+	return len(info.Mountpoint)
+}
+
+// HasTarget returns the source mounted at target.
+func (f *FakeManager) HasTarget(target string) (string, bool) {
+	f.Lock()
+	defer f.Unlock()
+	info, _ := f.find(normalizeMountPath(target))
+	if info == nil {
+		return "", false
+	}
+	return info.Device, true
+}
+
+// Exists returns true if source is mounted at path and ErrEnoent if source
+// is not mounted.
+func (f *FakeManager) Exists(source, path string) (bool, error) {
+	f.Lock()
+	defer f.Unlock()
+	info, ok := f.mounts[source]
+	if !ok {
+		return false, mount.ErrEnoent
+	}
+	path = normalizeMountPath(path)
+	for _, p := range info.Mountpoint {
+		if p.Path == path {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetRootPath returns the root of the mount at mountPath.
+func (f *FakeManager) GetRootPath(mountPath string) (string, error) {
+	f.Lock()
+	defer f.Unlock()
+	_, p := f.find(normalizeMountPath(mountPath))
+	if p == nil {
+		return "", mount.ErrEnoent
+	}
+	return p.Root, nil
+}
+
+// GetSourcePath returns the source mounted at mountPath.
+func (f *FakeManager) GetSourcePath(mountPath string) (string, error) {
+	f.Lock()
+	defer f.Unlock()
+	info, _ := f.find(normalizeMountPath(mountPath))
+	if info == nil {
+		return "", mount.ErrEnoent
+	}
+	return info.Device, nil
+}
+
+// GetSourcePaths returns all tracked sources.
+func (f *FakeManager) GetSourcePaths() []string {
+	f.Lock()
+	defer f.Unlock()
+	sources := make([]string, 0, len(f.mounts))
+	for source := range f.mounts {
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// Mount records device as mounted at path following the rules of
+// mount.Mounter: a path mounted by another device returns mount.ErrExist, a
+// filesystem mismatch returns mount.ErrEinval and mounting an existing
+// mountpoint again is a no-op.
+func (f *FakeManager) Mount(
+	minor int,
+	device string,
+	path string,
+	fs string,
+	flags uintptr,
+	data string,
+	timeout int,
+	opts map[string]string,
+) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed {
+		return mount.ErrClosed
+	}
+	if err := f.errs[MethodMount]; err != nil {
+		return err
+	}
+	if value, ok := opts[options.OptionsDeviceFuseMount]; ok {
+		device = value
+	}
+	path = normalizeMountPath(path)
+	existing, _ := f.find(path)
+	if existing != nil && existing.Device != device {
+		return mount.ErrExist
+	}
+	info, ok := f.mounts[device]
+	if !ok {
+		info = &mount.Info{Device: device, Minor: minor, Fs: fs}
+		f.mounts[device] = info
+	} else if !strings.HasPrefix(info.Fs, fs) {
+		return mount.ErrEinval
+	}
+	if existing != nil {
+		return nil
+	}
+	info.Mountpoint = append(info.Mountpoint, &mount.PathInfo{Path: path})
+	return nil
+}
+
+// Unmount removes path from the mountpoints of source. It returns
+// mount.ErrEnoent if source is not mounted at path.
+func (f *FakeManager) Unmount(source, path string, flags int, timeout int, opts map[string]string) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed {
+		return mount.ErrClosed
+	}
+	if err := f.errs[MethodUnmount]; err != nil {
+		return err
+	}
+	info, ok := f.mounts[source]
+	if !ok {
+		return mount.ErrEnoent
+	}
+	path = normalizeMountPath(path)
+	for i, p := range info.Mountpoint {
+		if p.Path != path {
+			continue
+		}
+		info.Mountpoint = append(info.Mountpoint[:i], info.Mountpoint[i+1:]...)
+		if len(info.Mountpoint) == 0 {
+			delete(f.mounts, source)
+		}
+		if options.IsBoolOptionSet(opts, options.OptionsDeleteAfterUnmount) {
+			f.removed = append(f.removed, path)
+		}
+		return nil
+	}
+	return mount.ErrEnoent
+}
+
+// RemoveMountPath records path as removed.
+func (f *FakeManager) RemoveMountPath(path string, opts map[string]string) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed {
+		return mount.ErrClosed
+	}
+	if err := f.errs[MethodRemoveMountPath]; err != nil {
+		return err
+	}
+	f.removed = append(f.removed, normalizeMountPath(path))
+	return nil
+}
+
+// EmptyTrashDir counts the call.
+func (f *FakeManager) EmptyTrashDir() error {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed {
+		return mount.ErrClosed
+	}
+	if err := f.errs[MethodEmptyTrashDir]; err != nil {
+		return err
+	}
+	f.trashed++
+	return nil
+}
+
+// Close makes Mount, Unmount, RemoveMountPath and EmptyTrashDir return
+// mount.ErrClosed.
+func (f *FakeManager) Close() error {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.errs[MethodClose]; err != nil {
+		return err
+	}
+	f.closed = true
+	return nil
+}
+
+// ValidateMountpath accepts every path unless an error is programmed for
+// MethodValidateMountpath.
+func (f *FakeManager) ValidateMountpath(path string) error {
+	f.Lock()
+	defer f.Unlock()
+	return f.errs[MethodValidateMountpath]
+}
+
+// SetUsage sets the usage Usage returns for path.
+func (f *FakeManager) SetUsage(path string, usage mount.MountUsage) {
+	f.Lock()
+	defer f.Unlock()
+	f.usage[normalizeMountPath(path)] = usage
+}
+
+// Usage returns the usage set with SetUsage for path, or a zero usage. It
+// returns mount.ErrEnoent if nothing is mounted at path.
+func (f *FakeManager) Usage(path string) (mount.MountUsage, error) {
+	f.Lock()
+	defer f.Unlock()
+	path = normalizeMountPath(path)
+	if info, _ := f.find(path); info == nil {
+		return mount.MountUsage{}, mount.ErrEnoent
+	}
+	return f.usage[path], nil
+}
+
+// IsMountpoint returns true if a source is mounted at path. Being in memory,
+// it consults the tracked mounts instead of the kernel.
+func (f *FakeManager) IsMountpoint(path string) (bool, error) {
+	f.Lock()
+	defer f.Unlock()
+	info, _ := f.find(normalizeMountPath(path))
+	return info != nil, nil
+}
+
+// find returns the info and mountpoint for path.
+func (f *FakeManager) find(path string) (*mount.Info, *mount.PathInfo) {
+	for _, info := range f.mounts {
+		for _, p := range info.Mountpoint {
+			if p.Path == path {
+				return info, p
+			}
+		}
+	}
+	return nil, nil
+}
+
+// normalizeMountPath removes the trailing slash, as mount.Mounter does.
+func normalizeMountPath(path string) string {
+	if len(path) > 1 && strings.HasSuffix(path, "/") {
+		return path[:len(path)-1]
+	}
+	return path
+}
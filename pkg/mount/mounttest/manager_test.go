@@ -0,0 +1,171 @@
+package mounttest
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/libopenstorage/openstorage/pkg/mount"
+	"github.com/libopenstorage/openstorage/pkg/options"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeManagerMounts(t *testing.T) {
+	var m mount.Manager = NewFakeManager()
+
+	require.NoError(t, m.Load(nil))
+	require.NoError(t, m.Mount(1, "/dev/sda", "/mnt/a/", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(1, "/dev/sda", "/mnt/b", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(1, "/dev/sda", "/mnt/b", "ext4", 0, "", 0, nil), "Remount should be a no-op")
+	require.NoError(t, m.Mount(2, "/dev/sdb", "/mnt/c", "xfs", 0, "", 0, nil))
+	require.Equal(t, mount.ErrExist, m.Mount(2, "/dev/sdb", "/mnt/a", "xfs", 0, "", 0, nil))
+	require.Equal(t, mount.ErrEinval, m.Mount(2, "/dev/sdb", "/mnt/d", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "fuse-src", "/mnt/fuse", "fuse", 0, "", 0,
+		map[string]string{options.OptionsDeviceFuseMount: "fuse-dev"}))
+
+	require.Equal(t, 2, m.HasMounts("/dev/sda"))
+	require.Equal(t, 0, m.HasMounts("/dev/sdz"))
+	require.Equal(t, []string{"/mnt/a", "/mnt/b"}, m.Mounts("/dev/sda"))
+	require.Len(t, m.Inspect("/dev/sdb"), 1)
+	require.Nil(t, m.Inspect("/dev/sdz"))
+	dev, ok := m.HasTarget("/mnt/c/")
+	require.True(t, ok)
+	require.Equal(t, "/dev/sdb", dev)
+	require.Equal(t, 1, m.HasMounts("fuse-dev"))
+	isMount, err := m.IsMountpoint("/mnt/c")
+	require.NoError(t, err)
+	require.True(t, isMount)
+	isMount, err = m.IsMountpoint("/mnt/z")
+	require.NoError(t, err)
+	require.False(t, isMount)
+
+	exists, err := m.Exists("/dev/sda", "/mnt/a")
+	require.NoError(t, err)
+	require.True(t, exists)
+	exists, err = m.Exists("/dev/sda", "/mnt/c")
+	require.NoError(t, err)
+	require.False(t, exists)
+	_, err = m.Exists("/dev/sdz", "/mnt/a")
+	require.Equal(t, mount.ErrEnoent, err)
+
+	source, err := m.GetSourcePath("/mnt/b")
+	require.NoError(t, err)
+	require.Equal(t, "/dev/sda", source)
+	_, err = m.GetSourcePath("/mnt/z")
+	require.Equal(t, mount.ErrEnoent, err)
+	root, err := m.GetRootPath("/mnt/b")
+	require.NoError(t, err)
+	require.Equal(t, "", root)
+	_, err = m.GetRootPath("/mnt/z")
+	require.Equal(t, mount.ErrEnoent, err)
+
+	sources := m.GetSourcePaths()
+	sort.Strings(sources)
+	require.Equal(t, []string{"/dev/sda", "/dev/sdb", "fuse-dev"}, sources)
+	require.Contains(t, m.String(), "/dev/sda (ext4): /mnt/a /mnt/b")
+}
+
+func TestFakeManagerUnmount(t *testing.T) {
+	f := NewFakeManager()
+	require.NoError(t, f.Mount(1, "/dev/sda", "/mnt/a", "ext4", 0, "", 0, nil))
+	require.NoError(t, f.Mount(1, "/dev/sda", "/mnt/b", "ext4", 0, "", 0, nil))
+
+	require.Equal(t, mount.ErrEnoent, f.Unmount("/dev/sdz", "/mnt/a", 0, 0, nil))
+	require.Equal(t, mount.ErrEnoent, f.Unmount("/dev/sda", "/mnt/z", 0, 0, nil))
+	require.NoError(t, f.Unmount("/dev/sda", "/mnt/a", 0, 0,
+		map[string]string{options.OptionsDeleteAfterUnmount: "true"}))
+	require.NoError(t, f.Unmount("/dev/sda", "/mnt/b", 0, 0, nil))
+	require.Equal(t, 0, f.HasMounts("/dev/sda"))
+	require.Empty(t, f.GetSourcePaths())
+	require.Equal(t, []string{"/mnt/a"}, f.RemovedPaths())
+
+	require.NoError(t, f.RemoveMountPath("/mnt/b", nil))
+	require.Equal(t, []string{"/mnt/a", "/mnt/b"}, f.RemovedPaths())
+	require.NoError(t, f.EmptyTrashDir())
+	require.Equal(t, 1, f.EmptyTrashDirCount())
+}
+
+func TestFakeManagerErrors(t *testing.T) {
+	f := NewFakeManager()
+	errFail := errors.New("injected")
+	for _, method := range []string{
+		MethodLoad,
+		MethodReload,
+		MethodMount,
+		MethodUnmount,
+		MethodRemoveMountPath,
+		MethodEmptyTrashDir,
+		MethodValidateMountpath,
+	} {
+		f.SetError(method, errFail)
+	}
+	require.Equal(t, errFail, f.Load(nil))
+	require.Equal(t, errFail, f.Reload("/dev/sda"))
+	require.Equal(t, errFail, f.Mount(1, "/dev/sda", "/mnt/a", "ext4", 0, "", 0, nil))
+	require.Equal(t, 0, f.HasMounts("/dev/sda"))
+	require.Equal(t, errFail, f.Unmount("/dev/sda", "/mnt/a", 0, 0, nil))
+	require.Equal(t, errFail, f.RemoveMountPath("/mnt/a", nil))
+	require.Equal(t, errFail, f.EmptyTrashDir())
+	require.Equal(t, errFail, f.ValidateMountpath("/mnt/a"))
+	require.Empty(t, f.RemovedPaths())
+
+	f.SetError(MethodMount, nil)
+	require.NoError(t, f.Mount(1, "/dev/sda", "/mnt/a", "ext4", 0, "", 0, nil))
+	require.Equal(t, errFail, f.Unmount("/dev/sda", "/mnt/a", 0, 0, nil))
+	require.Equal(t, 1, f.HasMounts("/dev/sda"))
+}
+
+func TestFakeManagerClose(t *testing.T) {
+	f := NewFakeManager()
+	require.NoError(t, f.Mount(1, "/dev/sda", "/mnt/a", "ext4", 0, "", 0, nil))
+	require.NoError(t, f.Close())
+
+	require.Equal(t, mount.ErrClosed, f.Mount(1, "/dev/sdb", "/mnt/b", "ext4", 0, "", 0, nil))
+	require.Equal(t, mount.ErrClosed, f.Unmount("/dev/sda", "/mnt/a", 0, 0, nil))
+	require.Equal(t, mount.ErrClosed, f.RemoveMountPath("/mnt/a", nil))
+	require.Equal(t, mount.ErrClosed, f.EmptyTrashDir())
+	require.Equal(t, 1, f.HasMounts("/dev/sda"))
+}
+
+func TestFakeManagerUsage(t *testing.T) {
+	f := NewFakeManager()
+	require.NoError(t, f.Mount(1, "/dev/sda", "/mnt/a", "ext4", 0, "", 0, nil))
+	usage := mount.MountUsage{TotalBytes: 100, FreeBytes: 40, AvailableBytes: 30}
+	f.SetUsage("/mnt/a/", usage)
+
+	got, err := f.Usage("/mnt/a")
+	require.NoError(t, err)
+	require.Equal(t, usage, got)
+	_, err = f.Usage("/mnt/z")
+	require.Equal(t, mount.ErrEnoent, err)
+}
+
+func TestFakeManagerTrailingSlash(t *testing.T) {
+	f := NewFakeManager()
+	require.NoError(t, f.Mount(1, "/dev/sda", "/mnt/a", "ext4", 0, "", 0, nil))
+
+	exists, err := f.Exists("/dev/sda", "/mnt/a/")
+	require.NoError(t, err)
+	require.True(t, exists)
+	source, err := f.GetSourcePath("/mnt/a/")
+	require.NoError(t, err)
+	require.Equal(t, "/dev/sda", source)
+	root, err := f.GetRootPath("/mnt/a/")
+	require.NoError(t, err)
+	require.Equal(t, "", root)
+	require.NoError(t, f.RemoveMountPath("/mnt/b/", nil))
+	require.Equal(t, []string{"/mnt/b"}, f.RemovedPaths())
+}
+
+func TestFakeManagerInspectCopy(t *testing.T) {
+	f := NewFakeManager()
+	require.NoError(t, f.Mount(1, "/dev/sda", "/mnt/a", "ext4", 0, "", 0, nil))
+
+	paths := f.Inspect("/dev/sda")
+	require.Len(t, paths, 1)
+	paths[0].Path = "/mnt/changed"
+	paths[0] = nil
+	require.Equal(t, []*mount.PathInfo{{Path: "/mnt/a"}}, f.Inspect("/dev/sda"),
+		"Expected changes to the returned mountpoints not to reach the tracked ones")
+	require.Equal(t, []string{"/mnt/a"}, f.Mounts("/dev/sda"))
+}
@@ -0,0 +1,239 @@
+// Package mounttest provides a fake mount.MountImpl and an in-memory
+// mount.Manager for testing code that depends on package mount without
+// touching the kernel.
+package mounttest
+
+import (
+	"sync"
+	"syscall"
+
+	"github.com/libopenstorage/openstorage/pkg/mount"
+)
+
+const (
+	// OpMount is the Call operation recorded for Mount.
+	OpMount = mount.OpMount
+	// OpUnmount is the Call operation recorded for Unmount.
+	OpUnmount = mount.OpUnmount
+)
+
+// Call records the arguments of a Mount or Unmount call on a FakeMounter.
+// Source, Fstype, Flags and Data are set for mounts only and UnmountFlags for
+// unmounts only.
+type Call struct {
+	Op           string
+	Source       string
+	Target       string
+	Fstype       string
+	Flags        uintptr
+	Data         string
+	UnmountFlags int
+	Timeout      int
+	// Err is the error returned to the caller.
+	Err error
+}
+
+// TestingT is the subset of testing.T used by the assertion helpers.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// FakeMounter is a mount.MountImpl that keeps an in-memory mount table and
+// records every call. Errors can be programmed per target or for the next
+// call. It is safe for concurrent use.
+type FakeMounter struct {
+	sync.Mutex
+	mounted     map[string]string
+	calls       []Call
+	mountErrs   map[string]error
+	unmountErrs map[string]error
+	nextMount   []error
+	nextUnmount []error
+}
+
+var _ mount.MountImpl = &FakeMounter{}
+
+// NewFakeMounter returns a FakeMounter with nothing mounted.
+func NewFakeMounter() *FakeMounter {
+	return &FakeMounter{
+		mounted:     make(map[string]string),
+		mountErrs:   make(map[string]error),
+		unmountErrs: make(map[string]error),
+	}
+}
+
+// Mount records the call and mounts source at target unless an error is
+// programmed for it.
+func (f *FakeMounter) Mount(
+	source string,
+	target string,
+	fstype string,
+	flags uintptr,
+	data string,
+	timeout int,
+) error {
+	f.Lock()
+	defer f.Unlock()
+	err := f.nextErr(&f.nextMount, f.mountErrs[target])
+	if err == nil {
+		f.mounted[target] = source
+	}
+	f.calls = append(f.calls, Call{
+		Op:      OpMount,
+		Source:  source,
+		Target:  target,
+		Fstype:  fstype,
+		Flags:   flags,
+		Data:    data,
+		Timeout: timeout,
+		Err:     err,
+	})
+	return err
+}
+
+// Unmount records the call and unmounts target unless an error is programmed
+// for it. Unmounting a target that is not mounted returns EINVAL.
+func (f *FakeMounter) Unmount(target string, flags int, timeout int) error {
+	f.Lock()
+	defer f.Unlock()
+	err := f.nextErr(&f.nextUnmount, f.unmountErrs[target])
+	if err == nil {
+		if _, ok := f.mounted[target]; ok {
+			delete(f.mounted, target)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	f.calls = append(f.calls, Call{
+		Op:           OpUnmount,
+		Target:       target,
+		UnmountFlags: flags,
+		Timeout:      timeout,
+		Err:          err,
+	})
+	return err
+}
+
+// nextErr pops the next one-shot error from queue, or returns err.
+func (f *FakeMounter) nextErr(queue *[]error, err error) error {
+	if len(*queue) > 0 {
+		err = (*queue)[0]
+		*queue = (*queue)[1:]
+	}
+	return err
+}
+
+// SetMountError makes every Mount at target fail with err. A nil err clears it.
+func (f *FakeMounter) SetMountError(target string, err error) {
+	f.Lock()
+	defer f.Unlock()
+	setErr(f.mountErrs, target, err)
+}
+
+// SetUnmountError makes every Unmount of target fail with err. A nil err
+// clears it.
+func (f *FakeMounter) SetUnmountError(target string, err error) {
+	f.Lock()
+	defer f.Unlock()
+	setErr(f.unmountErrs, target, err)
+}
+
+func setErr(errs map[string]error, target string, err error) {
+	if err == nil {
+		delete(errs, target)
+		return
+	}
+	errs[target] = err
+}
+
+// FailNextMount makes the next Mount fail with err, regardless of its target.
+// Repeated calls queue errors for the following mounts.
+func (f *FakeMounter) FailNextMount(err error) {
+	f.Lock()
+	defer f.Unlock()
+	f.nextMount = append(f.nextMount, err)
+}
+
+// FailNextUnmount makes the next Unmount fail with err, regardless of its
+// target. Repeated calls queue errors for the following unmounts.
+func (f *FakeMounter) FailNextUnmount(err error) {
+	f.Lock()
+	defer f.Unlock()
+	f.nextUnmount = append(f.nextUnmount, err)
+}
+
+// Calls returns the recorded calls in order.
+func (f *FakeMounter) Calls() []Call {
+	f.Lock()
+	defer f.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Reset forgets the recorded calls. The mount table and programmed errors are
+// kept.
+func (f *FakeMounter) Reset() {
+	f.Lock()
+	defer f.Unlock()
+	f.calls = nil
+}
+
+// Source returns the source mounted at target.
+func (f *FakeMounter) Source(target string) (string, bool) {
+	f.Lock()
+	defer f.Unlock()
+	source, ok := f.mounted[target]
+	return source, ok
+}
+
+// Mounted returns a copy of the mount table, mapping targets to sources.
+func (f *FakeMounter) Mounted() map[string]string {
+	f.Lock()
+	defer f.Unlock()
+	mounted := make(map[string]string, len(f.mounted))
+	for target, source := range f.mounted {
+		mounted[target] = source
+	}
+	return mounted
+}
+
+// AssertMounted fails t unless device is mounted at path.
+func (f *FakeMounter) AssertMounted(t TestingT, device, path string) bool {
+	t.Helper()
+	source, ok := f.Source(path)
+	if !ok {
+		t.Errorf("Expected %q to be mounted at %q, nothing is mounted", device, path)
+		return false
+	}
+	if source != device {
+		t.Errorf("Expected %q to be mounted at %q, found %q", device, path, source)
+		return false
+	}
+	return true
+}
+
+// AssertNotMounted fails t if anything is mounted at path.
+func (f *FakeMounter) AssertNotMounted(t TestingT, path string) bool {
+	t.Helper()
+	if source, ok := f.Source(path); ok {
+		t.Errorf("Expected nothing mounted at %q, found %q", path, source)
+		return false
+	}
+	return true
+}
+
+// AssertCallCount fails t unless op was called n times.
+func (f *FakeMounter) AssertCallCount(t TestingT, op string, n int) bool {
+	t.Helper()
+	count := 0
+	for _, c := range f.Calls() {
+		if c.Op == op {
+			count++
+		}
+	}
+	if count != n {
+		t.Errorf("Expected %d %s calls, found %d", n, op, count)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package mounttest
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingT is a TestingT that records failures instead of failing the test.
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestFakeMounterRecords(t *testing.T) {
+	f := NewFakeMounter()
+	require.NoError(t, f.Mount("/dev/sda", "/mnt/a", "ext4", 1, "discard", 5))
+	require.NoError(t, f.Unmount("/mnt/a", 2, 6))
+
+	require.Equal(t, []Call{
+		{Op: OpMount, Source: "/dev/sda", Target: "/mnt/a", Fstype: "ext4", Flags: 1, Data: "discard", Timeout: 5},
+		{Op: OpUnmount, Target: "/mnt/a", UnmountFlags: 2, Timeout: 6},
+	}, f.Calls())
+	f.AssertNotMounted(t, "/mnt/a")
+	f.AssertCallCount(t, OpMount, 1)
+
+	f.Reset()
+	require.Empty(t, f.Calls())
+	require.Equal(t, syscall.EINVAL, f.Unmount("/mnt/a", 0, 0))
+}
+
+func TestFakeMounterErrors(t *testing.T) {
+	f := NewFakeMounter()
+	errBusy := errors.New("busy")
+
+	f.SetMountError("/mnt/a", syscall.EPERM)
+	require.Equal(t, syscall.EPERM, f.Mount("/dev/sda", "/mnt/a", "ext4", 0, "", 0))
+	require.Equal(t, syscall.EPERM, f.Mount("/dev/sda", "/mnt/a", "ext4", 0, "", 0))
+	require.NoError(t, f.Mount("/dev/sdb", "/mnt/b", "ext4", 0, "", 0))
+	f.SetMountError("/mnt/a", nil)
+	require.NoError(t, f.Mount("/dev/sda", "/mnt/a", "ext4", 0, "", 0))
+
+	// One-shot errors take precedence and are consumed in order.
+	f.FailNextUnmount(errBusy)
+	f.FailNextUnmount(syscall.EIO)
+	require.Equal(t, errBusy, f.Unmount("/mnt/a", 0, 0))
+	require.Equal(t, syscall.EIO, f.Unmount("/mnt/b", 0, 0))
+	require.NoError(t, f.Unmount("/mnt/a", 0, 0))
+
+	f.SetUnmountError("/mnt/b", errBusy)
+	require.Equal(t, errBusy, f.Unmount("/mnt/b", 0, 0))
+	f.AssertMounted(t, "/dev/sdb", "/mnt/b")
+
+	f.FailNextMount(errBusy)
+	require.Equal(t, errBusy, f.Mount("/dev/sdc", "/mnt/c", "xfs", 0, "", 0))
+	f.AssertNotMounted(t, "/mnt/c")
+
+	calls := f.Calls()
+	require.Equal(t, errBusy, calls[len(calls)-1].Err)
+}
+
+func TestFakeMounterAssertions(t *testing.T) {
+	f := NewFakeMounter()
+	require.NoError(t, f.Mount("/dev/sda", "/mnt/a", "ext4", 0, "", 0))
+
+	r := &recordingT{}
+	require.True(t, f.AssertMounted(r, "/dev/sda", "/mnt/a"))
+	require.False(t, f.AssertMounted(r, "/dev/sdb", "/mnt/a"))
+	require.False(t, f.AssertMounted(r, "/dev/sda", "/mnt/b"))
+	require.False(t, f.AssertNotMounted(r, "/mnt/a"))
+	require.False(t, f.AssertCallCount(r, OpUnmount, 1))
+	require.Len(t, r.errors, 4)
+	require.Equal(t, map[string]string{"/mnt/a": "/dev/sda"}, f.Mounted())
+}
@@ -0,0 +1,132 @@
+package mount
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MoveMount moves the mount of device at oldPath to newPath with MS_MOVE. The
+// mountpoint keeps its flags, options and mount time. It returns ErrEnoent if
+// device is not tracked at oldPath and ErrExist if newPath is already a
+// mountpoint.
+func (m *Mounter) MoveMount(device, oldPath, newPath string, timeout int) error {
+	if msMove == 0 {
+		return ErrUnsupported
+	}
+	if m.isClosed() {
+		return ErrClosed
+	}
+	oldPath = normalizeMountPath(oldPath)
+	newPath = normalizeMountPath(newPath)
+	if oldPath == newPath {
+		return ErrExist
+	}
+	if err := m.validateMountpath(newPath); err != nil {
+		return err
+	}
+
+	// Path locks are taken in a fixed order so that two opposite moves
+	// cannot deadlock.
+	first, second := oldPath, newPath
+	if second < first {
+		first, second = second, first
+	}
+	h1 := m.kl.Acquire(first)
+	defer m.kl.Release(&h1)
+	h2 := m.kl.Acquire(second)
+	defer m.kl.Release(&h2)
+	dh := m.kl.Acquire(deviceLockKey(device))
+	defer m.kl.Release(&dh)
+
+	if _, ok := m.HasTarget(newPath); ok {
+		return ErrExist
+	}
+	info, ok := m.lookup(device)
+	if !ok {
+		return ErrEnoent
+	}
+
+	info.Lock()
+	found := false
+	for _, p := range info.Mountpoint {
+		if p.Path == oldPath {
+			found = true
+			break
+		}
+	}
+	if !found {
+		info.Unlock()
+		return ErrEnoent
+	}
+	// Like a new mountpoint, newPath is made immutable before it is mounted
+	// on.
+	pathWasReadOnly := m.isPathSetImmutable(newPath)
+	if err := m.makeMountpathReadOnly(newPath); err != nil {
+		info.Unlock()
+		return fmt.Errorf("failed to make %s readonly. Err: %w", newPath, err)
+	}
+	if err := m.mountImpl.Mount(oldPath, newPath, "", msMove, "", timeout); err != nil {
+		info.Unlock()
+		if !pathWasReadOnly {
+			if e := m.makeMountpathWriteable(newPath); e != nil {
+				m.logger.Warnf("Failed to make %s writeable after move failure: %v", newPath, e)
+			}
+		}
+		return fmt.Errorf("moving mount of %s from %s to %s: %w", device, oldPath, newPath, err)
+	}
+	info.Unlock()
+
+	m.moveMountpoint(device, info, oldPath, newPath)
+	if err := m.makeMountpathWriteable(oldPath); err != nil {
+		m.logger.Warnf("Failed to make %s writeable after moving its mount: %v", oldPath, err)
+	}
+	return nil
+}
+
+// moveMountpoint records that the mountpoint of device at oldPath, tracked in
+// info, is now at newPath.
+func (m *Mounter) moveMountpoint(device string, info *Info, oldPath, newPath string) {
+	m.Lock()
+	defer m.Unlock()
+	info.Lock()
+	for _, p := range info.Mountpoint {
+		if p.Path == oldPath {
+			p.Path = newPath
+			break
+		}
+	}
+	info.Unlock()
+	m.deletePath(oldPath, device)
+	m.addPath(newPath, device)
+	if source, ok := m.loadedSourceLocked(oldPath); ok {
+		m.deleteLoadedPath(oldPath, source)
+		m.setLoadedSourceLocked(newPath, source)
+	}
+	if e, ok := m.expiries[oldPath]; ok {
+		delete(m.expiries, oldPath)
+		e.path = newPath
+		m.expiries[newPath] = e
+	}
+	if backing, ok := m.deps[oldPath]; ok {
+		delete(m.deps, oldPath)
+		m.deps[newPath] = backing
+	}
+	// The directories under oldPath that back other mounts moved with it.
+	// The slices are replaced rather than updated, as teardownOrder reads
+	// them without the lock.
+	for path, backing := range m.deps {
+		var moved []string
+		for i, dir := range backing {
+			if !isWithin(oldPath, dir) {
+				continue
+			}
+			if moved == nil {
+				moved = append([]string(nil), backing...)
+			}
+			moved[i] = newPath + strings.TrimPrefix(dir, oldPath)
+		}
+		if moved != nil {
+			m.deps[path] = moved
+		}
+	}
+}
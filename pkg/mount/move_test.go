@@ -0,0 +1,84 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveMount(t *testing.T) {
+	m, mi := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/dev/move", "/mnt/old", "ext4", syscall.MS_NOATIME, "data", 0, nil))
+	before := m.Inspect("/dev/move")[0]
+	mountedAt, flags := before.MountedAt, before.Flags
+
+	require.NoError(t, m.MoveMount("/dev/move", "/mnt/old", "/mnt/new", 0))
+	call := mi.lastCall()
+	require.Equal(t, "/mnt/old", call.source)
+	require.Equal(t, "/mnt/new", call.target)
+	require.Equal(t, uintptr(syscall.MS_MOVE), call.flags)
+
+	paths := m.Inspect("/dev/move")
+	require.Len(t, paths, 1)
+	require.Equal(t, "/mnt/new", paths[0].Path)
+	require.Equal(t, flags, paths[0].Flags)
+	require.Equal(t, "data", paths[0].Data)
+	require.Equal(t, mountedAt, paths[0].MountedAt)
+	_, ok := m.HasTarget("/mnt/old")
+	require.False(t, ok)
+	dev, ok := m.HasTarget("/mnt/new")
+	require.True(t, ok)
+	require.Equal(t, "/dev/move", dev)
+
+	fsops := m.fsops.(*testFsOps)
+	require.True(t, fsops.IsImmutable("/mnt/new"))
+	require.False(t, fsops.IsImmutable("/mnt/old"))
+}
+
+func TestMoveMountTTL(t *testing.T) {
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	m, mi := newTestMounter(t, WithClock(clk), WithScheduler(s))
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/move", Path: "/mnt/old", Fs: "ext4", TTL: time.Hour,
+	}))
+	m.addDeps("/mnt/old", []string{"/mnt/lower"})
+	m.addDeps("/mnt/overlay", []string{"/mnt/old/upper", "/mnt/other"})
+
+	require.NoError(t, m.MoveMount("/dev/move", "/mnt/old", "/mnt/new", 0))
+	require.Equal(t, map[string][]string{
+		"/mnt/new":     {"/mnt/lower"},
+		"/mnt/overlay": {"/mnt/new/upper", "/mnt/other"},
+	}, m.deps)
+	require.Equal(t, ErrEnoent, m.Refresh("/mnt/old"))
+	s.Advance(30 * time.Minute)
+	require.NoError(t, m.Refresh("/mnt/new"))
+	s.Advance(59 * time.Minute)
+	require.Equal(t, 1, m.HasMounts("/dev/move"), "Expected Refresh to postpone the unmount")
+	s.Advance(time.Minute)
+	require.Equal(t, 0, m.HasMounts("/dev/move"), "Expected the moved mount to be unmounted after its TTL")
+	require.Equal(t, []string{"/mnt/new"}, mi.unmounted)
+	require.Empty(t, m.expiries)
+}
+
+func TestMoveMountErrors(t *testing.T) {
+	m, mi := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/dev/move", "/mnt/old", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/dev/other", "/mnt/busy", "ext4", 0, "", 0, nil))
+
+	require.Equal(t, ErrEnoent, m.MoveMount("/dev/move", "/mnt/missing", "/mnt/new", 0))
+	require.Equal(t, ErrEnoent, m.MoveMount("/dev/missing", "/mnt/old", "/mnt/new", 0))
+	require.Equal(t, ErrExist, m.MoveMount("/dev/move", "/mnt/old", "/mnt/busy", 0))
+	require.NotEqual(t, uintptr(syscall.MS_MOVE), mi.lastCall().flags)
+
+	mi.targetErrs["/mnt/new"] = syscall.EINVAL
+	err := m.MoveMount("/dev/move", "/mnt/old", "/mnt/new", 0)
+	require.ErrorIs(t, err, syscall.EINVAL)
+	require.Equal(t, []string{"/mnt/old"}, m.Mounts("/dev/move"))
+	require.False(t, m.fsops.(*testFsOps).IsImmutable("/mnt/new"))
+}
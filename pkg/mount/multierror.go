@@ -0,0 +1,86 @@
+package mount
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PathError is the failure of one path of an operation on several paths.
+type PathError struct {
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the error of the path.
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError is returned by the operations on several paths, such as
+// MountBatch and recursive unmounts, when more than one step can fail. It
+// holds the failures in the order they happened. errors.Is and errors.As
+// match any of them.
+type MultiError struct {
+	Errors []*PathError
+}
+
+func (e *MultiError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	msgs := make([]string, len(e.Errors))
+	for i, pe := range e.Errors {
+		msgs[i] = pe.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// ByPath returns the errors by path. A path that failed more than once has
+// its first error.
+func (e *MultiError) ByPath() map[string]error {
+	byPath := make(map[string]error, len(e.Errors))
+	for _, pe := range e.Errors {
+		if _, ok := byPath[pe.Path]; !ok {
+			byPath[pe.Path] = pe.Err
+		}
+	}
+	return byPath
+}
+
+// Is returns true if any of the errors matches target.
+func (e *MultiError) Is(target error) bool {
+	for _, pe := range e.Errors {
+		if errors.Is(pe, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors that matches target.
+func (e *MultiError) As(target interface{}) bool {
+	for _, pe := range e.Errors {
+		if errors.As(pe, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// add records the failure of path.
+func (e *MultiError) add(path string, err error) {
+	e.Errors = append(e.Errors, &PathError{Path: path, Err: err})
+}
+
+// errOrNil returns e, or nil if it holds no errors.
+func (e *MultiError) errOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
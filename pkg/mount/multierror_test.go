@@ -0,0 +1,51 @@
+package mount
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiError(t *testing.T) {
+	errBusy := errors.New("busy")
+	var merr MultiError
+	require.NoError(t, merr.errOrNil())
+	merr.add("/mnt/a", fmt.Errorf("failed: %w", ErrEnoent))
+	merr.add("/mnt/b", errBusy)
+	merr.add("/mnt/a", errBusy)
+
+	err := fmt.Errorf("wrapped: %w", merr.errOrNil())
+	require.True(t, errors.Is(err, ErrEnoent))
+	require.True(t, errors.Is(err, errBusy))
+	require.False(t, errors.Is(err, ErrExist))
+	var pe *PathError
+	require.True(t, errors.As(err, &pe))
+	require.Equal(t, "/mnt/a", pe.Path)
+	require.Equal(t, "wrapped: 3 errors: /mnt/a: failed: Mountpath is not mounted; /mnt/b: busy; /mnt/a: busy",
+		err.Error())
+
+	byPath := merr.ByPath()
+	require.Len(t, byPath, 2)
+	require.True(t, errors.Is(byPath["/mnt/a"], ErrEnoent), "Expected the first error of a path")
+	require.Equal(t, errBusy, byPath["/mnt/b"])
+}
+
+func TestMountBatchMultiError(t *testing.T) {
+	m, mi := newTestMounter(t)
+	errBusy := errors.New("busy")
+	mi.targetErrs["/mnt/batch1"] = ErrEnoent
+	mi.targetErrs["/mnt/batch3"] = errBusy
+
+	errs, err := m.MountBatch(newTestBatch(false))
+	require.Len(t, errs, 5)
+	var merr *MultiError
+	require.True(t, errors.As(err, &merr))
+	byPath := merr.ByPath()
+	require.Len(t, byPath, 2)
+	require.True(t, errors.Is(byPath["/mnt/batch1"], ErrEnoent))
+	require.True(t, errors.Is(byPath["/mnt/batch3"], errBusy))
+	require.True(t, errors.Is(err, ErrEnoent))
+	require.True(t, errors.Is(err, errBusy))
+}
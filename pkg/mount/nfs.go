@@ -1,13 +1,14 @@
-//go:build linux
-// +build linux
-
 package mount
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"os"
 	"regexp"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/docker/docker/pkg/mount"
 	"github.com/libopenstorage/openstorage/pkg/keylock"
@@ -16,11 +17,16 @@ import (
 const (
 	// NFSAllServers is a catch all for all servers.
 	NFSAllServers = "NFSAllServers"
+	// nfsHealthTimeout bounds the stat of CheckHealth.
+	nfsHealthTimeout = 5 * time.Second
 )
 
 // nfsMounter implements Manager and keeps track of active mounts for volume drivers.
 type nfsMounter struct {
 	servers []*regexp.Regexp
+	// stat and healthTimeout are used by CheckHealth.
+	stat          func(string) (os.FileInfo, error)
+	healthTimeout time.Duration
 	Mounter
 }
 
@@ -31,18 +37,20 @@ func NewNFSMounter(servers []*regexp.Regexp,
 	mountImpl MountImpl,
 	allowedDirs []string,
 	trashLocation string,
-) (Manager, error) {
+	opts ...MounterOption,
+) (NFSManager, error) {
 	m := &nfsMounter{
-		servers: servers,
+		servers:       servers,
+		stat:          os.Stat,
+		healthTimeout: nfsHealthTimeout,
 		Mounter: Mounter{
 			mountImpl:     mountImpl,
-			mounts:        make(DeviceMap),
-			paths:         make(PathMap),
 			allowedDirs:   allowedDirs,
 			kl:            keylock.New(),
 			trashLocation: trashLocation,
 		},
 	}
+	m.setOptions(opts)
 	err := m.Load([]*regexp.Regexp{}) // Input value is not used, can be anything
 	if err != nil {
 		return nil, err
@@ -54,7 +62,7 @@ func NewNFSMounter(servers []*regexp.Regexp,
 func (m *nfsMounter) Reload(source string) error {
 	newNFSm, err := NewNFSMounter([]*regexp.Regexp{regexp.MustCompile(NFSAllServers)},
 		m.mountImpl,
-		m.Mounter.allowedDirs,
+		m.Mounter.AllowedDirs(),
 		m.trashLocation,
 	)
 	if err != nil {
@@ -67,16 +75,22 @@ func (m *nfsMounter) Reload(source string) error {
 			newNFSmounter)
 	}
 
-	return m.reload(source, newNFSmounter.mounts[source])
+	info, _ := newNFSmounter.lookup(source)
+	return m.reload(source, info)
 }
 
-//serverExists utility function to test if a server is part of driver config
+// serverExists utility function to test if a server is part of driver config.
+// Servers are compared literally, except for the patterns anchored at both
+// ends, like the ones NewAuto builds, which are matched as regexps.
 func (m *nfsMounter) serverExists(server string) bool {
 	for _, v := range m.servers {
 		vStr := v.String()
 		if vStr == server || vStr == NFSAllServers {
 			return true
 		}
+		if strings.HasPrefix(vStr, "^") && strings.HasSuffix(vStr, "$") && v.MatchString(server) {
+			return true
+		}
 	}
 	return false
 }
@@ -106,6 +120,8 @@ func (m *nfsMounter) Load(source []*regexp.Regexp) error {
 		return err
 	}
 	re := regexp.MustCompile(`,addr=(.*)`)
+	m.Lock()
+	defer m.Unlock()
 MountLoop:
 	for _, v := range info {
 		host := "localhost"
@@ -124,16 +140,12 @@ MountLoop:
 			host = matches[1]
 		}
 		m.normalizeSource(v, host)
-		mount, ok := m.mounts[v.Source]
-		if !ok {
-			mount = &Info{
-				Device:     v.Source,
-				Fs:         v.Fstype,
-				Minor:      v.Minor,
-				Mountpoint: make([]*PathInfo, 0),
-			}
-			m.mounts[v.Source] = mount
-		}
+		mount, _ := m.loadOrStoreLocked(v.Source, &Info{
+			Device:     v.Source,
+			Fs:         v.Fstype,
+			Minor:      v.Minor,
+			Mountpoint: make([]*PathInfo, 0),
+		})
 		// Allow Load to be called multiple times.
 		for _, p := range mount.Mountpoint {
 			if p.Path == v.Mountpoint {
@@ -147,5 +159,27 @@ MountLoop:
 			pi,
 		)
 	}
+	m.reindexLocked()
 	return nil
 }
+
+// CheckHealth stats target and returns ErrStaleMount if the stat fails with
+// ESTALE or does not complete within the health check timeout. A stat that
+// hangs on an unresponsive server is left running in the background.
+func (m *nfsMounter) CheckHealth(target string) error {
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := m.stat(target)
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		if errors.Is(err, syscall.ESTALE) {
+			return fmt.Errorf("NFS mount %s: %v: %w", target, err, ErrStaleMount)
+		}
+		return err
+	case <-time.After(m.healthTimeout):
+		return fmt.Errorf("NFS mount %s did not respond within %v: %w",
+			target, m.healthTimeout, ErrStaleMount)
+	}
+}
@@ -0,0 +1,121 @@
+package mount
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const nfsType = "nfs"
+
+// NFSOptions are the NFS client options of NFSMount. Zero values are left
+// out of the mount data so that the kernel defaults apply.
+type NFSOptions struct {
+	// Version is the protocol version, one of 3, 4, 4.1 or 4.2.
+	Version string
+	// Proto is the transport protocol, e.g. tcp or udp.
+	Proto string
+	// Soft makes requests fail after Retrans retries instead of retrying
+	// forever.
+	Soft bool
+	// Timeo is the time in tenths of a second before a request is retried.
+	Timeo int
+	// Retrans is the number of retries before a soft mount fails.
+	Retrans int
+	// Rsize is the maximum number of bytes of a read request.
+	Rsize int
+	// Wsize is the maximum number of bytes of a write request.
+	Wsize int
+}
+
+var (
+	nfsVersions = map[string]bool{"3": true, "4": true, "4.1": true, "4.2": true}
+	nfsProtos   = map[string]bool{"tcp": true, "tcp6": true, "udp": true, "udp6": true, "rdma": true}
+	// lookupHost resolves NFS server names, replaced by tests.
+	lookupHost = net.LookupHost
+)
+
+// NFSManager is a Manager that mounts NFS exports.
+type NFSManager interface {
+	Manager
+	// NFSMount mounts export of server at target with opts.
+	NFSMount(server, export, target string, opts NFSOptions, timeout int) error
+	// CheckHealth returns ErrStaleMount if the NFS mount at target is stale.
+	CheckHealth(target string) error
+}
+
+// NFSMount mounts export of server at target. The mount is tracked under
+// the server:/export source reported by the mount table.
+func (m *nfsMounter) NFSMount(server, export, target string, opts NFSOptions, timeout int) error {
+	source, err := nfsSource(server, export)
+	if err != nil {
+		return err
+	}
+	data, err := opts.data()
+	if err != nil {
+		return err
+	}
+	// The kernel client needs the server address as it does not resolve
+	// names itself.
+	addr := server
+	if net.ParseIP(server) == nil {
+		addrs, err := lookupHost(server)
+		if err != nil {
+			return fmt.Errorf("failed to resolve NFS server %s: %w", server, err)
+		}
+		addr = addrs[0]
+	}
+	data += ",addr=" + addr
+	return m.mount(0, source, source, target, nfsType, 0, data, timeout)
+}
+
+// nfsSource returns the server:/export source of an NFS mount.
+func nfsSource(server, export string) (string, error) {
+	if server == "" {
+		return "", fmt.Errorf("NFS server is empty: %w", ErrEinval)
+	}
+	if !strings.HasPrefix(export, "/") {
+		return "", fmt.Errorf("NFS export %q is not an absolute path: %w", export, ErrEinval)
+	}
+	if strings.Contains(server, ":") && net.ParseIP(server) != nil {
+		server = "[" + server + "]"
+	}
+	return server + ":" + export, nil
+}
+
+// data returns the validated NFS mount data for o.
+func (o NFSOptions) data() (string, error) {
+	if !nfsVersions[o.Version] {
+		return "", fmt.Errorf("unsupported NFS version %q: %w", o.Version, ErrEinval)
+	}
+	opts := []string{"vers=" + o.Version}
+	if o.Proto != "" {
+		if !nfsProtos[o.Proto] {
+			return "", fmt.Errorf("unsupported NFS proto %q: %w", o.Proto, ErrEinval)
+		}
+		opts = append(opts, "proto="+o.Proto)
+	}
+	if o.Soft {
+		opts = append(opts, "soft")
+	} else {
+		opts = append(opts, "hard")
+	}
+	for _, opt := range []struct {
+		name  string
+		value int
+	}{
+		{"timeo", o.Timeo},
+		{"retrans", o.Retrans},
+		{"rsize", o.Rsize},
+		{"wsize", o.Wsize},
+	} {
+		if opt.value < 0 {
+			return "", fmt.Errorf("NFS option %s is negative: %w", opt.name, ErrEinval)
+		}
+		if opt.value > 0 {
+			opts = append(opts, opt.name+"="+strconv.Itoa(opt.value))
+		}
+	}
+	return strings.Join(opts, ","), nil
+}
@@ -0,0 +1,95 @@
+package mount
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNFSOptionsData(t *testing.T) {
+	tests := []struct {
+		opts     NFSOptions
+		expected string
+	}{
+		{opts: NFSOptions{Version: "3"}, expected: "vers=3,hard"},
+		{opts: NFSOptions{Version: "4.1", Proto: "tcp"}, expected: "vers=4.1,proto=tcp,hard"},
+		{
+			opts: NFSOptions{
+				Version: "4.2",
+				Proto:   "tcp6",
+				Soft:    true,
+				Timeo:   600,
+				Retrans: 2,
+				Rsize:   1 << 20,
+				Wsize:   1 << 20,
+			},
+			expected: "vers=4.2,proto=tcp6,soft,timeo=600,retrans=2,rsize=1048576,wsize=1048576",
+		},
+	}
+	for _, test := range tests {
+		data, err := test.opts.data()
+		require.NoError(t, err)
+		require.Equal(t, test.expected, data)
+	}
+
+	for _, opts := range []NFSOptions{
+		{},
+		{Version: "2"},
+		{Version: "4.0.1"},
+		{Version: "4", Proto: "sctp"},
+		{Version: "4", Timeo: -1},
+		{Version: "4", Wsize: -4096},
+	} {
+		_, err := opts.data()
+		require.True(t, errors.Is(err, ErrEinval), "Expected %+v to be rejected, got %v", opts, err)
+	}
+}
+
+func TestNFSSource(t *testing.T) {
+	for _, test := range []struct {
+		server, export, expected string
+	}{
+		{"10.0.0.1", "/export", "10.0.0.1:/export"},
+		{"nfs.example.com", "/a/b", "nfs.example.com:/a/b"},
+		{"fd00::1", "/export", "[fd00::1]:/export"},
+	} {
+		source, err := nfsSource(test.server, test.export)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, source)
+	}
+
+	_, err := nfsSource("", "/export")
+	require.True(t, errors.Is(err, ErrEinval))
+	_, err = nfsSource("10.0.0.1", "export")
+	require.True(t, errors.Is(err, ErrEinval))
+}
+
+func TestNFSMount(t *testing.T) {
+	mi := newTestMountImpl()
+	m, err := NewNFSMounter(nil, mi, nil, "", withFsOps(newTestFsOps()))
+	require.NoError(t, err)
+	target := filepath.Join(t.TempDir(), "nfs")
+	require.NoError(t, os.Mkdir(target, 0755))
+
+	orig := lookupHost
+	lookupHost = func(host string) ([]string, error) {
+		require.Equal(t, "nfs.example.com", host)
+		return []string{"10.0.0.2"}, nil
+	}
+	defer func() { lookupHost = orig }()
+
+	opts := NFSOptions{Version: "4.1", Proto: "tcp", Timeo: 600}
+	require.NoError(t, m.NFSMount("nfs.example.com", "/export", target, opts, 0))
+	call := mi.lastCall()
+	require.Equal(t, "nfs.example.com:/export", call.source)
+	require.Equal(t, "nfs", call.fstype)
+	require.Equal(t, "vers=4.1,proto=tcp,hard,timeo=600,addr=10.0.0.2", call.data)
+	require.Equal(t, []string{target}, m.Mounts("nfs.example.com:/export"))
+	require.NoError(t, m.Unmount("nfs.example.com:/export", target, 0, 0, nil))
+
+	require.True(t, errors.Is(m.NFSMount("10.0.0.1", "/export", target, NFSOptions{Version: "5"}, 0), ErrEinval))
+	require.Len(t, mi.calls, 1, "Expected no mount with invalid options")
+}
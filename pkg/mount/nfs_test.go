@@ -0,0 +1,54 @@
+package mount
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNFSMounter(t *testing.T, stat func(string) (os.FileInfo, error)) *nfsMounter {
+	nm, err := NewNFSMounter(nil, newTestMountImpl(), nil, "", withFsOps(newTestFsOps()))
+	require.NoError(t, err)
+	m := nm.(*nfsMounter)
+	m.stat = stat
+	m.healthTimeout = 200 * time.Millisecond
+	return m
+}
+
+func TestNFSCheckHealth(t *testing.T) {
+	m := newTestNFSMounter(t, func(path string) (os.FileInfo, error) {
+		return os.Stat(os.TempDir())
+	})
+	require.NoError(t, m.CheckHealth("/mnt/nfs"))
+
+	m.stat = func(path string) (os.FileInfo, error) {
+		return nil, &os.PathError{Op: "stat", Path: path, Err: syscall.ESTALE}
+	}
+	err := m.CheckHealth("/mnt/nfs")
+	require.True(t, errors.Is(err, ErrStaleMount), "Unexpected error %v", err)
+
+	m.stat = func(path string) (os.FileInfo, error) {
+		return nil, &os.PathError{Op: "stat", Path: path, Err: syscall.ENOENT}
+	}
+	err = m.CheckHealth("/mnt/nfs")
+	require.True(t, os.IsNotExist(err), "Unexpected error %v", err)
+	require.False(t, errors.Is(err, ErrStaleMount))
+}
+
+func TestNFSCheckHealthHang(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	m := newTestNFSMounter(t, func(path string) (os.FileInfo, error) {
+		<-hang
+		return nil, nil
+	})
+
+	start := time.Now()
+	err := m.CheckHealth("/mnt/nfs")
+	require.True(t, errors.Is(err, ErrStaleMount), "Unexpected error %v", err)
+	require.True(t, time.Since(start) < 5*time.Second, "CheckHealth was not bounded by its timeout")
+}
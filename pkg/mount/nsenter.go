@@ -0,0 +1,76 @@
+package mount
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	osdexec "github.com/libopenstorage/openstorage/pkg/exec"
+)
+
+// NsenterMounter is a MountImpl that mounts and unmounts in another mount
+// namespace, such as the host's from a container, by running mount and
+// umount under nsenter. A process cannot setns into a mount namespace once
+// it runs several threads, as Go programs do. The mount table is still read
+// from the namespace of the process, which only sees the mounts if they
+// propagate to it.
+type NsenterMounter struct {
+	// nsPath is the mount namespace file, e.g. /proc/1/ns/mnt.
+	nsPath string
+	// run runs a helper binary, replaced by tests.
+	run func(name string, args ...string) error
+}
+
+// NewNsenterMounter returns an NsenterMounter for the mount namespace at
+// nsPath, e.g. /proc/1/ns/mnt for the namespace of the host's init.
+func NewNsenterMounter(nsPath string) *NsenterMounter {
+	return &NsenterMounter{
+		nsPath: nsPath,
+		run: func(name string, args ...string) error {
+			return runMountHelper(exec.Command(name, args...))
+		},
+	}
+}
+
+// WithMountNamespace makes the Mounter mount and unmount in the mount
+// namespace at nsPath with an NsenterMounter, in place of its MountImpl.
+func WithMountNamespace(nsPath string) MounterOption {
+	return func(m *Mounter) {
+		m.mountImpl = NewNsenterMounter(nsPath)
+	}
+}
+
+// Mount runs mount in the namespace. flags are passed as mount options.
+func (n *NsenterMounter) Mount(source, target, fstype string, flags uintptr, data string, timeout int) error {
+	args := []string{"mount"}
+	if msMove != 0 && flags&msMove != 0 {
+		args = append(args, "--move")
+		flags &^= msMove
+	}
+	if fstype != "" {
+		args = append(args, "-t", fstype)
+	}
+	opts := FlagsToString(flags)
+	if strings.Contains(opts, "0x") {
+		return fmt.Errorf("%w: mount flags %s in another namespace", ErrUnsupported, opts)
+	}
+	if data != "" {
+		opts += "," + data
+	}
+	args = append(args, "-o", opts, source, target)
+	return n.nsenter(args...)
+}
+
+// Unmount runs umount in the namespace, with -l for a lazy unmount.
+func (n *NsenterMounter) Unmount(target string, flags int, timeout int) error {
+	args := []string{"umount"}
+	if mntDetach != 0 && flags&mntDetach != 0 {
+		args = append(args, "-l")
+	}
+	return n.nsenter(append(args, target)...)
+}
+
+// nsenter runs the command args in the mount namespace.
+func (n *NsenterMounter) nsenter(args ...string) error {
+	return n.run(osdexec.Which("nsenter"), append([]string{"--mount=" + n.nsPath, "--"}, args...)...)
+}
@@ -0,0 +1,54 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordNsenter makes n record the commands it runs.
+func recordNsenter(n *NsenterMounter) *[]string {
+	var cmds []string
+	n.run = func(name string, args ...string) error {
+		cmds = append(cmds, strings.Join(args, " "))
+		return nil
+	}
+	return &cmds
+}
+
+func TestNsenterMounter(t *testing.T) {
+	n := NewNsenterMounter("/proc/1/ns/mnt")
+	cmds := recordNsenter(n)
+
+	require.NoError(t, n.Mount("/dev/sdb", "/mnt/host", "ext4", syscall.MS_RDONLY|syscall.MS_NOEXEC, "discard", 0))
+	require.NoError(t, n.Mount("/src", "/mnt/bind", "", syscall.MS_BIND, "", 0))
+	require.NoError(t, n.Mount("/mnt/bind", "/mnt/moved", "", syscall.MS_MOVE, "", 0))
+	require.NoError(t, n.Unmount("/mnt/host", 0, 0))
+	require.NoError(t, n.Unmount("/mnt/moved", syscall.MNT_DETACH, 0))
+	require.Equal(t, []string{
+		"--mount=/proc/1/ns/mnt -- mount -t ext4 -o ro,noexec,discard /dev/sdb /mnt/host",
+		"--mount=/proc/1/ns/mnt -- mount -o rw,bind /src /mnt/bind",
+		"--mount=/proc/1/ns/mnt -- mount --move -o rw /mnt/bind /mnt/moved",
+		"--mount=/proc/1/ns/mnt -- umount /mnt/host",
+		"--mount=/proc/1/ns/mnt -- umount -l /mnt/moved",
+	}, *cmds)
+}
+
+func TestWithMountNamespace(t *testing.T) {
+	m, _ := newTestMounter(t, WithMountNamespace("/proc/1/ns/mnt"))
+	n, ok := m.mountImpl.(*NsenterMounter)
+	require.True(t, ok, "Expected the MountImpl to be replaced")
+	cmds := recordNsenter(n)
+
+	require.NoError(t, m.Mount(0, "/dev/sdb", "/mnt/host", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Unmount("/dev/sdb", "/mnt/host", 0, 0, nil))
+	require.Equal(t, []string{
+		"--mount=/proc/1/ns/mnt -- mount -t ext4 -o rw /dev/sdb /mnt/host",
+		"--mount=/proc/1/ns/mnt -- umount /mnt/host",
+	}, *cmds)
+}
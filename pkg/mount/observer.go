@@ -0,0 +1,34 @@
+package mount
+
+// Observer is notified of the outcome of every mount and unmount made through
+// a Mounter. Observers are called after the operation, without any Mounter
+// lock held, so they may call back into the Mounter.
+type Observer interface {
+	// OnMount is called after device was mounted at path.
+	OnMount(device, path, fs string)
+	// OnUnmount is called after device was unmounted from path.
+	OnUnmount(device, path, fs string)
+	// OnError is called when op, one of OpMount or OpUnmount, failed.
+	OnError(op, device, path, fs string, err error)
+}
+
+// WithObservers adds observers to be notified of mounts and unmounts.
+func WithObservers(observers ...Observer) MounterOption {
+	return func(m *Mounter) {
+		m.observers = append(m.observers, observers...)
+	}
+}
+
+// notify calls the observers with the outcome of op.
+func (m *Mounter) notify(op, device, path, fs string, err error) {
+	for _, o := range m.observers {
+		switch {
+		case err != nil:
+			o.OnError(op, device, path, fs, err)
+		case op == OpMount:
+			o.OnMount(device, path, fs)
+		default:
+			o.OnUnmount(device, path, fs)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package mount
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testObserver records events and checks the Mounter lock is not held.
+type testObserver struct {
+	sync.Mutex
+	m      *Mounter
+	events []string
+}
+
+func (o *testObserver) record(event string) {
+	// Deadlocks if called with the Mounter lock held.
+	o.m.HasTarget("/")
+	o.Lock()
+	defer o.Unlock()
+	o.events = append(o.events, event)
+}
+
+func (o *testObserver) OnMount(device, path, fs string) {
+	o.record(fmt.Sprintf("mount %s %s %s", device, path, fs))
+}
+
+func (o *testObserver) OnUnmount(device, path, fs string) {
+	o.record(fmt.Sprintf("unmount %s %s %s", device, path, fs))
+}
+
+func (o *testObserver) OnError(op, device, path, fs string, err error) {
+	o.record(fmt.Sprintf("%s error %s %s %s: %v", op, device, path, fs, err))
+}
+
+func TestObserver(t *testing.T) {
+	o := &testObserver{}
+	m, mi := newTestMounter(t, WithObservers(o))
+	o.m = &m.Mounter
+	path := t.TempDir()
+
+	require.NoError(t, m.Mount(0, "/dev/sda", path+"/", "ext4", 0, "", 0, nil))
+	require.Equal(t, ErrEnoent, m.Unmount("/dev/sdb", path, 0, 0, nil))
+	mi.unmountErr = errors.New("busy")
+	require.Error(t, m.Unmount("/dev/sda", path, 0, 0, nil))
+	mi.unmountErr = nil
+	require.NoError(t, m.Unmount("/dev/sda", path, 0, 0, nil))
+	mi.mountErr = errors.New("bad superblock")
+	require.Error(t, m.Mount(0, "/dev/sda", path, "ext4", 0, "", 0, nil))
+
+	require.Equal(t, []string{
+		fmt.Sprintf("mount /dev/sda %s ext4", path),
+		fmt.Sprintf("unmount error /dev/sdb %s : %v", path, ErrEnoent),
+		fmt.Sprintf("unmount error /dev/sda %s ext4: unmounting /dev/sda from %s: busy", path, path),
+		fmt.Sprintf("unmount /dev/sda %s ext4", path),
+		fmt.Sprintf("mount error /dev/sda %s ext4: mounting /dev/sda at %s: bad superblock", path, path),
+	}, o.events)
+}
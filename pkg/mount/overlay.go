@@ -0,0 +1,76 @@
+package mount
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// OverlayDevice is the device under which overlay mounts are tracked.
+	OverlayDevice = "overlay"
+	overlayType   = "overlay"
+)
+
+// OverlayMount mounts an overlay filesystem at target. lower lists the lower
+// directories from the top most layer down. If upper and work are empty the
+// overlay is read-only, otherwise both must be set. All directories must
+// exist. The mount is tracked under OverlayDevice.
+func (m *Mounter) OverlayMount(
+	target string,
+	lower []string,
+	upper, work string,
+	timeout int,
+) error {
+	data, err := overlayData(lower, upper, work)
+	if err != nil {
+		return err
+	}
+	dirs := append([]string{}, lower...)
+	if upper != "" {
+		dirs = append(dirs, upper, work)
+	}
+	for _, dir := range dirs {
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("overlay directory %v: %w", dir, err)
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("overlay directory %v is not a directory: %w", dir, ErrEinval)
+		}
+	}
+	if err := m.mount(0, overlayType, OverlayDevice, target, overlayType, 0, data, timeout); err != nil {
+		return err
+	}
+	m.addDeps(target, dirs)
+	return nil
+}
+
+// overlayData returns the overlay mount data for the given directories.
+func overlayData(lower []string, upper, work string) (string, error) {
+	if len(lower) == 0 {
+		return "", fmt.Errorf("overlay requires at least one lower directory: %w", ErrEinval)
+	}
+	if (upper == "") != (work == "") {
+		return "", fmt.Errorf("overlay requires both upper and work directories: %w", ErrEinval)
+	}
+	escaped := make([]string, len(lower))
+	for i, dir := range lower {
+		if dir == "" {
+			return "", fmt.Errorf("overlay lower directory %d is empty: %w", i, ErrEinval)
+		}
+		escaped[i] = escapeOverlayPath(dir)
+	}
+	data := "lowerdir=" + strings.Join(escaped, ":")
+	if upper != "" {
+		data += ",upperdir=" + escapeOverlayPath(upper) +
+			",workdir=" + escapeOverlayPath(work)
+	}
+	return data, nil
+}
+
+// escapeOverlayPath escapes the characters overlayfs treats as separators in
+// its mount options.
+func escapeOverlayPath(path string) string {
+	return strings.NewReplacer(`\`, `\\`, `:`, `\:`, `,`, `\,`).Replace(path)
+}
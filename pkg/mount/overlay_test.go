@@ -0,0 +1,78 @@
+package mount
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlayData(t *testing.T) {
+	tests := []struct {
+		lower    []string
+		upper    string
+		work     string
+		expected string
+	}{
+		{
+			lower:    []string{"/l1"},
+			upper:    "/u",
+			work:     "/w",
+			expected: "lowerdir=/l1,upperdir=/u,workdir=/w",
+		},
+		{
+			lower:    []string{"/l1", "/l2", "/l3"},
+			upper:    "/u",
+			work:     "/w",
+			expected: "lowerdir=/l1:/l2:/l3,upperdir=/u,workdir=/w",
+		},
+		{
+			lower:    []string{"/l1", "/l2"},
+			expected: "lowerdir=/l1:/l2",
+		},
+		{
+			lower:    []string{"/a:b", `/c\d`, "/e,f"},
+			upper:    "/u:1",
+			work:     "/w,1",
+			expected: `lowerdir=/a\:b:/c\\d:/e\,f,upperdir=/u\:1,workdir=/w\,1`,
+		},
+	}
+	for _, test := range tests {
+		data, err := overlayData(test.lower, test.upper, test.work)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, data)
+	}
+
+	_, err := overlayData(nil, "/u", "/w")
+	require.True(t, errors.Is(err, ErrEinval), "Expected ErrEinval without lowerdir")
+	_, err = overlayData([]string{"/l1"}, "/u", "")
+	require.True(t, errors.Is(err, ErrEinval), "Expected ErrEinval without workdir")
+	_, err = overlayData([]string{"/l1", ""}, "", "")
+	require.True(t, errors.Is(err, ErrEinval), "Expected ErrEinval for an empty lowerdir")
+}
+
+func TestOverlayMount(t *testing.T) {
+	m, mi := newTestMounter(t)
+	dir := t.TempDir()
+	dirs := map[string]string{}
+	for _, name := range []string{"lower1", "lower2", "upper", "work", "target"} {
+		dirs[name] = filepath.Join(dir, name)
+		require.NoError(t, os.Mkdir(dirs[name], 0755))
+	}
+
+	err := m.OverlayMount(dirs["target"], []string{dirs["lower1"], filepath.Join(dir, "missing")},
+		dirs["upper"], dirs["work"], 0)
+	require.Error(t, err, "Expected a missing lowerdir to fail")
+	require.Empty(t, mi.calls, "Mount must not be issued when validation fails")
+
+	require.NoError(t, m.OverlayMount(dirs["target"], []string{dirs["lower1"], dirs["lower2"]},
+		dirs["upper"], dirs["work"], 0))
+	call := mi.lastCall()
+	require.Equal(t, "overlay", call.fstype)
+	require.Equal(t, "lowerdir="+dirs["lower1"]+":"+dirs["lower2"]+
+		",upperdir="+dirs["upper"]+",workdir="+dirs["work"], call.data)
+	require.Equal(t, "overlay", m.testInfo(OverlayDevice).Fs)
+	require.Equal(t, []string{dirs["target"]}, m.Mounts(OverlayDevice))
+}
@@ -0,0 +1,194 @@
+package mount
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Ownership is applied to the root of a mount once it is mounted and before
+// it is recorded in the mount table. Filesystems without file ownership, such
+// as vfat, get it as the uid, gid and umask mount options instead.
+type Ownership struct {
+	// UID and GID own the mount root if set.
+	UID *int
+	GID *int
+	// Mode is the permission of the mount root if not zero.
+	Mode os.FileMode
+}
+
+// postMountHook is run on the path of a successful mount before it is
+// recorded. The mount is rolled back if the hook fails.
+type postMountHook func(path string) error
+
+// mountCall is the behavior of a single call to mount set by mountOptions.
+type mountCall struct {
+	hooks         []postMountHook
+	failIfMounted bool
+	// redact returns the mount data recorded in the table.
+	redact func(data string) string
+	// remountReadOnly remounts a read-only mount that turns out read-write.
+	remountReadOnly bool
+	// backend mounts instead of the MountImpl if set.
+	backend func(source, target, fstype string, flags uintptr, data string, timeout int) error
+	// added is set if the call recorded a new mountpoint.
+	added *bool
+	// sourceID is recorded as the SourceID of the device mounted.
+	sourceID string
+	// source returns the source to mount with the device and Info locks
+	// held, for the sources attached on the first mount of the device.
+	source func(info *Info) (*lockedSource, error)
+}
+
+// lockedSource is a source of a mount set up with the device lock held.
+type lockedSource struct {
+	devPath string
+	// record records the source in the Info of the device, locked, along
+	// with a new mountpoint.
+	record func(info *Info)
+	// release undoes the setup if the mount fails or adds no mountpoint.
+	release func()
+}
+
+// mountOption changes the behavior of a single call to mount.
+type mountOption func(*mountCall)
+
+// withPostMountHook runs hook after the mount succeeds.
+func withPostMountHook(hook postMountHook) mountOption {
+	return func(c *mountCall) {
+		c.hooks = append(c.hooks, hook)
+	}
+}
+
+// withRedactedData records the mount data returned by redact in the table
+// instead of the data passed to the kernel, so that secrets are not kept.
+func withRedactedData(redact func(data string) string) mountOption {
+	return func(c *mountCall) {
+		c.redact = redact
+	}
+}
+
+// withMountBackend mounts with backend instead of the MountImpl.
+func withMountBackend(
+	backend func(source, target, fstype string, flags uintptr, data string, timeout int) error,
+) mountOption {
+	return func(c *mountCall) {
+		c.backend = backend
+	}
+}
+
+// withReadOnlyRemount remounts a read-only mount that the mount table shows
+// read-write with MS_REMOUNT|MS_RDONLY.
+func withReadOnlyRemount() mountOption {
+	return func(c *mountCall) {
+		c.remountReadOnly = true
+	}
+}
+
+// withFailIfMounted makes mount return ErrAlreadyMounted instead of nil if the
+// device is already mounted at the path.
+func withFailIfMounted() mountOption {
+	return func(c *mountCall) {
+		c.failIfMounted = true
+	}
+}
+
+// withAdded sets *added if mount records a new mountpoint, rather than finding
+// the device already mounted at the path or remounting it.
+func withAdded(added *bool) mountOption {
+	return func(c *mountCall) {
+		c.added = added
+	}
+}
+
+// withSourceID records sourceID as the SourceID of the device once mounted.
+func withSourceID(sourceID string) mountOption {
+	return func(c *mountCall) {
+		c.sourceID = sourceID
+	}
+}
+
+// withLockedSource mounts the source returned by source, called with the
+// device and Info locks held, instead of devPath.
+func withLockedSource(source func(info *Info) (*lockedSource, error)) mountOption {
+	return func(c *mountCall) {
+		c.source = source
+	}
+}
+
+// dataOwnershipFs are the filesystems without file ownership, which take the
+// owner and mode of all their files as mount options and ignore chown.
+var dataOwnershipFs = map[string]bool{
+	"vfat":  true,
+	"msdos": true,
+	"exfat": true,
+	"ntfs":  true,
+	"ntfs3": true,
+}
+
+// ownershipData returns data with the uid, gid and umask mount options
+// applying o if filesystem fs takes ownership as mount options, and false
+// otherwise. Options already in data are kept.
+func ownershipData(fs, data string, o *Ownership) (string, bool) {
+	if !dataOwnershipFs[fs] {
+		return data, false
+	}
+	has := func(name string) bool {
+		for _, opt := range strings.Split(data, ",") {
+			if strings.HasPrefix(opt, name+"=") {
+				return true
+			}
+		}
+		return false
+	}
+	opts := []string{}
+	if data != "" {
+		opts = append(opts, data)
+	}
+	if o.UID != nil && !has("uid") {
+		opts = append(opts, fmt.Sprintf("uid=%d", *o.UID))
+	}
+	if o.GID != nil && !has("gid") {
+		opts = append(opts, fmt.Sprintf("gid=%d", *o.GID))
+	}
+	if o.Mode != 0 && !has("umask") {
+		opts = append(opts, fmt.Sprintf("umask=%03o", 0777&^o.Mode.Perm()))
+	}
+	return strings.Join(opts, ","), true
+}
+
+// osOwnerOps implements the ownership operations of fsOps with the os
+// package.
+type osOwnerOps struct{}
+
+func (osOwnerOps) Chown(path string, uid, gid int) error {
+	return os.Lchown(path, uid, gid)
+}
+
+func (osOwnerOps) Chmod(path string, mode os.FileMode) error {
+	return os.Chmod(path, mode)
+}
+
+// ownershipHook returns a hook applying o to the mount root.
+func (m *Mounter) ownershipHook(o *Ownership) postMountHook {
+	return func(path string) error {
+		if o.UID != nil || o.GID != nil {
+			uid, gid := -1, -1
+			if o.UID != nil {
+				uid = *o.UID
+			}
+			if o.GID != nil {
+				gid = *o.GID
+			}
+			if err := m.fsops.Chown(path, uid, gid); err != nil {
+				return fmt.Errorf("failed to change owner of %s: %w", path, err)
+			}
+		}
+		if o.Mode != 0 {
+			if err := m.fsops.Chmod(path, o.Mode); err != nil {
+				return fmt.Errorf("failed to change mode of %s: %w", path, err)
+			}
+		}
+		return nil
+	}
+}
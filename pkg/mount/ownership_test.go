@@ -0,0 +1,148 @@
+package mount
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// orderedMountImpl records mounts and unmounts along with the fsOps
+// operations, to check their order.
+type orderedMountImpl struct {
+	*testMountImpl
+	fsops *testFsOps
+}
+
+func (o *orderedMountImpl) Mount(source, target, fstype string, flags uintptr, data string, timeout int) error {
+	o.fsops.Lock()
+	o.fsops.record("mount", target)
+	o.fsops.Unlock()
+	return o.testMountImpl.Mount(source, target, fstype, flags, data, timeout)
+}
+
+func (o *orderedMountImpl) Unmount(target string, flags int, timeout int) error {
+	o.fsops.Lock()
+	o.fsops.record("umount", target)
+	o.fsops.Unlock()
+	return o.testMountImpl.Unmount(target, flags, timeout)
+}
+
+func newOrderedMounter(t *testing.T) (*Mounter, *testMountImpl, *testFsOps) {
+	fsops := newTestFsOps()
+	mi := newTestMountImpl()
+	m, err := NewDeviceMounter(nil, &orderedMountImpl{testMountImpl: mi, fsops: fsops}, nil, "",
+		withFsOps(fsops), withDeviceCheck(nil))
+	require.NoError(t, err)
+	return &m.Mounter, mi, fsops
+}
+
+func TestMountOwnership(t *testing.T) {
+	m, _, fsops := newOrderedMounter(t)
+	uid, gid := 1000, 2000
+
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/owned",
+		Path:   "/mnt/owned",
+		Fs:     "ext4",
+		Owner:  &Ownership{UID: &uid, GID: &gid, Mode: 0770},
+	}))
+	require.Equal(t, []string{
+		"+i /mnt/owned",
+		"mount /mnt/owned",
+		"chown 1000:2000 /mnt/owned",
+		"chmod 770 /mnt/owned",
+	}, fsops.ops, "Expected the mountpoint to be immutable before the mount and ownership to be applied to the mount root")
+	require.Equal(t, 1, m.HasMounts("/dev/owned"))
+}
+
+func TestMountOwnershipPartial(t *testing.T) {
+	m, _, fsops := newOrderedMounter(t)
+	gid := 2000
+
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/owned",
+		Path:   "/mnt/owned",
+		Fs:     "ext4",
+		Owner:  &Ownership{GID: &gid},
+	}))
+	require.Equal(t, []string{
+		"+i /mnt/owned",
+		"mount /mnt/owned",
+		"chown -1:2000 /mnt/owned",
+	}, fsops.ops)
+}
+
+func TestMountOwnershipMountFailure(t *testing.T) {
+	m, mi, fsops := newOrderedMounter(t)
+	uid := 1000
+	mi.mountErr = errors.New("mount failed")
+
+	require.Error(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/owned",
+		Path:   "/mnt/owned",
+		Fs:     "ext4",
+		Owner:  &Ownership{UID: &uid},
+	}))
+	require.Equal(t, []string{
+		"+i /mnt/owned",
+		"mount /mnt/owned",
+		"-i /mnt/owned",
+	}, fsops.ops, "Ownership must not be applied if the mount fails")
+}
+
+// failingChownFsOps fails Chown.
+type failingChownFsOps struct {
+	*testFsOps
+}
+
+func (failingChownFsOps) Chown(path string, uid, gid int) error {
+	return errors.New("chown failed")
+}
+
+func TestMountOwnershipFailure(t *testing.T) {
+	fsops := newTestFsOps()
+	mi := newTestMountImpl()
+	m, err := NewDeviceMounter(nil, mi, nil, "", withFsOps(failingChownFsOps{fsops}), withDeviceCheck(nil))
+	require.NoError(t, err)
+	uid := 1000
+
+	require.Error(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/owned",
+		Path:   "/mnt/owned",
+		Fs:     "ext4",
+		Owner:  &Ownership{UID: &uid},
+	}))
+	require.Equal(t, 0, m.HasMounts("/dev/owned"))
+	require.Equal(t, []string{"/mnt/owned"}, mi.unmounted, "Expected the mount to be rolled back")
+	require.False(t, fsops.IsImmutable("/mnt/owned"))
+}
+
+func TestMountOwnershipData(t *testing.T) {
+	m, mi, fsops := newOrderedMounter(t)
+	uid, gid := 1000, 2000
+
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/owned",
+		Path:   "/mnt/owned",
+		Fs:     "vfat",
+		Data:   "shortname=mixed",
+		Owner:  &Ownership{UID: &uid, GID: &gid, Mode: 0750},
+	}))
+	require.Equal(t, "shortname=mixed,uid=1000,gid=2000,umask=027", mi.lastCall().data)
+	require.Equal(t, []string{
+		"+i /mnt/owned",
+		"mount /mnt/owned",
+	}, fsops.ops, "Expected vfat ownership to be passed as mount data rather than chown")
+}
+
+func TestOwnershipData(t *testing.T) {
+	gid := 2000
+	data, ok := ownershipData("ext4", "", &Ownership{GID: &gid})
+	require.False(t, ok)
+	require.Empty(t, data)
+
+	data, ok = ownershipData("exfat", "gid=100", &Ownership{GID: &gid, Mode: 0700})
+	require.True(t, ok)
+	require.Equal(t, "gid=100,umask=077", data, "Expected options in data to be kept")
+}
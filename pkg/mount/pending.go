@@ -0,0 +1,127 @@
+package mount
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"github.com/libopenstorage/openstorage/pkg/sched"
+)
+
+// PendingRemoval is a mount path whose delayed removal is scheduled.
+type PendingRemoval struct {
+	Path string
+	// ScheduledAt is when the removal was requested.
+	ScheduledAt time.Time
+	// RemoveAt is when the removal, or its next retry, is due.
+	RemoveAt time.Time
+	// Attempts is the number of failed attempts to remove Path.
+	Attempts int
+}
+
+// WithRemovalErrorHandler sets a handler called with the path and the error
+// when a removal scheduled by RemoveMountPath fails, so that the application
+// can retry or alert. Failures are only logged by default.
+func WithRemovalErrorHandler(handler func(path string, err error)) MounterOption {
+	return func(m *Mounter) {
+		m.removalErrorHandler = handler
+	}
+}
+
+// WithRemovalRetries retries a removal scheduled by RemoveMountPath that
+// failed up to retries times. The first retry is after backoff, which doubles
+// for every further retry. The removal error handler is only called once the
+// last retry has failed.
+func WithRemovalRetries(retries int, backoff time.Duration) MounterOption {
+	return func(m *Mounter) {
+		if retries < 0 {
+			retries = 0
+		}
+		m.removalRetries = retries
+		m.removalBackoff = backoff
+	}
+}
+
+// PendingRemovals returns the mount paths whose delayed removal has not run
+// yet, the earliest due first. A removal leaves the set once it has run,
+// whether it succeeded or not.
+func (m *Mounter) PendingRemovals() []PendingRemoval {
+	m.RLock()
+	defer m.RUnlock()
+	pending := make([]PendingRemoval, 0, len(m.pendingRemovals))
+	for _, p := range m.pendingRemovals {
+		pending = append(pending, p)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].RemoveAt.Equal(pending[j].RemoveAt) {
+			return pending[i].RemoveAt.Before(pending[j].RemoveAt)
+		}
+		return pending[i].Path < pending[j].Path
+	})
+	return pending
+}
+
+// addPendingRemoval records that the removal of path is scheduled after
+// delay, after attempts failed attempts.
+func (m *Mounter) addPendingRemoval(path string, delay time.Duration, attempts int) {
+	now := m.clock.Now()
+	m.Lock()
+	defer m.Unlock()
+	if m.pendingRemovals == nil {
+		m.pendingRemovals = make(map[string]PendingRemoval)
+	}
+	scheduledAt := now
+	if p, ok := m.pendingRemovals[path]; ok {
+		scheduledAt = p.ScheduledAt
+	}
+	m.pendingRemovals[path] = PendingRemoval{
+		Path:        path,
+		ScheduledAt: scheduledAt,
+		RemoveAt:    now.Add(delay),
+		Attempts:    attempts,
+	}
+}
+
+// deletePendingRemoval records that the removal of path is no longer
+// pending.
+func (m *Mounter) deletePendingRemoval(path string) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.pendingRemovals, path)
+}
+
+// removalFailed reports the failure of the scheduled removal of path.
+func (m *Mounter) removalFailed(path string, err error) {
+	m.logger.Warnf("Scheduled removal of mount path %v failed: %v", path, err)
+	if m.removalErrorHandler != nil {
+		m.removalErrorHandler(path, err)
+	}
+}
+
+// scheduleRemoval removes mountPath and then the trash symlinkPath pointing to
+// it after delay. attempts is the number of failed attempts so far. A failed
+// removal is retried as set by WithRemovalRetries.
+func (m *Mounter) scheduleRemoval(mountPath, symlinkPath string, attempts int, delay time.Duration) error {
+	m.addPendingRemoval(mountPath, delay, attempts)
+	err := m.scheduleAfter(func(sched.Interval) {
+		m.logger.Infof("[RemoveMountPath] Scheduled removing mount path %v ", mountPath)
+		err := m.removeMountPath(mountPath)
+		if err != nil && attempts < m.removalRetries {
+			backoff := m.removalBackoff << uint(attempts)
+			m.logger.Warnf("Failed to remove mount path %v, retrying in %v: %v", mountPath, backoff, err)
+			if e := m.scheduleRemoval(mountPath, symlinkPath, attempts+1, backoff); e == nil {
+				return
+			}
+		}
+		m.deletePendingRemoval(mountPath)
+		if err != nil {
+			m.removalFailed(mountPath, err)
+			return
+		}
+		os.Remove(symlinkPath)
+	}, delay)
+	if err != nil {
+		m.deletePendingRemoval(mountPath)
+	}
+	return err
+}
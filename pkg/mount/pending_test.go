@@ -0,0 +1,177 @@
+package mount
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/libopenstorage/openstorage/pkg/options"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingRemovals(t *testing.T) {
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	m, err := NewDeviceMounter(nil, newTestMountImpl(), nil, t.TempDir(),
+		withFsOps(newTestFsOps()), WithClock(clk), WithScheduler(s))
+	require.NoError(t, err)
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	require.NoError(t, os.Mkdir(first, 0755))
+	require.NoError(t, os.Mkdir(second, 0755))
+	require.Empty(t, m.PendingRemovals())
+
+	opts := map[string]string{options.OptionsWaitBeforeDelete: "true"}
+	start := clk.Now()
+	require.NoError(t, m.RemoveMountPath(first, opts))
+	s.Advance(10 * time.Second)
+	require.NoError(t, m.RemoveMountPath(second, opts))
+	require.Equal(t, []PendingRemoval{
+		{Path: first, ScheduledAt: start, RemoveAt: start.Add(mountPathRemoveDelay)},
+		{Path: second, ScheduledAt: start.Add(10 * time.Second),
+			RemoveAt: start.Add(10*time.Second + mountPathRemoveDelay)},
+	}, m.PendingRemovals())
+
+	// Requesting a pending removal again does not reschedule it.
+	require.NoError(t, m.RemoveMountPath(first, opts))
+	require.Len(t, m.PendingRemovals(), 2)
+
+	s.Advance(mountPathRemoveDelay - 10*time.Second)
+	pending := m.PendingRemovals()
+	require.Len(t, pending, 1)
+	require.Equal(t, second, pending[0].Path)
+
+	// A failed removal leaves the set too.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(second, "file"), nil, 0644))
+	s.Advance(10 * time.Second)
+	require.Empty(t, m.PendingRemovals())
+	_, err = os.Stat(second)
+	require.NoError(t, err, "Expected the non-empty path to be kept")
+}
+
+func TestCloseClearsPendingRemovals(t *testing.T) {
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	m, err := NewDeviceMounter(nil, newTestMountImpl(), nil, t.TempDir(),
+		withFsOps(newTestFsOps()), WithClock(clk), WithScheduler(s))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "mnt")
+	require.NoError(t, os.Mkdir(path, 0755))
+
+	require.NoError(t, m.RemoveMountPath(path, map[string]string{options.OptionsWaitBeforeDelete: "true"}))
+	require.Len(t, m.PendingRemovals(), 1)
+	require.NoError(t, m.Close())
+	require.Empty(t, m.PendingRemovals())
+}
+
+func TestRemovalErrorHandler(t *testing.T) {
+	removeErr := errors.New("remove failed")
+	orig := removeDir
+	removeDir = func(string) error { return removeErr }
+	t.Cleanup(func() { removeDir = orig })
+
+	type failure struct {
+		path string
+		err  error
+	}
+	var failures []failure
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	m, err := NewDeviceMounter(nil, newTestMountImpl(), nil, t.TempDir(),
+		withFsOps(newTestFsOps()), WithClock(clk), WithScheduler(s),
+		WithRemovalErrorHandler(func(path string, err error) {
+			failures = append(failures, failure{path, err})
+		}))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "mnt")
+	require.NoError(t, os.Mkdir(path, 0755))
+
+	require.NoError(t, m.RemoveMountPath(path, map[string]string{options.OptionsWaitBeforeDelete: "true"}))
+	require.Empty(t, failures)
+	s.Advance(mountPathRemoveDelay)
+	require.Equal(t, []failure{{path, removeErr}}, failures)
+	require.Empty(t, m.PendingRemovals())
+}
+
+func TestRemovalRetries(t *testing.T) {
+	removeErr := errors.New("device or resource busy")
+	failures := 2
+	orig := removeDir
+	removeDir = func(path string) error {
+		if failures > 0 {
+			failures--
+			return removeErr
+		}
+		return orig(path)
+	}
+	t.Cleanup(func() { removeDir = orig })
+
+	var handled []error
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	m, err := NewDeviceMounter(nil, newTestMountImpl(), nil, t.TempDir(),
+		withFsOps(newTestFsOps()), WithClock(clk), WithScheduler(s),
+		WithRemovalRetries(3, 5*time.Second),
+		WithRemovalErrorHandler(func(path string, err error) { handled = append(handled, err) }))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "mnt")
+	require.NoError(t, os.Mkdir(path, 0755))
+	start := clk.Now()
+
+	require.NoError(t, m.RemoveMountPath(path, map[string]string{options.OptionsWaitBeforeDelete: "true"}))
+	s.Advance(mountPathRemoveDelay)
+	require.Equal(t, []PendingRemoval{{
+		Path:        path,
+		ScheduledAt: start,
+		RemoveAt:    clk.Now().Add(5 * time.Second),
+		Attempts:    1,
+	}}, m.PendingRemovals())
+
+	// The second retry is after twice the backoff.
+	s.Advance(5 * time.Second)
+	pending := m.PendingRemovals()
+	require.Len(t, pending, 1)
+	require.Equal(t, 2, pending[0].Attempts)
+	require.Equal(t, clk.Now().Add(10*time.Second), pending[0].RemoveAt)
+	s.Advance(9 * time.Second)
+	_, err = os.Stat(path)
+	require.NoError(t, err, "Expected %v to exist until the retry", path)
+
+	s.Advance(time.Second)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err), "Expected %v to be removed by the retry", path)
+	require.Empty(t, m.PendingRemovals())
+	require.Empty(t, handled, "Expected no failure to be reported")
+	require.Equal(t, 0, s.pending())
+}
+
+func TestRemovalRetriesExhausted(t *testing.T) {
+	removeErr := errors.New("device or resource busy")
+	orig := removeDir
+	removeDir = func(string) error { return removeErr }
+	t.Cleanup(func() { removeDir = orig })
+
+	var handled []error
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	m, err := NewDeviceMounter(nil, newTestMountImpl(), nil, t.TempDir(),
+		withFsOps(newTestFsOps()), WithClock(clk), WithScheduler(s),
+		WithRemovalRetries(2, time.Second),
+		WithRemovalErrorHandler(func(path string, err error) { handled = append(handled, err) }))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "mnt")
+	require.NoError(t, os.Mkdir(path, 0755))
+
+	require.NoError(t, m.RemoveMountPath(path, map[string]string{options.OptionsWaitBeforeDelete: "true"}))
+	s.Advance(mountPathRemoveDelay)
+	s.Advance(time.Second)
+	require.Empty(t, handled)
+	s.Advance(2 * time.Second)
+	require.Equal(t, []error{removeErr}, handled, "Expected the failure to be reported once")
+	require.Empty(t, m.PendingRemovals())
+	require.Equal(t, 0, s.pending())
+}
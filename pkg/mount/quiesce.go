@@ -0,0 +1,26 @@
+package mount
+
+// Quiesce makes the Mounter reject new mounts with ErrQuiesced, as during the
+// drain of a node, until Unquiesce is called. Unmounts and the other
+// operations proceed normally, and mounts already in progress complete.
+func (m *Mounter) Quiesce() {
+	m.Lock()
+	defer m.Unlock()
+	m.quiesced = true
+	m.logger.Info("Quiesced, rejecting new mounts")
+}
+
+// Unquiesce makes the Mounter accept mounts again after Quiesce.
+func (m *Mounter) Unquiesce() {
+	m.Lock()
+	defer m.Unlock()
+	m.quiesced = false
+	m.logger.Info("Unquiesced, accepting new mounts")
+}
+
+// isQuiesced returns true if the Mounter is quiesced.
+func (m *Mounter) isQuiesced() bool {
+	m.RLock()
+	defer m.RUnlock()
+	return m.quiesced
+}
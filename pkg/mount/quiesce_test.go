@@ -0,0 +1,26 @@
+package mount
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuiesce(t *testing.T) {
+	m, mi := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/dev/dev", "/mnt/dev1", "ext4", 0, "", 0, nil))
+
+	m.Quiesce()
+	err := m.Mount(0, "/dev/dev", "/mnt/dev2", "ext4", 0, "", 0, nil)
+	require.True(t, errors.Is(err, ErrQuiesced), "Unexpected error %v", err)
+	require.Len(t, mi.calls, 1)
+	require.Equal(t, []string{"/mnt/dev1"}, m.Mounts("/dev/dev"))
+
+	require.NoError(t, m.Unmount("/dev/dev", "/mnt/dev1", 0, 0, nil))
+	require.Equal(t, []string{"/mnt/dev1"}, mi.unmounted)
+
+	m.Unquiesce()
+	require.NoError(t, m.Mount(0, "/dev/dev", "/mnt/dev2", "ext4", 0, "", 0, nil))
+	require.Equal(t, []string{"/mnt/dev2"}, m.Mounts("/dev/dev"))
+}
@@ -1,6 +1,3 @@
-//go:build linux
-// +build linux
-
 package mount
 
 import (
@@ -27,17 +24,17 @@ func NewRawBindMounter(
 	mountImpl MountImpl,
 	allowedDirs []string,
 	trashLocation string,
+	opts ...MounterOption,
 ) (*rawMounter, error) {
 	rm := &rawMounter{
 		Mounter: Mounter{
 			mountImpl:     mountImpl,
-			mounts:        make(DeviceMap),
-			paths:         make(PathMap),
 			allowedDirs:   allowedDirs,
 			kl:            keylock.New(),
 			trashLocation: trashLocation,
 		},
 	}
+	rm.setOptions(opts)
 	if err := rm.Load(rootSubstrings); err != nil {
 		return nil, err
 	}
@@ -48,13 +45,14 @@ func (rm *rawMounter) Reload(rootSubstring string) error {
 	newRBM, err := NewRawBindMounter(
 		[]*regexp.Regexp{regexp.MustCompile(regexp.QuoteMeta(rootSubstring))},
 		rm.mountImpl,
-		rm.allowedDirs,
+		rm.AllowedDirs(),
 		rm.trashLocation,
 	)
 	if err != nil {
 		return err
 	}
-	return rm.reload(rootSubstring, newRBM.mounts[rootSubstring])
+	info, _ := newRBM.lookup(rootSubstring)
+	return rm.reload(rootSubstring, info)
 }
 
 func shouldSkipMountPoint(mountPoint string) bool {
@@ -73,6 +71,8 @@ func (rm *rawMounter) Load(rawVolumeDevicesPaths []*regexp.Regexp) error {
 	if err != nil {
 		return err
 	}
+	rm.Lock()
+	defer rm.Unlock()
 
 	// try to find all bind mounts of raw volumes
 	if len(rawVolumeDevicesPaths) == 0 || rawVolumeDevicesPaths[0].String() == "" {
@@ -118,9 +118,8 @@ func (rm *rawMounter) Load(rawVolumeDevicesPaths []*regexp.Regexp) error {
 			devicePath := "/dev" + mp.Root
 
 			// source for raw volumes is equal to rawVolumeDevicePath
-			mount, ok := rm.mounts[devicePath]
-			if !ok {
-				mount = &Info{
+			if _, ok := rm.lookupLocked(devicePath); !ok {
+				rm.storeLocked(devicePath, &Info{
 					Device: devicePath,
 					Fs:     "",
 					Minor:  mp.Minor,
@@ -128,8 +127,7 @@ func (rm *rawMounter) Load(rawVolumeDevicesPaths []*regexp.Regexp) error {
 						Root: normalizeMountPath(devicePath),
 						Path: normalizeMountPath(mp.Mountpoint),
 					}},
-				}
-				rm.mounts[devicePath] = mount
+				})
 			}
 		}
 	}
@@ -151,9 +149,8 @@ func (rm *rawMounter) Load(rawVolumeDevicesPaths []*regexp.Regexp) error {
 		devicePath := "/dev" + mountPointForRoot.Root
 
 		// source for raw volumes is equal to rawVolumeDevicePath
-		mount, ok := rm.mounts[devicePath]
-		if !ok {
-			mount = &Info{
+		if _, ok := rm.lookupLocked(devicePath); !ok {
+			rm.storeLocked(devicePath, &Info{
 				Device: devicePath,
 				Fs:     "",
 				Minor:  mountPointForRoot.Minor,
@@ -161,10 +158,10 @@ func (rm *rawMounter) Load(rawVolumeDevicesPaths []*regexp.Regexp) error {
 					Root: normalizeMountPath(devicePath),
 					Path: normalizeMountPath(mountPointForRoot.Mountpoint),
 				}},
-			}
-			rm.mounts[devicePath] = mount
+			})
 		}
 	}
 
+	rm.reindexLocked()
 	return nil
 }
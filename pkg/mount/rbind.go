@@ -0,0 +1,128 @@
+package mount
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/docker/docker/pkg/mount"
+)
+
+// recordChildMounts records the mounts under path in the mount table, such
+// as the submounts carried by a recursive bind mount at path, as mountpoints
+// of their own sources. The submounts are found by walking the mount tree
+// from the mounts at path, which returns ErrMountLoopDetected if a mount is
+// reached twice.
+func (m *Mounter) recordChildMounts(path string) error {
+	infos, err := mountTable()
+	if err != nil {
+		return fmt.Errorf("failed to find the mounts under %s: %w", path, err)
+	}
+	children, err := childMounts(infos, path)
+	if err != nil {
+		return fmt.Errorf("failed to find the mounts under %s: %w", path, err)
+	}
+	m.Lock()
+	defer m.Unlock()
+	for _, v := range children {
+		child := normalizeMountPath(v.Mountpoint)
+		if child == path || !isWithin(path, child) {
+			continue
+		}
+		if _, ok := m.target(child); ok {
+			continue
+		}
+		m.addLoadedMountpoint(v, v.Source, v.Source, true /*updatePaths*/)
+		m.addPath(child, v.Source)
+	}
+	return nil
+}
+
+// mountNode identifies a mount while walking the mount tree. The mount ID
+// tells apart mounts of a source stacked at the same path.
+type mountNode struct {
+	id     int
+	source string
+	path   string
+}
+
+// childMounts returns the descendants of the mounts at path in infos, parents
+// first. It returns ErrMountLoopDetected if the parents of the mounts form a
+// cycle. Tables without mount IDs have no tree and are matched by path.
+func childMounts(infos []*mount.Info, path string) ([]*mount.Info, error) {
+	if !hasMountIDs(infos) {
+		var children []*mount.Info
+		for _, v := range infos {
+			if child := normalizeMountPath(v.Mountpoint); child != path && isWithin(path, child) {
+				children = append(children, v)
+			}
+		}
+		return children, nil
+	}
+	byParent := make(map[int][]*mount.Info)
+	var queue []*mount.Info
+	for _, v := range infos {
+		byParent[v.Parent] = append(byParent[v.Parent], v)
+		if normalizeMountPath(v.Mountpoint) == path {
+			queue = append(queue, v)
+		}
+	}
+	visited := make(map[mountNode]bool)
+	var children []*mount.Info
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		node := mountNode{id: v.ID, source: v.Source, path: normalizeMountPath(v.Mountpoint)}
+		if visited[node] {
+			return nil, fmt.Errorf("%w: %s at %s", ErrMountLoopDetected, v.Source, node.path)
+		}
+		visited[node] = true
+		if node.path != path {
+			children = append(children, v)
+		}
+		for _, c := range byParent[v.ID] {
+			// The root of the tree is its own parent.
+			if c != v {
+				queue = append(queue, c)
+			}
+		}
+	}
+	return children, nil
+}
+
+// unmountChildren unmounts the tracked mountpoints under path, deepest first.
+// It attempts all of them and returns a *MultiError of the failures.
+func (m *Mounter) unmountChildren(path string, flags, timeout int) error {
+	type child struct {
+		source, path string
+	}
+	var children []child
+	m.RLock()
+	m.eachTargetLocked(func(p string, sources []string) {
+		if p == path || !isWithin(path, p) {
+			return
+		}
+		children = append(children, child{source: sources[0], path: p})
+	})
+	m.RUnlock()
+	sort.Slice(children, func(i, j int) bool {
+		return len(children[i].path) > len(children[j].path)
+	})
+	var merr MultiError
+	for _, c := range children {
+		if err := m.Unmount(c.source, c.path, flags, timeout, nil); err != nil {
+			merr.add(c.path, fmt.Errorf("failed to unmount under %s: %w", path, err))
+		}
+	}
+	return merr.errOrNil()
+}
+
+// hasMountIDs returns true if the entries of infos have mount IDs, as the
+// ones read from mountinfo do.
+func hasMountIDs(infos []*mount.Info) bool {
+	for _, v := range infos {
+		if v.ID != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/docker/docker/pkg/mount"
+	"github.com/libopenstorage/openstorage/pkg/options"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecursiveBindMount(t *testing.T) {
+	orig := mountTable
+	mountTable = func() ([]*mount.Info, error) {
+		return []*mount.Info{
+			{Source: "/dev/sda1", Mountpoint: "/mnt/rbind", Fstype: "ext4", Root: "/src"},
+			{Source: "/dev/sdb", Mountpoint: "/mnt/rbind/data", Fstype: "xfs", Root: "/", Minor: 16},
+			{Source: "tmpfs", Mountpoint: "/mnt/rbind/data/tmp", Fstype: "tmpfs", Root: "/"},
+			{Source: "/dev/sdc", Mountpoint: "/mnt/rbind2", Fstype: "ext4", Root: "/"},
+		}, nil
+	}
+	t.Cleanup(func() { mountTable = orig })
+	m, mi := newTestMounter(t)
+
+	require.ErrorIs(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/sda1", Path: "/mnt/rbind", Fs: "ext4", Recursive: true,
+	}), ErrEinval, "Expected a recursive mount to be a bind mount")
+
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/src", Path: "/mnt/rbind", Flags: syscall.MS_BIND, Recursive: true,
+	}))
+	require.Equal(t, uintptr(syscall.MS_BIND|syscall.MS_REC), mi.lastCall().flags)
+	for child, source := range map[string]string{
+		"/mnt/rbind/data":     "/dev/sdb",
+		"/mnt/rbind/data/tmp": "tmpfs",
+	} {
+		dev, ok := m.HasTarget(child)
+		require.True(t, ok, child)
+		require.Equal(t, source, dev, child)
+	}
+	_, ok := m.HasTarget("/mnt/rbind2")
+	require.False(t, ok, "Expected mounts outside of the target to be ignored")
+	minor, err := m.GetMinor("/dev/sdb")
+	require.NoError(t, err)
+	require.Equal(t, 16, minor)
+
+	require.NoError(t, m.Unmount("/src", "/mnt/rbind", 0, 0,
+		map[string]string{options.OptionsUnmountChildren: "true"}))
+	require.Equal(t, []string{"/mnt/rbind/data/tmp", "/mnt/rbind/data", "/mnt/rbind"}, mi.unmounted,
+		"Expected the children to be unmounted first, deepest first")
+	require.Empty(t, m.GetSourcePaths())
+}
+
+func TestRecordChildMountsTree(t *testing.T) {
+	orig := mountTable
+	mountTable = func() ([]*mount.Info, error) {
+		return []*mount.Info{
+			{ID: 1, Parent: 1, Source: "/dev/root", Mountpoint: "/", Fstype: "ext4", Root: "/"},
+			// Shadowed by the mount at /mnt/rbind.
+			{ID: 2, Parent: 1, Source: "/dev/old", Mountpoint: "/mnt/rbind/old", Fstype: "ext4", Root: "/"},
+			{ID: 3, Parent: 1, Source: "/dev/sda1", Mountpoint: "/mnt/rbind", Fstype: "ext4", Root: "/src"},
+			{ID: 4, Parent: 3, Source: "/dev/sdb", Mountpoint: "/mnt/rbind/data", Fstype: "xfs", Root: "/"},
+			{ID: 5, Parent: 4, Source: "tmpfs", Mountpoint: "/mnt/rbind/data/tmp", Fstype: "tmpfs", Root: "/"},
+			// Stacked on the mount at /mnt/rbind/data/tmp.
+			{ID: 6, Parent: 5, Source: "tmpfs", Mountpoint: "/mnt/rbind/data/tmp", Fstype: "tmpfs", Root: "/"},
+		}, nil
+	}
+	t.Cleanup(func() { mountTable = orig })
+	m, _ := newTestMounter(t)
+
+	require.NoError(t, m.recordChildMounts("/mnt/rbind"))
+	for _, child := range []string{"/mnt/rbind/data", "/mnt/rbind/data/tmp"} {
+		_, ok := m.HasTarget(child)
+		require.True(t, ok, child)
+	}
+	_, ok := m.HasTarget("/mnt/rbind/old")
+	require.False(t, ok, "Expected mounts outside of the tree to be ignored")
+}
+
+func TestRecordChildMountsLoop(t *testing.T) {
+	orig := mountTable
+	mountTable = func() ([]*mount.Info, error) {
+		// A is mounted on B mounted on A.
+		return []*mount.Info{
+			{ID: 1, Parent: 1, Source: "/dev/root", Mountpoint: "/", Fstype: "ext4", Root: "/"},
+			{ID: 2, Parent: 3, Source: "/dev/a", Mountpoint: "/mnt/loop", Fstype: "ext4", Root: "/"},
+			{ID: 3, Parent: 2, Source: "/dev/b", Mountpoint: "/mnt/loop/b", Fstype: "ext4", Root: "/"},
+		}, nil
+	}
+	t.Cleanup(func() { mountTable = orig })
+	m, _ := newTestMounter(t)
+
+	require.ErrorIs(t, m.recordChildMounts("/mnt/loop"), ErrMountLoopDetected)
+	require.Empty(t, m.GetSourcePaths(), "Expected nothing to be recorded")
+}
@@ -0,0 +1,155 @@
+package mount
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// WithReadOnlyVerification makes Mount check the mount table after a
+// read-only mount or remount, and fail with ErrNotReadOnly if the path is
+// not mounted read-only. A failed mount is unmounted.
+func WithReadOnlyVerification() MounterOption {
+	return func(m *Mounter) {
+		m.verifyReadOnly = true
+	}
+}
+
+// IsReadOnly returns true if path is tracked as mounted read-only. It
+// returns ErrEnoent if path is not tracked.
+func (m *Mounter) IsReadOnly(path string) (bool, error) {
+	path = normalizeMountPath(path)
+	m.RLock()
+	defer m.RUnlock()
+
+	source, ok := m.target(path)
+	if !ok {
+		return false, ErrEnoent
+	}
+	sh := m.deviceShard(source)
+	sh.Lock()
+	defer sh.Unlock()
+	info, ok := sh.mounts[source]
+	if !ok {
+		return false, ErrEnoent
+	}
+	for _, p := range info.Mountpoint {
+		if p.Path == path {
+			return p.ReadOnly, nil
+		}
+	}
+	return false, ErrEnoent
+}
+
+// isReadOnlyFlags returns true if flags make a read-only mount. MS_RDONLY is
+// ignored when creating a bind mount, it needs a remount.
+func isReadOnlyFlags(flags uintptr) bool {
+	if msRdonly == 0 || flags&msRdonly == 0 {
+		return false
+	}
+	return !isBindMount(flags) || flags&msRemount != 0
+}
+
+// checkReadOnly returns ErrNotReadOnly if verification is enabled, flags
+// make a read-only mount and path is not mounted read-only.
+func (m *Mounter) checkReadOnly(path string, flags uintptr) error {
+	if !m.verifyReadOnly || !isReadOnlyFlags(flags) {
+		return nil
+	}
+	readOnly, _, err := mountedReadOnly(path)
+	if err != nil {
+		return err
+	}
+	if !readOnly {
+		return ErrNotReadOnly
+	}
+	return nil
+}
+
+// mountedReadOnly returns true if path is mounted read-only in the mount
+// table, and whether path is found in the mount table.
+func mountedReadOnly(path string) (bool, bool, error) {
+	infos, err := mountTable()
+	if err != nil {
+		return false, false, err
+	}
+	path = filepath.Clean(path)
+	readOnly, found := false, false
+	// The last entry for path is the one visible.
+	for _, info := range infos {
+		if filepath.Clean(info.Mountpoint) == path {
+			readOnly = hasMountOption(info.Opts, "ro")
+			found = true
+		}
+	}
+	return readOnly, found, nil
+}
+
+// remountReadOnly remounts path read-only if the mount table shows that the
+// read-only mount of devPath at path with flags is read-write, as some
+// filesystems ignore MS_RDONLY on the initial mount.
+func (m *Mounter) remountReadOnly(
+	devPath, path, fs string,
+	flags uintptr,
+	data string,
+	timeout int,
+	backend func(source, target, fstype string, flags uintptr, data string, timeout int) error,
+) error {
+	if !isReadOnlyFlags(flags) || flags&msRemount != 0 {
+		return nil
+	}
+	readOnly, found, err := mountedReadOnly(path)
+	if err != nil {
+		return err
+	}
+	if readOnly || !found {
+		return nil
+	}
+	m.logger.Infof("Remounting %s read-only as its read-only mount is read-write", path)
+	return backend(devPath, path, fs, flags|msRemount, data, timeout)
+}
+
+// hasMountOption returns true if opt is one of the comma separated opts.
+func hasMountOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// osWriteProbe implements the write probe of fsOps with the os package.
+type osWriteProbe struct{}
+
+func (osWriteProbe) ProbeWrite(dir string) error {
+	f, err := ioutil.TempFile(dir, ".mount-probe-")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	if err := f.Close(); err != nil {
+		os.Remove(name)
+		return err
+	}
+	return os.Remove(name)
+}
+
+// writeProbeHook returns a hook failing with ErrFilesystemReadOnly if a file
+// cannot be created in the mount root because the filesystem is read-only.
+func (m *Mounter) writeProbeHook() postMountHook {
+	return func(path string) error {
+		err := m.fsops.ProbeWrite(path)
+		if errors.Is(err, syscall.EROFS) {
+			return ErrFilesystemReadOnly
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write to %s: %w", path, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,171 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/docker/docker/pkg/mount"
+	"github.com/stretchr/testify/require"
+)
+
+// setTestMountOpts makes the mount table report path mounted with opts.
+func setTestMountOpts(t *testing.T, path, opts string) {
+	orig := mountTable
+	mountTable = func() ([]*mount.Info, error) {
+		return []*mount.Info{{Mountpoint: "/"}, {Mountpoint: path, Opts: opts}}, nil
+	}
+	t.Cleanup(func() { mountTable = orig })
+}
+
+func TestIsReadOnly(t *testing.T) {
+	m, _ := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/dev/ro", "/mnt/ro", "ext4", msRdonly, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/dev/rw", "/mnt/rw", "ext4", 0, "", 0, nil))
+
+	ro, err := m.IsReadOnly("/mnt/ro")
+	require.NoError(t, err)
+	require.True(t, ro)
+	ro, err = m.IsReadOnly("/mnt/rw/")
+	require.NoError(t, err)
+	require.False(t, ro)
+	_, err = m.IsReadOnly("/mnt/none")
+	require.Equal(t, ErrEnoent, err)
+
+	errs, err := m.MountBatch([]MountOptions{{Device: "/dev/ro", Path: "/mnt/ro2", Fs: "ext4", ReadOnly: true}})
+	require.NoError(t, err)
+	require.NoError(t, errs[0])
+	ro, err = m.IsReadOnly("/mnt/ro2")
+	require.NoError(t, err)
+	require.True(t, ro)
+}
+
+func TestIsReadOnlyBind(t *testing.T) {
+	m, _ := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/src", "/mnt/bind", "", msBind|msRdonly, "", 0, nil))
+	ro, err := m.IsReadOnly("/mnt/bind")
+	require.NoError(t, err)
+	require.False(t, ro, "MS_RDONLY is ignored when creating a bind mount")
+}
+
+func TestRemountReadOnly(t *testing.T) {
+	m, mi := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/dev/re", "/mnt/re", "ext4", 0, "", 0, nil))
+
+	require.NoError(t, m.Mount(0, "/dev/re", "/mnt/re", "ext4", msRemount|msRdonly, "", 0, nil))
+	require.Equal(t, uintptr(msRemount|msRdonly), mi.lastCall().flags)
+	ro, err := m.IsReadOnly("/mnt/re")
+	require.NoError(t, err)
+	require.True(t, ro, "Expected the remount to flip the path read-only")
+	require.Equal(t, 1, m.HasMounts("/dev/re"))
+
+	require.NoError(t, m.Mount(0, "/dev/re", "/mnt/re", "ext4", msRemount, "", 0, nil))
+	ro, err = m.IsReadOnly("/mnt/re")
+	require.NoError(t, err)
+	require.False(t, ro, "Expected the remount to flip the path writeable")
+
+	mi.mountErr = errors.New("remount failed")
+	require.Error(t, m.Mount(0, "/dev/re", "/mnt/re", "ext4", msRemount|msRdonly, "", 0, nil))
+	ro, err = m.IsReadOnly("/mnt/re")
+	require.NoError(t, err)
+	require.False(t, ro, "A failed remount must not change the stored flag")
+}
+
+func TestReadOnlyVerification(t *testing.T) {
+	m, mi := newTestMounter(t, WithReadOnlyVerification())
+
+	setTestMountOpts(t, "/mnt/verify", "ro,relatime")
+	require.NoError(t, m.Mount(0, "/dev/verify", "/mnt/verify", "ext4", msRdonly, "", 0, nil))
+
+	setTestMountOpts(t, "/mnt/writeable", "rw,relatime")
+	err := m.Mount(0, "/dev/writeable", "/mnt/writeable", "ext4", msRdonly, "", 0, nil)
+	require.True(t, errors.Is(err, ErrNotReadOnly), "got %v", err)
+	require.Equal(t, 0, m.HasMounts("/dev/writeable"))
+	require.Contains(t, mi.unmounted, "/mnt/writeable")
+
+	// Writeable mounts are not verified.
+	require.NoError(t, m.Mount(0, "/dev/writeable", "/mnt/writeable", "ext4", 0, "", 0, nil))
+}
+
+func TestMountReadOnlyRemount(t *testing.T) {
+	m, mi := newTestMounter(t, WithReadOnlyVerification())
+	// The filesystem ignores MS_RDONLY until it is remounted.
+	orig := mountTable
+	mountTable = func() ([]*mount.Info, error) {
+		opts := "rw,relatime"
+		if call := mi.lastCall(); call.flags&msRemount != 0 {
+			opts = "ro,relatime"
+		}
+		return []*mount.Info{{Mountpoint: "/"}, {Mountpoint: "/mnt/quirk", Opts: opts}}, nil
+	}
+	t.Cleanup(func() { mountTable = orig })
+
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/quirk", Path: "/mnt/quirk", Fs: "vfat", Data: "utf8", ReadOnly: true,
+	}))
+	require.Len(t, mi.calls, 2)
+	require.Equal(t, uintptr(msRdonly), mi.calls[0].flags)
+	require.Equal(t, testMountCall{
+		source: "/dev/quirk",
+		target: "/mnt/quirk",
+		fstype: "vfat",
+		flags:  msRdonly | msRemount,
+		data:   "utf8",
+	}, mi.calls[1])
+	ro, err := m.IsReadOnly("/mnt/quirk")
+	require.NoError(t, err)
+	require.True(t, ro)
+
+	// A mount that is read-only right away is not remounted.
+	setTestMountOpts(t, "/mnt/ro", "ro,relatime")
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/ro", Path: "/mnt/ro", Fs: "ext4", ReadOnly: true,
+	}))
+	require.Len(t, mi.calls, 3)
+}
+
+func TestMountVerifyWritable(t *testing.T) {
+	m, mi := newTestMounter(t)
+	fsops := m.fsops.(*testFsOps)
+	o := MountOptions{Device: "/dev/rofs", Path: "/mnt/rofs", Fs: "ext4", VerifyWritable: true}
+
+	require.NoError(t, m.MountWithOptions(o))
+	require.Contains(t, fsops.ops, "probe /mnt/rofs")
+	require.NoError(t, m.Unmount("/dev/rofs", "/mnt/rofs", 0, 0, nil))
+
+	fsops.probeErr = &os.PathError{Op: "open", Path: "/mnt/rofs/.mount-probe-1", Err: syscall.EROFS}
+	err := m.MountWithOptions(o)
+	require.True(t, errors.Is(err, ErrFilesystemReadOnly), "got %v", err)
+	require.Equal(t, 0, m.HasMounts("/dev/rofs"))
+	require.Equal(t, []string{"/mnt/rofs", "/mnt/rofs"}, mi.unmounted)
+
+	// Other failures are not reported as a read-only filesystem.
+	fsops.probeErr = &os.PathError{Op: "open", Path: "/mnt/rofs/.mount-probe-1", Err: syscall.EACCES}
+	err = m.MountWithOptions(o)
+	require.True(t, errors.Is(err, syscall.EACCES), "got %v", err)
+	require.False(t, errors.Is(err, ErrFilesystemReadOnly))
+
+	// Read-only mounts and mounts without the option are not probed.
+	fsops.ops = nil
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/rofs", Path: "/mnt/rofs", Fs: "ext4", ReadOnly: true, VerifyWritable: true,
+	}))
+	require.NoError(t, m.MountWithOptions(MountOptions{Device: "/dev/rofs", Path: "/mnt/rofs2", Fs: "ext4"}))
+	require.NotContains(t, fsops.ops, "probe /mnt/rofs")
+	require.NotContains(t, fsops.ops, "probe /mnt/rofs2")
+}
+
+func TestWriteProbe(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, osWriteProbe{}.ProbeWrite(dir))
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	require.Error(t, osWriteProbe{}.ProbeWrite(filepath.Join(dir, "missing")))
+}
@@ -0,0 +1,46 @@
+package mount
+
+// Rename re-keys the device oldDevice as newDevice, e.g. after udev renamed
+// sdb to sdc, keeping its mountpoints and their reference counts. It returns
+// ErrEnoent if oldDevice is not tracked and ErrExist if newDevice is.
+func (m *Mounter) Rename(oldDevice, newDevice string) error {
+	first, second := deviceLockKey(oldDevice), deviceLockKey(newDevice)
+	if second < first {
+		first, second = second, first
+	}
+	h1 := m.kl.Acquire(first)
+	defer m.kl.Release(&h1)
+	if second != first {
+		h2 := m.kl.Acquire(second)
+		defer m.kl.Release(&h2)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	info, ok := m.lookupLocked(oldDevice)
+	if !ok {
+		return ErrEnoent
+	}
+	if _, ok := m.lookupLocked(newDevice); ok {
+		return ErrExist
+	}
+	m.deleteLocked(oldDevice)
+	m.storeLocked(newDevice, info)
+	info.Lock()
+	info.Device = newDevice
+	info.Unlock()
+	m.eachLoadedPathLocked(func(path, source string) string {
+		if source == oldDevice {
+			return newDevice
+		}
+		return source
+	})
+	for _, e := range m.expiries {
+		if e.device == oldDevice {
+			e.device = newDevice
+		}
+	}
+	m.reindexLocked()
+	m.logger.Infof("Renamed device %s to %s", oldDevice, newDevice)
+	return nil
+}
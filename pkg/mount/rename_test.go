@@ -0,0 +1,41 @@
+package mount
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRename(t *testing.T) {
+	m, mi := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/dev/sdb", "/mnt/a", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/dev/sdb", "/mnt/b", "ext4", 0, "", 0, nil))
+
+	require.NoError(t, m.Rename("/dev/sdb", "/dev/sdc"))
+	require.Equal(t, 0, m.HasMounts("/dev/sdb"))
+	require.Equal(t, 2, m.HasMounts("/dev/sdc"))
+	require.Equal(t, []string{"/mnt/a", "/mnt/b"}, m.Mounts("/dev/sdc"))
+	for _, path := range []string{"/mnt/a", "/mnt/b"} {
+		dev, ok := m.HasTarget(path)
+		require.True(t, ok)
+		require.Equal(t, "/dev/sdc", dev)
+		source, err := m.GetSourcePath(path)
+		require.NoError(t, err)
+		require.Equal(t, "/dev/sdc", source)
+	}
+
+	require.NoError(t, m.Unmount("/dev/sdc", "/mnt/a", 0, 0, nil))
+	require.Equal(t, []string{"/mnt/a"}, mi.unmounted)
+	require.Equal(t, []string{"/mnt/b"}, m.Mounts("/dev/sdc"))
+}
+
+func TestRenameErrors(t *testing.T) {
+	m, _ := newTestMounter(t)
+	require.NoError(t, m.Mount(0, "/dev/sdb", "/mnt/a", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/dev/sdc", "/mnt/b", "ext4", 0, "", 0, nil))
+
+	require.Equal(t, ErrEnoent, m.Rename("/dev/missing", "/dev/sdd"))
+	require.Equal(t, ErrExist, m.Rename("/dev/sdb", "/dev/sdc"))
+	require.Equal(t, []string{"/mnt/a"}, m.Mounts("/dev/sdb"))
+	require.Equal(t, []string{"/mnt/b"}, m.Mounts("/dev/sdc"))
+}
@@ -0,0 +1,53 @@
+package mount
+
+import (
+	"fmt"
+	"strings"
+)
+
+// selinuxContextOption is the mount option labeling all the files of a
+// mount with an SELinux context.
+const selinuxContextOption = "context"
+
+// validateSELinuxLabel checks that label looks like an SELinux context of the
+// form user:role:type[:level]. The level may contain commas, such as
+// s0:c1,c2, but no quotes or spaces.
+func validateSELinuxLabel(label string) error {
+	fields := strings.SplitN(label, ":", 4)
+	if strings.ContainsAny(label, "\"' \t\n") || len(fields) < 3 {
+		return fmt.Errorf("invalid SELinux label %q: %w", label, ErrEinval)
+	}
+	for _, field := range fields[:3] {
+		if field == "" {
+			return fmt.Errorf("invalid SELinux label %q: %w", label, ErrEinval)
+		}
+	}
+	return nil
+}
+
+// withSELinuxContext returns data with a context option for label. The label
+// is quoted as its level may contain commas, which separate mount options.
+func withSELinuxContext(data, label string) (string, error) {
+	for _, o := range strings.Split(data, ",") {
+		if strings.HasPrefix(o, selinuxContextOption+"=") {
+			return "", fmt.Errorf("mount data %q already has a %s option: %w",
+				data, selinuxContextOption, ErrEinval)
+		}
+	}
+	option := fmt.Sprintf("%s=%q", selinuxContextOption, label)
+	if data == "" {
+		return option, nil
+	}
+	return data + "," + option, nil
+}
+
+// relabelHook returns a hook labeling the files of a bind mount with label,
+// as the context option only applies to new mounts of a filesystem.
+func (m *Mounter) relabelHook(label string) postMountHook {
+	return func(path string) error {
+		if err := m.fsops.Relabel(path, label); err != nil {
+			return fmt.Errorf("failed to relabel %s with %s: %w", path, label, err)
+		}
+		return nil
+	}
+}
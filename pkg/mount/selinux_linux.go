@@ -0,0 +1,26 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// selinuxXattr is the extended attribute holding the SELinux label of a file.
+const selinuxXattr = "security.selinux"
+
+// xattrLabeler implements the relabeling of fsOps with extended attributes.
+type xattrLabeler struct{}
+
+func (xattrLabeler) Relabel(path, label string) error {
+	return filepath.Walk(path, func(p string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return unix.Lsetxattr(p, selinuxXattr, []byte(label), 0)
+	})
+}
@@ -0,0 +1,66 @@
+package mount
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSELinuxLabel = "system_u:object_r:container_file_t:s0:c1,c2"
+
+func TestValidateSELinuxLabel(t *testing.T) {
+	for _, label := range []string{
+		"system_u:object_r:container_file_t",
+		"system_u:object_r:container_file_t:s0",
+		testSELinuxLabel,
+	} {
+		require.NoError(t, validateSELinuxLabel(label), label)
+	}
+	for _, label := range []string{
+		"container_file_t",
+		"system_u:object_r",
+		"system_u::container_file_t:s0",
+		`system_u:object_r:container_file_t:s0",uid=0`,
+		"system_u:object_r:container file_t:s0",
+	} {
+		require.ErrorIs(t, validateSELinuxLabel(label), ErrEinval, label)
+	}
+}
+
+func TestMountWithSELinuxLabel(t *testing.T) {
+	m, mi := newTestMounter(t)
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/selinux", Path: "/mnt/selinux1", Fs: "ext4", SELinuxLabel: testSELinuxLabel,
+	}))
+	require.Equal(t, `context="`+testSELinuxLabel+`"`, mi.lastCall().data)
+
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/selinux", Path: "/mnt/selinux2", Fs: "ext4", Data: "discard",
+		SELinuxLabel: "system_u:object_r:container_file_t:s0",
+	}))
+	require.Equal(t, `discard,context="system_u:object_r:container_file_t:s0"`, mi.lastCall().data)
+
+	err := m.MountWithOptions(MountOptions{
+		Device: "/dev/selinux", Path: "/mnt/selinux3", Fs: "ext4", Data: `context="other_u:r:t"`,
+		SELinuxLabel: testSELinuxLabel,
+	})
+	require.ErrorIs(t, err, ErrEinval)
+	err = m.MountWithOptions(MountOptions{
+		Device: "/dev/selinux", Path: "/mnt/selinux3", Fs: "ext4", SELinuxLabel: "invalid",
+	})
+	require.ErrorIs(t, err, ErrEinval)
+	require.Len(t, mi.calls, 2)
+}
+
+func TestBindMountWithSELinuxLabel(t *testing.T) {
+	if msBind == 0 {
+		t.Skip("bind mounts are specific to Linux")
+	}
+	ops := newTestFsOps()
+	m, mi := newTestMounter(t, withFsOps(ops))
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/src/selinux", Path: "/mnt/selinux", Flags: msBind, SELinuxLabel: testSELinuxLabel,
+	}))
+	require.Empty(t, mi.lastCall().data, "Expected no context option for a bind mount")
+	require.Contains(t, ops.ops, "relabel "+testSELinuxLabel+" /mnt/selinux")
+}
@@ -0,0 +1,233 @@
+package mount
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// The device map and the paths tracked by the Mounter are split in shards,
+// each with its own lock, so that mounts and unmounts of different devices
+// at different paths do not wait on each other. Operations on a device or a
+// path hold the Mounter lock for reading and the lock of the shard; only the
+// operations on the whole table, such as load and reload, hold the Mounter
+// lock for writing. With the Mounter lock held for writing, shards may also
+// be accessed without their locks.
+
+// tableShards is the number of shards of the device map and of the paths.
+// It is a power of two so that a shard is picked with a mask.
+const tableShards = 32
+
+// deviceShard is a part of the device map.
+type deviceShard struct {
+	sync.Mutex
+	mounts DeviceMap
+}
+
+// pathShard is a part of the paths map and of the index of mountpoints to
+// the sources mounted there.
+type pathShard struct {
+	sync.Mutex
+	paths   PathMap
+	targets map[string][]string
+}
+
+// shardOf returns the shard of key, hashed with FNV-1a.
+func shardOf(key string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return int(h & (tableShards - 1))
+}
+
+// deviceShard returns the shard of the device map holding device.
+func (m *Mounter) deviceShard(device string) *deviceShard {
+	return &m.deviceShards[shardOf(device)]
+}
+
+// pathShard returns the shard of the paths holding path.
+func (m *Mounter) pathShard(path string) *pathShard {
+	return &m.pathShards[shardOf(path)]
+}
+
+// set records info as the Info of device with s locked.
+func (s *deviceShard) set(device string, info *Info) {
+	if s.mounts == nil {
+		s.mounts = make(DeviceMap)
+	}
+	s.mounts[device] = info
+}
+
+// lookup returns the Info of device.
+func (m *Mounter) lookup(device string) (*Info, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.lookupLocked(device)
+}
+
+// lookupLocked returns the Info of device with m locked.
+func (m *Mounter) lookupLocked(device string) (*Info, bool) {
+	s := m.deviceShard(device)
+	s.Lock()
+	defer s.Unlock()
+	info, ok := s.mounts[device]
+	return info, ok
+}
+
+// storeLocked records info as the Info of device with m locked.
+func (m *Mounter) storeLocked(device string, info *Info) {
+	s := m.deviceShard(device)
+	s.Lock()
+	defer s.Unlock()
+	s.set(device, info)
+}
+
+// loadOrStoreLocked returns the Info of device with m locked, recording info
+// as the Info of device if there is none. ok is true if there was one.
+func (m *Mounter) loadOrStoreLocked(device string, info *Info) (*Info, bool) {
+	s := m.deviceShard(device)
+	s.Lock()
+	defer s.Unlock()
+	if current, ok := s.mounts[device]; ok {
+		return current, true
+	}
+	s.set(device, info)
+	return info, false
+}
+
+// deleteLocked removes device from the device map with m locked.
+func (m *Mounter) deleteLocked(device string) {
+	s := m.deviceShard(device)
+	s.Lock()
+	defer s.Unlock()
+	delete(s.mounts, device)
+}
+
+// eachDeviceLocked calls fn for every device and its Info with m locked. The
+// shard of the device is locked while fn is called, fn must not use the
+// device map.
+func (m *Mounter) eachDeviceLocked(fn func(device string, info *Info)) {
+	for i := range m.deviceShards {
+		s := &m.deviceShards[i]
+		s.Lock()
+		for device, info := range s.mounts {
+			fn(device, info)
+		}
+		s.Unlock()
+	}
+}
+
+// deviceCountLocked returns the number of devices with m locked.
+func (m *Mounter) deviceCountLocked() int {
+	n := 0
+	for i := range m.deviceShards {
+		s := &m.deviceShards[i]
+		s.Lock()
+		n += len(s.mounts)
+		s.Unlock()
+	}
+	return n
+}
+
+// loadedSourceLocked returns the source path is recorded under in the paths
+// map with m locked.
+func (m *Mounter) loadedSourceLocked(path string) (string, bool) {
+	s := m.pathShard(path)
+	s.Lock()
+	defer s.Unlock()
+	source, ok := s.paths[path]
+	return source, ok
+}
+
+// setLoadedSourceLocked records source as the source of path in the paths
+// map with m locked.
+func (m *Mounter) setLoadedSourceLocked(path, source string) {
+	s := m.pathShard(path)
+	s.Lock()
+	defer s.Unlock()
+	if s.paths == nil {
+		s.paths = make(PathMap)
+	}
+	if _, ok := s.paths[path]; !ok {
+		atomic.AddInt32(&m.loadedPaths, 1)
+	}
+	s.paths[path] = source
+}
+
+// deleteLoadedPath removes path from the paths map with m locked if it is
+// tracked under device.
+func (m *Mounter) deleteLoadedPath(path, device string) {
+	s := m.pathShard(path)
+	s.Lock()
+	defer s.Unlock()
+	if source, ok := s.paths[path]; ok && source == device {
+		delete(s.paths, path)
+		atomic.AddInt32(&m.loadedPaths, -1)
+	}
+}
+
+// eachLoadedPathLocked calls fn for every path of the paths map and its
+// source with m locked. fn returns the source to record for path, or an empty
+// one to remove path. The shard of the path is locked while fn is called.
+func (m *Mounter) eachLoadedPathLocked(fn func(path, source string) string) {
+	for i := range m.pathShards {
+		s := &m.pathShards[i]
+		s.Lock()
+		for path, source := range s.paths {
+			if update := fn(path, source); update == "" {
+				delete(s.paths, path)
+				atomic.AddInt32(&m.loadedPaths, -1)
+			} else if update != source {
+				s.paths[path] = update
+			}
+		}
+		s.Unlock()
+	}
+}
+
+// tableLocked returns copies of the device map and of the paths map with m
+// locked.
+func (m *Mounter) tableLocked() (DeviceMap, PathMap) {
+	mounts := make(DeviceMap)
+	m.eachDeviceLocked(func(device string, info *Info) {
+		mounts[device] = info
+	})
+	paths := make(PathMap)
+	m.eachLoadedPathLocked(func(path, source string) string {
+		paths[path] = source
+		return source
+	})
+	return mounts, paths
+}
+
+// setTableLocked replaces the device map and the paths map with mounts and
+// paths, and rebuilds the index, with m locked for writing.
+func (m *Mounter) setTableLocked(mounts DeviceMap, paths PathMap) {
+	for i := range m.deviceShards {
+		m.deviceShards[i].mounts = nil
+	}
+	for i := range m.pathShards {
+		m.pathShards[i].paths = nil
+	}
+	atomic.StoreInt32(&m.loadedPaths, 0)
+	for device, info := range mounts {
+		m.deviceShard(device).set(device, info)
+	}
+	for path, source := range paths {
+		m.setLoadedSourceLocked(path, source)
+	}
+	m.reindexLocked()
+}
+
+// updateTable calls fn with copies of the device map and of the paths map,
+// which fn may change, and replaces the table with them. m is locked for
+// writing while fn is called, so that no mount or unmount is lost.
+func (m *Mounter) updateTable(fn func(DeviceMap, PathMap) error) error {
+	m.Lock()
+	defer m.Unlock()
+	mounts, paths := m.tableLocked()
+	err := fn(mounts, paths)
+	m.setTableLocked(mounts, paths)
+	return err
+}
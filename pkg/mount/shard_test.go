@@ -0,0 +1,63 @@
+package mount
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// testInfo returns the Info of device, or nil if it is not tracked.
+func (m *Mounter) testInfo(device string) *Info {
+	info, _ := m.lookup(device)
+	return info
+}
+
+// setTestInfo records info as the Info of device. Tests call reindex once
+// the table is set up.
+func (m *Mounter) setTestInfo(device string, info *Info) {
+	m.Lock()
+	defer m.Unlock()
+	m.storeLocked(device, info)
+}
+
+// setTestPath records source as the source of path in the paths map.
+func (m *Mounter) setTestPath(path, source string) {
+	m.Lock()
+	defer m.Unlock()
+	m.setLoadedSourceLocked(path, source)
+}
+
+// testPaths returns a copy of the paths map.
+func (m *Mounter) testPaths() PathMap {
+	m.RLock()
+	defer m.RUnlock()
+	_, paths := m.tableLocked()
+	return paths
+}
+
+// BenchmarkConcurrentDevices mounts and unmounts a device per goroutine, with
+// devices goroutines per CPU, in a table of 1000 devices.
+func BenchmarkConcurrentDevices(b *testing.B) {
+	for _, devices := range []int{1, 8, 64} {
+		b.Run(fmt.Sprintf("devices=%d", devices), func(b *testing.B) {
+			m := newBenchTable(b, 1000)
+			m.mountImpl = newTestMountImpl()
+			m.fsops = newTestFsOps()
+			var n int64
+			b.SetParallelism(devices)
+			b.RunParallel(func(pb *testing.PB) {
+				i := atomic.AddInt64(&n, 1)
+				dev := fmt.Sprintf("/dev/device%d", i)
+				path := fmt.Sprintf("/mnt/device%d", i)
+				for pb.Next() {
+					if err := m.mount(0, dev, dev, path, "ext4", 0, "", 0); err != nil {
+						b.Fatal(err)
+					}
+					if err := m.Unmount(dev, path, 0, 0, nil); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package mount
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// tableSnapshot is the serialized form of the mount table.
+type tableSnapshot struct {
+	Mounts map[string]*deviceSnapshot `json:"mounts"`
+	Paths  PathMap                    `json:"paths,omitempty"`
+}
+
+// deviceSnapshot is the serialized form of an Info.
+type deviceSnapshot struct {
+	Device      string                `json:"device"`
+	Minor       int                   `json:"minor,omitempty"`
+	Fs          string                `json:"fs,omitempty"`
+	LoopDevice  string                `json:"loopDevice,omitempty"`
+	CryptName   string                `json:"cryptName,omitempty"`
+	SourceID    string                `json:"sourceID,omitempty"`
+	Mountpoints []*mountpointSnapshot `json:"mountpoints"`
+}
+
+// mountpointSnapshot is the serialized form of a PathInfo.
+type mountpointSnapshot struct {
+	Root  string  `json:"root,omitempty"`
+	Path  string  `json:"path"`
+	Flags uintptr `json:"flags,omitempty"`
+	Data  string  `json:"data,omitempty"`
+	// MountedAt is a pointer so that a zero time is left out.
+	MountedAt *time.Time `json:"mountedAt,omitempty"`
+	ReadOnly  bool       `json:"readOnly,omitempty"`
+}
+
+// Save writes the mount table to w as JSON, to be restored with Restore.
+func (m *Mounter) Save(w io.Writer) error {
+	m.RLock()
+	mounts, paths := m.tableLocked()
+	m.RUnlock()
+	s := tableSnapshot{
+		Mounts: make(map[string]*deviceSnapshot, len(mounts)),
+		Paths:  paths,
+	}
+	for source, info := range mounts {
+		info.Lock()
+		d := &deviceSnapshot{
+			Device:      info.Device,
+			Minor:       info.Minor,
+			Fs:          info.Fs,
+			LoopDevice:  info.LoopDevice,
+			CryptName:   info.CryptName,
+			SourceID:    info.SourceID,
+			Mountpoints: make([]*mountpointSnapshot, 0, len(info.Mountpoint)),
+		}
+		for _, p := range info.Mountpoint {
+			d.Mountpoints = append(d.Mountpoints, &mountpointSnapshot{
+				Root:     p.Root,
+				Path:     p.Path,
+				Flags:    p.Flags,
+				Data:     p.Data,
+				ReadOnly: p.ReadOnly,
+			})
+			if !p.MountedAt.IsZero() {
+				mountedAt := p.MountedAt
+				d.Mountpoints[len(d.Mountpoints)-1].MountedAt = &mountedAt
+			}
+		}
+		info.Unlock()
+		s.Mounts[source] = d
+	}
+	return json.NewEncoder(w).Encode(&s)
+}
+
+// Restore replaces the mount table with one written by Save. No mounts are
+// made, but mountpoints the kernel mount table no longer has are dropped.
+func (m *Mounter) Restore(r io.Reader) error {
+	var s tableSnapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return err
+	}
+	infos, err := mountTable()
+	if err != nil {
+		return err
+	}
+	mounted := make(map[string]bool, len(infos))
+	for _, v := range infos {
+		mounted[normalizeMountPath(v.Mountpoint)] = true
+	}
+
+	mounts := make(DeviceMap, len(s.Mounts))
+	paths := make(PathMap)
+	for source, d := range s.Mounts {
+		info := &Info{
+			Device:     d.Device,
+			Minor:      d.Minor,
+			Fs:         d.Fs,
+			LoopDevice: d.LoopDevice,
+			CryptName:  d.CryptName,
+			SourceID:   d.SourceID,
+			Mountpoint: make([]*PathInfo, 0, len(d.Mountpoints)),
+		}
+		for _, p := range d.Mountpoints {
+			if !mounted[p.Path] {
+				m.logger.Infof("Dropping restored mountpoint %q of %q, not in the mount table",
+					p.Path, source)
+				continue
+			}
+			pi := &PathInfo{
+				Root:     p.Root,
+				Path:     p.Path,
+				Flags:    p.Flags,
+				Data:     p.Data,
+				ReadOnly: p.ReadOnly,
+			}
+			if p.MountedAt != nil {
+				pi.MountedAt = *p.MountedAt
+			}
+			info.Mountpoint = append(info.Mountpoint, pi)
+		}
+		if len(info.Mountpoint) > 0 {
+			mounts[source] = info
+		}
+	}
+	for path, source := range s.Paths {
+		if mounted[normalizeMountPath(path)] {
+			paths[path] = source
+		}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.setTableLocked(mounts, paths)
+	return nil
+}
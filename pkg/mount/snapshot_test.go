@@ -0,0 +1,76 @@
+package mount
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/mount"
+	"github.com/stretchr/testify/require"
+)
+
+// setTestKernelMounts replaces the mount table with mountpoints.
+func setTestKernelMounts(t *testing.T, mountpoints ...string) {
+	orig := mountTable
+	mountTable = func() ([]*mount.Info, error) {
+		infos := make([]*mount.Info, 0, len(mountpoints))
+		for _, mp := range mountpoints {
+			infos = append(infos, &mount.Info{Mountpoint: mp})
+		}
+		return infos, nil
+	}
+	t.Cleanup(func() { mountTable = orig })
+}
+
+func TestSaveRestore(t *testing.T) {
+	m := newTestTable()
+	m.testInfo("dev1").Minor = 3
+	m.testInfo("dev1").Mountpoint[0].Flags = 1
+	m.testInfo("dev1").Mountpoint[0].Data = "discard"
+	m.testInfo("dev2").LoopDevice = "/dev/loop4"
+	m.testInfo("dev2").SourceID = "UUID=1234"
+	mountedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.testInfo("dev2").Mountpoint[0].MountedAt = mountedAt
+	m.setTestPath("/mnt/dev1/a", "dev1")
+
+	var buf bytes.Buffer
+	require.NoError(t, m.Save(&buf))
+
+	setTestKernelMounts(t, "/", "/mnt/dev1/a", "/mnt/dev1/b", "/mnt/dev2")
+	restored := &Mounter{}
+	restored.setOptions(nil)
+	require.NoError(t, restored.Restore(bytes.NewReader(buf.Bytes())))
+
+	require.Len(t, restored.devices(), 2)
+	dev1 := restored.testInfo("dev1")
+	require.Equal(t, 3, dev1.Minor)
+	require.Equal(t, "ext4", dev1.Fs)
+	require.Equal(t, []*PathInfo{
+		{Root: "/", Path: "/mnt/dev1/a", Flags: 1, Data: "discard"},
+		{Root: "/sub", Path: "/mnt/dev1/b"},
+	}, dev1.Mountpoint)
+	require.Equal(t, "/dev/loop4", restored.testInfo("dev2").LoopDevice)
+	require.Equal(t, "UUID=1234", restored.testInfo("dev2").SourceID)
+	require.True(t, mountedAt.Equal(restored.testInfo("dev2").Mountpoint[0].MountedAt))
+	require.Equal(t, PathMap{"/mnt/dev1/a": "dev1"}, restored.testPaths())
+	require.Equal(t, 2, restored.HasMounts("dev1"))
+}
+
+func TestRestoreReconcile(t *testing.T) {
+	m := newTestTable()
+	m.setTestPath("/mnt/dev1/a", "dev1")
+	var buf bytes.Buffer
+	require.NoError(t, m.Save(&buf))
+
+	// The kernel lost /mnt/dev1/a and all of dev2 since the table was saved.
+	setTestKernelMounts(t, "/", "/mnt/dev1/b")
+	require.NoError(t, m.Restore(&buf))
+
+	require.Equal(t, []string{"/mnt/dev1/b"}, m.Mounts("dev1"))
+	require.Equal(t, 0, m.HasMounts("dev2"))
+	_, ok := m.lookup("dev2")
+	require.False(t, ok, "Expected dev2 to be dropped")
+	require.Empty(t, m.testPaths())
+	_, ok = m.HasTarget("/mnt/dev1/a")
+	require.False(t, ok)
+}
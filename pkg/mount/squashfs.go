@@ -0,0 +1,10 @@
+package mount
+
+const squashfsType = "squashfs"
+
+// SquashfsMount attaches the squashfs image at imagePath to a loop device and
+// mounts it read-only at target, as LoopMount does. The loop device is
+// detached by the Unmount of the last mountpoint.
+func (m *Mounter) SquashfsMount(imagePath, target string, timeout int) error {
+	return m.LoopMount(imagePath, target, squashfsType, true, timeout)
+}
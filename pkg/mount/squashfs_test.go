@@ -0,0 +1,41 @@
+package mount
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSquashfsMount(t *testing.T) {
+	loop := newTestLoopDevices()
+	m, mi := newTestMounter(t, withLoopDevices(loop))
+	dir := t.TempDir()
+	image := filepath.Join(dir, "content.sqfs")
+	target := filepath.Join(dir, "mnt")
+	require.NoError(t, os.Mkdir(target, 0755))
+
+	require.NoError(t, m.SquashfsMount(image, target, 0))
+	call := mi.lastCall()
+	require.Equal(t, "/dev/loop0", call.source)
+	require.Equal(t, squashfsType, call.fstype)
+	require.Equal(t, uintptr(msRdonly), call.flags)
+	require.Equal(t, []string{target}, m.Mounts(image))
+
+	require.NoError(t, m.Unmount(image, target, 0, 0, nil))
+	require.Equal(t, []string{"/dev/loop0"}, loop.detached)
+	require.Equal(t, 0, m.HasMounts(image))
+}
+
+func TestSquashfsMountWritable(t *testing.T) {
+	loop := newTestLoopDevices()
+	m, mi := newTestMounter(t, withLoopDevices(loop))
+	dir := t.TempDir()
+
+	err := m.LoopMount(filepath.Join(dir, "content.sqfs"), dir, squashfsType, false, 0)
+	require.True(t, errors.Is(err, ErrEinval), "Unexpected error %v", err)
+	require.Empty(t, mi.calls)
+	require.Empty(t, loop.attached)
+}
@@ -0,0 +1,93 @@
+package mount
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SubpathMount bind mounts subpath of the volume mounted at volumeRoot onto
+// target. Symlinks in subpath are evaluated and the result must stay within
+// volumeRoot, otherwise ErrSubpathEscape is returned and nothing is mounted.
+// The result is then opened without following symlinks and the open file is
+// mounted, so that a symlink swapped in meanwhile fails the mount.
+// If readOnly is set, the bind mount is remounted read-only. The mount is
+// tracked under the resolved source path.
+func (m *Mounter) SubpathMount(volumeRoot, subpath, target string, readOnly bool, timeout int) error {
+	if msBind == 0 {
+		return ErrUnsupported
+	}
+	root, source, err := resolveSubpath(volumeRoot, subpath)
+	if err != nil {
+		return err
+	}
+	// Mount the file opened without following symlinks rather than the
+	// path, whose elements may be swapped for symlinks meanwhile.
+	f, err := openSubpath(root, source)
+	if err != nil {
+		return err
+	}
+	err = m.mount(0, fmt.Sprintf("/proc/self/fd/%d", f.Fd()), source, target, "", msBind, "", timeout)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if !readOnly {
+		return nil
+	}
+	// MS_RDONLY is ignored when creating a bind mount and needs a remount.
+	if err := m.mount(0, source, source, target, "", msBind|msRemount|msRdonly, "", timeout); err != nil {
+		if e := m.Unmount(source, target, 0, timeout, nil); e != nil {
+			m.logger.Warnf("Failed to unmount %s after read-only remount failure: %v", target, e)
+		}
+		return err
+	}
+	return nil
+}
+
+// resolveSubpath returns volumeRoot and the path subpath points to within it
+// with all symlinks evaluated. It returns ErrSubpathEscape if subpath is
+// absolute or has a ".." element, or if the result is not volumeRoot or one
+// of its descendants.
+func resolveSubpath(volumeRoot, subpath string) (string, string, error) {
+	if filepath.IsAbs(subpath) {
+		return "", "", ErrSubpathEscape
+	}
+	// Joining would clean ".." lexically, which differs from how the kernel
+	// walks ".." after a symlink.
+	for _, elem := range strings.Split(filepath.ToSlash(subpath), "/") {
+		if elem == ".." {
+			return "", "", ErrSubpathEscape
+		}
+	}
+	root, err := filepath.EvalSymlinks(volumeRoot)
+	if err != nil {
+		return "", "", err
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return "", "", err
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, subpath))
+	if err != nil {
+		return "", "", err
+	}
+	resolved, err = filepath.Abs(resolved)
+	if err != nil {
+		return "", "", err
+	}
+	if !isWithin(root, resolved) {
+		return "", "", ErrSubpathEscape
+	}
+	return root, resolved, nil
+}
+
+// isWithin returns true if path is root or is below root. Both paths must be
+// absolute and clean.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
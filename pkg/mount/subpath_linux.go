@@ -0,0 +1,67 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// openSubpath opens path, a resolved subpath of root, by walking its elements
+// from root with O_NOFOLLOW, so that an element replaced by a symlink after
+// path was resolved is not followed. ErrSubpathEscape is returned if one
+// was. The file, opened with O_PATH, can be bind mounted from
+// /proc/self/fd/<fd> while it is open.
+func openSubpath(root, path string) (*os.File, error) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := unix.Open(root, unix.O_PATH|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, subpathOpenError(root, err)
+	}
+	current := root
+	if rel != "." {
+		elems := strings.Split(rel, string(filepath.Separator))
+		for i, elem := range elems {
+			flags := unix.O_PATH | unix.O_NOFOLLOW | unix.O_CLOEXEC
+			if i < len(elems)-1 {
+				flags |= unix.O_DIRECTORY
+			}
+			current = filepath.Join(current, elem)
+			next, err := unix.Openat(fd, elem, flags, 0)
+			unix.Close(fd)
+			if err != nil {
+				return nil, subpathOpenError(current, err)
+			}
+			fd = next
+		}
+	}
+	// O_PATH with O_NOFOLLOW opens a symlink itself as the last element.
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		unix.Close(fd)
+		return nil, &os.PathError{Op: "fstat", Path: current, Err: err}
+	}
+	if st.Mode&unix.S_IFMT == unix.S_IFLNK {
+		unix.Close(fd)
+		return nil, ErrSubpathEscape
+	}
+	return os.NewFile(uintptr(fd), path), nil
+}
+
+// subpathOpenError returns ErrSubpathEscape if opening path failed as it is
+// a symlink, and the error otherwise.
+func subpathOpenError(path string, err error) error {
+	if err == unix.ELOOP || err == unix.ENOTDIR {
+		if fi, e := os.Lstat(path); e == nil && fi.Mode()&os.ModeSymlink != 0 {
+			return ErrSubpathEscape
+		}
+	}
+	return &os.PathError{Op: "open", Path: path, Err: err}
+}
@@ -0,0 +1,123 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestVolume returns a volume root containing data/, a symlink inside the
+// volume and symlinks escaping it, along with a directory outside the volume.
+func newTestVolume(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "vol")
+	outside := filepath.Join(dir, "outside")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "data", "sub"), 0755))
+	require.NoError(t, os.Mkdir(outside, 0755))
+	require.NoError(t, os.Symlink("data/sub", filepath.Join(root, "inside")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "abs")))
+	require.NoError(t, os.Symlink("../outside", filepath.Join(root, "rel")))
+	require.NoError(t, os.Symlink("../../outside", filepath.Join(root, "data", "deep")))
+	return root, outside
+}
+
+func TestResolveSubpath(t *testing.T) {
+	root, _ := newTestVolume(t)
+
+	for subpath, expected := range map[string]string{
+		"":          root,
+		".":         root,
+		"data":      filepath.Join(root, "data"),
+		"data/sub/": filepath.Join(root, "data", "sub"),
+		"inside":    filepath.Join(root, "data", "sub"),
+	} {
+		_, resolved, err := resolveSubpath(root, subpath)
+		require.NoError(t, err, subpath)
+		require.Equal(t, expected, resolved, subpath)
+	}
+
+	for _, subpath := range []string{
+		"/etc",
+		"..",
+		"../outside",
+		"data/../../outside",
+		"abs",
+		"rel",
+		"data/deep",
+		"inside/../../../outside",
+		"data/../data",
+	} {
+		_, _, err := resolveSubpath(root, subpath)
+		require.Equal(t, ErrSubpathEscape, err, subpath)
+	}
+
+	_, _, err := resolveSubpath(root, "missing")
+	require.True(t, os.IsNotExist(err), "Unexpected error %v", err)
+}
+
+func TestSubpathMount(t *testing.T) {
+	m, mi := newTestMounter(t)
+	root, _ := newTestVolume(t)
+	target := filepath.Join(t.TempDir(), "target")
+	require.NoError(t, os.Mkdir(target, 0755))
+	source := filepath.Join(root, "data", "sub")
+
+	require.NoError(t, m.SubpathMount(root, "inside", target, true, 0))
+	require.Len(t, mi.calls, 2)
+	require.True(t, strings.HasPrefix(mi.calls[0].source, "/proc/self/fd/"),
+		"Expected the opened subpath to be mounted, got %s", mi.calls[0].source)
+	require.Equal(t, target, mi.calls[0].target)
+	require.Equal(t, uintptr(msBind), mi.calls[0].flags)
+	require.Equal(t, uintptr(msBind|msRemount|msRdonly), mi.calls[1].flags)
+	dev, ok := m.HasTarget(target)
+	require.True(t, ok)
+	require.Equal(t, source, dev)
+	require.NoError(t, m.Unmount(source, target, 0, 0, nil))
+
+	require.Equal(t, ErrSubpathEscape, m.SubpathMount(root, "abs", target, false, 0))
+	require.Len(t, mi.calls, 2, "Expected no mount for an escaping subpath")
+
+	mi.mountErr = errors.New("denied")
+	require.Error(t, m.SubpathMount(root, "data", target, false, 0))
+	require.Equal(t, 0, m.HasMounts(filepath.Join(root, "data")))
+}
+
+func TestOpenSubpath(t *testing.T) {
+	root, outside := newTestVolume(t)
+	source := filepath.Join(root, "data", "sub")
+	f, err := openSubpath(root, source)
+	require.NoError(t, err)
+	target, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", f.Fd()))
+	require.NoError(t, err)
+	require.Equal(t, source, target)
+	require.NoError(t, f.Close())
+
+	f, err = openSubpath(root, root)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// An element swapped for a symlink after resolution is not followed.
+	_, resolved, err := resolveSubpath(root, "data/sub")
+	require.NoError(t, err)
+	require.NoError(t, os.Rename(filepath.Join(root, "data"), filepath.Join(root, "moved")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "data")))
+	require.NoError(t, os.Mkdir(filepath.Join(outside, "sub"), 0755))
+	_, err = openSubpath(root, resolved)
+	require.Equal(t, ErrSubpathEscape, err)
+
+	// So is a last element swapped for a symlink.
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "moved", "link")))
+	_, err = openSubpath(root, filepath.Join(root, "moved", "link"))
+	require.Equal(t, ErrSubpathEscape, err)
+
+	_, err = openSubpath(root, filepath.Join(root, "missing"))
+	require.True(t, os.IsNotExist(err), "Unexpected error %v", err)
+}
@@ -0,0 +1,392 @@
+package mount
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// testMountCall records the arguments of a testMountImpl.Mount call.
+type testMountCall struct {
+	source string
+	target string
+	fstype string
+	flags  uintptr
+	data   string
+}
+
+// testMountImpl is a MountImpl that records calls without touching the kernel.
+type testMountImpl struct {
+	sync.Mutex
+	mounted    map[string]string
+	mountErr   error
+	unmountErr error
+	// targetErrs fails mounts of specific targets.
+	targetErrs map[string]error
+	calls      []testMountCall
+	unmounted  []string
+}
+
+func newTestMountImpl() *testMountImpl {
+	return &testMountImpl{
+		mounted:    make(map[string]string),
+		targetErrs: make(map[string]error),
+	}
+}
+
+func (f *testMountImpl) Mount(source, target, fstype string, flags uintptr, data string, timeout int) error {
+	f.Lock()
+	defer f.Unlock()
+	f.calls = append(f.calls, testMountCall{
+		source: source,
+		target: target,
+		fstype: fstype,
+		flags:  flags,
+		data:   data,
+	})
+	if f.mountErr != nil {
+		return f.mountErr
+	}
+	if err := f.targetErrs[target]; err != nil {
+		return err
+	}
+	f.mounted[target] = source
+	return nil
+}
+
+func (f *testMountImpl) Unmount(target string, flags int, timeout int) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.unmountErr != nil {
+		return f.unmountErr
+	}
+	delete(f.mounted, target)
+	f.unmounted = append(f.unmounted, target)
+	return nil
+}
+
+func (f *testMountImpl) lastCall() testMountCall {
+	f.Lock()
+	defer f.Unlock()
+	if len(f.calls) == 0 {
+		return testMountCall{}
+	}
+	return f.calls[len(f.calls)-1]
+}
+
+// testFsOps is an fsOps that records operations without changing attributes.
+type testFsOps struct {
+	sync.Mutex
+	immutable map[string]bool
+	ops       []string
+	// probeErr is returned by ProbeWrite.
+	probeErr error
+}
+
+func newTestFsOps() *testFsOps {
+	return &testFsOps{immutable: make(map[string]bool)}
+}
+
+func (f *testFsOps) record(op, path string) {
+	f.ops = append(f.ops, op+" "+path)
+}
+
+func (f *testFsOps) IsImmutable(path string) bool {
+	f.Lock()
+	defer f.Unlock()
+	return f.immutable[path]
+}
+
+func (f *testFsOps) AddImmutable(path string) error {
+	f.Lock()
+	defer f.Unlock()
+	f.record("+i", path)
+	f.immutable[path] = true
+	return nil
+}
+
+func (f *testFsOps) RemoveImmutable(path string) error {
+	f.Lock()
+	defer f.Unlock()
+	f.record("-i", path)
+	delete(f.immutable, path)
+	return nil
+}
+
+func (f *testFsOps) Chown(path string, uid, gid int) error {
+	f.Lock()
+	defer f.Unlock()
+	f.record(fmt.Sprintf("chown %d:%d", uid, gid), path)
+	return nil
+}
+
+func (f *testFsOps) Chmod(path string, mode os.FileMode) error {
+	f.Lock()
+	defer f.Unlock()
+	f.record(fmt.Sprintf("chmod %o", mode), path)
+	return nil
+}
+
+func (f *testFsOps) ProbeWrite(dir string) error {
+	f.Lock()
+	defer f.Unlock()
+	f.record("probe", dir)
+	return f.probeErr
+}
+
+func (f *testFsOps) Relabel(path, label string) error {
+	f.Lock()
+	defer f.Unlock()
+	f.record("relabel "+label, path)
+	return nil
+}
+
+// newTestMounter returns a Mounter backed by fakes that does not touch the
+// kernel or file attributes.
+func newTestMounter(t *testing.T, opts ...MounterOption) (*deviceMounter, *testMountImpl) {
+	mi := newTestMountImpl()
+	opts = append([]MounterOption{withFsOps(newTestFsOps()), withDeviceCheck(nil)}, opts...)
+	m, err := NewDeviceMounter(nil, mi, nil, "", opts...)
+	require.NoError(t, err, "Failed to create test mounter")
+	return m, mi
+}
+
+// logHook captures log entries emitted through an injected logger.
+type logHook struct {
+	sync.Mutex
+	entries []*logrus.Entry
+}
+
+func (h *logHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *logHook) Fire(e *logrus.Entry) error {
+	h.Lock()
+	defer h.Unlock()
+	h.entries = append(h.entries, e)
+	return nil
+}
+
+func (h *logHook) find(msg string) *logrus.Entry {
+	h.Lock()
+	defer h.Unlock()
+	for _, e := range h.entries {
+		if e.Message == msg {
+			return e
+		}
+	}
+	return nil
+}
+
+func newTestTable() *Mounter {
+	m := &Mounter{}
+	m.setOptions(nil)
+	m.setTestInfo("dev1", &Info{
+		Device: "dev1",
+		Fs:     "ext4",
+		Mountpoint: []*PathInfo{
+			{Root: "/", Path: "/mnt/dev1/a"},
+			{Root: "/sub", Path: "/mnt/dev1/b"},
+		},
+	})
+	m.setTestInfo("dev2", &Info{
+		Device:     "dev2",
+		Fs:         "xfs",
+		Mountpoint: []*PathInfo{{Path: "/mnt/dev2"}},
+	})
+	m.reindex()
+	return m
+}
+
+func TestTableLookups(t *testing.T) {
+	m := newTestTable()
+
+	require.Len(t, m.Inspect("dev1"), 2)
+	require.Nil(t, m.Inspect("dev3"))
+	require.ElementsMatch(t, []string{"/mnt/dev1/a", "/mnt/dev1/b"}, m.Mounts("dev1"))
+	require.Nil(t, m.Mounts("dev3"))
+	require.ElementsMatch(t, []string{"dev1", "dev2"}, m.GetSourcePaths())
+	require.Equal(t, 2, m.HasMounts("dev1"))
+	require.Equal(t, 0, m.HasMounts("dev3"))
+
+	dev, ok := m.HasTarget("/mnt/dev2")
+	require.True(t, ok)
+	require.Equal(t, "dev2", dev)
+	_, ok = m.HasTarget("/mnt/dev3")
+	require.False(t, ok)
+
+	exists, err := m.Exists("dev1", "/mnt/dev1/b")
+	require.NoError(t, err)
+	require.True(t, exists)
+	exists, err = m.Exists("dev1", "/mnt/dev2")
+	require.NoError(t, err)
+	require.False(t, exists)
+	_, err = m.Exists("dev3", "/mnt/dev3")
+	require.Equal(t, ErrEnoent, err)
+
+	root, err := m.GetRootPath("/mnt/dev1/b")
+	require.NoError(t, err)
+	require.Equal(t, "/sub", root)
+	_, err = m.GetRootPath("/mnt/dev3")
+	require.Equal(t, ErrEnoent, err)
+
+	src, err := m.GetSourcePath("/mnt/dev1/a")
+	require.NoError(t, err)
+	require.Equal(t, "dev1", src)
+	_, err = m.GetSourcePath("/mnt/dev3")
+	require.Equal(t, ErrEnoent, err)
+}
+
+func TestFindMountForPath(t *testing.T) {
+	m := newTestTable()
+	m.setTestInfo("dev3", &Info{
+		Device:     "dev3",
+		Mountpoint: []*PathInfo{{Path: "/mnt/dev1/a/nested"}},
+	})
+	m.reindex()
+
+	for file, want := range map[string][2]string{
+		"/mnt/dev1/a/sub/file":       {"dev1", "/mnt/dev1/a"},
+		"/mnt/dev1/a":                {"dev1", "/mnt/dev1/a"},
+		"/mnt/dev1/a/nested/file":    {"dev3", "/mnt/dev1/a/nested"},
+		"/mnt/dev1/a/nested/":        {"dev3", "/mnt/dev1/a/nested"},
+		"/mnt/dev1/a/nestedfile":     {"dev1", "/mnt/dev1/a"},
+		"/mnt/dev1/a/x/../nested/ab": {"dev3", "/mnt/dev1/a/nested"},
+		"/mnt/dev2/file":             {"dev2", "/mnt/dev2"},
+	} {
+		source, mountpoint, err := m.FindMountForPath(file)
+		require.NoError(t, err, file)
+		require.Equal(t, want[0], source, file)
+		require.Equal(t, want[1], mountpoint, file)
+	}
+
+	for _, file := range []string{"", "/mnt", "/mnt/dev1/c/file", "relative/path"} {
+		_, _, err := m.FindMountForPath(file)
+		require.Equal(t, ErrEnoent, err, file)
+	}
+}
+
+func TestTotalMounts(t *testing.T) {
+	m, _ := newTestMounter(t)
+	require.Equal(t, 0, m.TotalMounts())
+
+	for _, mnt := range []struct{ dev, path string }{
+		{"/dev/total1", "/mnt/total1a"},
+		{"/dev/total1", "/mnt/total1b"},
+		{"/dev/total2", "/mnt/total2"},
+		{"/dev/total3", "/mnt/total3a"},
+		{"/dev/total3", "/mnt/total3b"},
+		{"/dev/total3", "/mnt/total3c"},
+	} {
+		require.NoError(t, m.Mount(0, mnt.dev, mnt.path, "ext4", 0, "", 0, nil))
+	}
+	require.Equal(t, 6, m.TotalMounts())
+
+	require.NoError(t, m.Unmount("/dev/total2", "/mnt/total2", 0, 0, nil))
+	require.Equal(t, 5, m.TotalMounts())
+}
+
+func TestGetMinor(t *testing.T) {
+	m := newTestTable()
+	m.testInfo("dev1").Minor = 7
+
+	minor, err := m.GetMinor("dev1")
+	require.NoError(t, err)
+	require.Equal(t, 7, minor)
+	minor, err = m.GetMinor("dev2")
+	require.NoError(t, err)
+	require.Equal(t, 0, minor)
+	_, err = m.GetMinor("dev3")
+	require.Equal(t, ErrEnoent, err)
+}
+
+func TestGetSourcePathsFiltered(t *testing.T) {
+	m := newTestTable()
+	m.setTestInfo("server:/export", &Info{
+		Device:     "server:/export",
+		Fs:         "nfs",
+		Mountpoint: []*PathInfo{{Path: "/mnt/nfs"}},
+	})
+	m.setTestInfo("dev3", &Info{Device: "dev3", Fs: "ext4"})
+
+	require.ElementsMatch(t, []string{"dev1", "dev2", "dev3", "server:/export"},
+		m.GetSourcePathsFiltered(SourceFilter{}))
+	require.ElementsMatch(t, []string{"dev1", "dev3"},
+		m.GetSourcePathsFiltered(SourceFilter{Fs: "ext4"}))
+	require.ElementsMatch(t, []string{"server:/export"},
+		m.GetSourcePathsFiltered(SourceFilter{Fs: "nfs"}))
+	require.ElementsMatch(t, []string{"dev1", "dev2", "dev3"},
+		m.GetSourcePathsFiltered(SourceFilter{DevicePrefix: "dev"}))
+	require.ElementsMatch(t, []string{"dev1"},
+		m.GetSourcePathsFiltered(SourceFilter{Fs: "ext4", MinMounts: 1}))
+	require.ElementsMatch(t, []string{"dev1"},
+		m.GetSourcePathsFiltered(SourceFilter{MinMounts: 2}))
+	require.Empty(t, m.GetSourcePathsFiltered(SourceFilter{Fs: "nfs", DevicePrefix: "dev"}))
+}
+
+func TestTableReload(t *testing.T) {
+	m := newTestTable()
+	kept := m.testInfo("dev1").Mountpoint[0]
+
+	require.NoError(t, m.reload("dev1", &Info{
+		Device: "dev1",
+		Fs:     "ext4",
+		Mountpoint: []*PathInfo{
+			{Root: "/", Path: "/mnt/dev1/a"},
+			{Path: "/mnt/dev1/c"},
+		},
+	}))
+	require.ElementsMatch(t, []string{"/mnt/dev1/a", "/mnt/dev1/c"}, m.Mounts("dev1"))
+	require.True(t, kept == m.testInfo("dev1").Mountpoint[0], "Expected the existing entry to be preserved")
+
+	require.NoError(t, m.reload("dev2", nil))
+	require.Equal(t, 0, m.HasMounts("dev2"))
+
+	m.testInfo("dev1").Mountpoint = nil
+	m.maybeRemoveDevice("dev1")
+	require.NotContains(t, m.GetSourcePaths(), "dev1")
+}
+
+func TestRemoveDeviceSweepsOrphanedPaths(t *testing.T) {
+	hook := &logHook{}
+	logger := logrus.New()
+	logger.AddHook(hook)
+	m := newTestTable()
+	m.logger = logger
+	m.setTestPath("/mnt/dev2", "dev2")
+	m.setTestPath("/mnt/orphan", "dev1")
+	m.setTestPath("/mnt/other", "dev3")
+
+	m.testInfo("dev1").Mountpoint = nil
+	require.NotNil(t, m.maybeRemoveDevice("dev1"))
+	require.NotContains(t, m.testPaths(), "/mnt/orphan", "Expected the orphaned path to be swept")
+	require.Equal(t, "dev3", m.testPaths()["/mnt/other"])
+	e := hook.find("Removing orphaned path /mnt/orphan of the removed device")
+	require.NotNil(t, e, "Expected a warning for the orphaned path")
+	require.Equal(t, logrus.WarnLevel, e.Level)
+
+	// Removing the last mountpoint of a device also sweeps its paths.
+	m.setTestPath("/mnt/orphan", "dev2")
+	info := m.testInfo("dev2")
+	require.NotNil(t, m.removeMountpoint("dev2", info, "/mnt/dev2"))
+	require.NotContains(t, m.testPaths(), "/mnt/dev2")
+	require.NotContains(t, m.testPaths(), "/mnt/orphan")
+	require.Equal(t, map[string]string{"/mnt/other": "dev3"}, map[string]string(m.testPaths()))
+}
+
+func TestStringAndDump(t *testing.T) {
+	m := newTestTable()
+	m.testInfo("dev1").Minor = 3
+	m.testInfo("dev1").Mountpoint[1].ReadOnly = true
+	require.Equal(t, "Mounter with 2 devices and 3 mountpoints", m.String())
+	require.Equal(t, "dev1: device=dev1 fs=ext4 minor=3\n"+
+		"\t/mnt/dev1/a root=/ options=rw readonly=false\n"+
+		"\t/mnt/dev1/b root=/sub options=rw readonly=true\n"+
+		"dev2: device=dev2 fs=xfs minor=0\n"+
+		"\t/mnt/dev2 root= options=rw readonly=false\n", m.Dump())
+}
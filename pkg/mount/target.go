@@ -0,0 +1,92 @@
+package mount
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// CreateTarget describes how a missing mountpoint directory is created.
+type CreateTarget struct {
+	// Mode is the permission of the created directories.
+	Mode os.FileMode
+	// UID and GID own the mountpoint directory if set.
+	UID *int
+	GID *int
+}
+
+// WithSymlinkTargets allows mounting onto a path that is a symlink, which is
+// then followed. It does not apply to Mounters with allowed directories.
+func WithSymlinkTargets() MounterOption {
+	return func(m *Mounter) {
+		m.allowSymlinkTargets = true
+	}
+}
+
+// checkTargetSymlink returns ErrTargetIsSymlink if the last element of path
+// is a symlink. The mount fails later if path does not exist.
+func checkTargetSymlink(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return nil
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return ErrTargetIsSymlink
+	}
+	return nil
+}
+
+// createTarget creates path as described by t if it does not exist. It
+// returns the topmost directory it created, or an empty string if path
+// already existed.
+func createTarget(path string, t *CreateTarget) (string, error) {
+	if _, err := os.Lstat(path); err == nil || !os.IsNotExist(err) {
+		return "", err
+	}
+	created := filepath.Clean(path)
+	for {
+		parent := filepath.Dir(created)
+		if parent == created {
+			break
+		}
+		if _, err := os.Lstat(parent); err == nil {
+			break
+		}
+		created = parent
+	}
+	if err := os.MkdirAll(path, t.Mode); err != nil {
+		return "", err
+	}
+	// MkdirAll is subject to the umask.
+	if err := os.Chmod(path, t.Mode); err != nil {
+		removeCreated(path, created)
+		return "", err
+	}
+	if t.UID != nil || t.GID != nil {
+		uid, gid := -1, -1
+		if t.UID != nil {
+			uid = *t.UID
+		}
+		if t.GID != nil {
+			gid = *t.GID
+		}
+		if err := os.Lchown(path, uid, gid); err != nil {
+			removeCreated(path, created)
+			return "", err
+		}
+	}
+	return created, nil
+}
+
+// removeCreated removes path and its parents up to created, as created by
+// createTarget. Directories that are no longer empty are left in place.
+func removeCreated(path, created string) error {
+	created = filepath.Clean(created)
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+		if dir == created || filepath.Dir(dir) == dir {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,169 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountCreateTarget(t *testing.T) {
+	m, _ := newTestMounter(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a", "b")
+	uid, gid := 1234, 5678
+
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device:       "/dev/create",
+		Path:         path,
+		Fs:           "ext4",
+		CreateTarget: &CreateTarget{Mode: 0750, UID: &uid, GID: &gid},
+	}))
+	require.Equal(t, 1, m.HasMounts("/dev/create"))
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.True(t, fi.IsDir())
+	require.Equal(t, os.FileMode(0750), fi.Mode().Perm())
+	st := fi.Sys().(*syscall.Stat_t)
+	require.Equal(t, uint32(uid), st.Uid)
+	require.Equal(t, uint32(gid), st.Gid)
+}
+
+func TestMountCreateTargetExisting(t *testing.T) {
+	m, mi := newTestMounter(t)
+	path := t.TempDir()
+	require.NoError(t, os.Chmod(path, 0700))
+	mi.mountErr = errors.New("mount failed")
+
+	require.Error(t, m.MountWithOptions(MountOptions{
+		Device:       "/dev/create",
+		Path:         path,
+		Fs:           "ext4",
+		CreateTarget: &CreateTarget{Mode: 0755},
+	}))
+	fi, err := os.Stat(path)
+	require.NoError(t, err, "An existing mountpoint must not be removed")
+	require.Equal(t, os.FileMode(0700), fi.Mode().Perm(), "An existing mountpoint must not be changed")
+}
+
+func TestMountCreateTargetCleanup(t *testing.T) {
+	m, mi := newTestMounter(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a", "b")
+	mountErr := errors.New("mount failed")
+	mi.mountErr = mountErr
+
+	err := m.MountWithOptions(MountOptions{
+		Device:       "/dev/create",
+		Path:         path,
+		Fs:           "ext4",
+		CreateTarget: &CreateTarget{Mode: 0755},
+	})
+	require.True(t, errors.Is(err, mountErr))
+	_, err = os.Stat(filepath.Join(dir, "a"))
+	require.True(t, os.IsNotExist(err), "Expected the created directories to be removed")
+	_, err = os.Stat(dir)
+	require.NoError(t, err, "Expected the existing parent to be kept")
+}
+
+func TestMountCreateTargetValidatesFirst(t *testing.T) {
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed")
+	denied := filepath.Join(allowed, "denied")
+	outside := filepath.Join(dir, "outside")
+	require.NoError(t, os.MkdirAll(denied, 0755))
+	require.NoError(t, os.Mkdir(outside, 0755))
+	// escape is a symlinked parent pointing out of the allowed directory.
+	require.NoError(t, os.Symlink(outside, filepath.Join(allowed, "escape")))
+	// A directory created and removed again still changes the mtime of its
+	// parent, so the parents are checked to be untouched.
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	parents := []string{dir, allowed, denied, outside}
+	for _, p := range parents {
+		require.NoError(t, os.Chtimes(p, past, past))
+	}
+
+	m, mi := newTestMounter(t, WithDeniedDirs(denied))
+	m.AddAllowedDir(allowed)
+	for path, want := range map[string]error{
+		filepath.Join(dir, "other", "vol"):      ErrMountpathNotAllowed,
+		filepath.Join(allowed, "escape", "vol"): ErrMountpathNotAllowed,
+		filepath.Join(denied, "vol"):            ErrMountpathDenied,
+	} {
+		err := m.MountWithOptions(MountOptions{
+			Device:       "/dev/create",
+			Path:         path,
+			Fs:           "ext4",
+			CreateTarget: &CreateTarget{Mode: 0755},
+		})
+		require.True(t, errors.Is(err, want), "%s: got %v", path, err)
+	}
+	require.NoError(t, m.Close())
+	err := m.MountWithOptions(MountOptions{
+		Device:       "/dev/create",
+		Path:         filepath.Join(allowed, "closed"),
+		Fs:           "ext4",
+		CreateTarget: &CreateTarget{Mode: 0755},
+	})
+	require.Equal(t, ErrClosed, err)
+	require.Empty(t, mi.calls)
+	for _, p := range parents {
+		fi, err := os.Stat(p)
+		require.NoError(t, err)
+		require.True(t, fi.ModTime().Equal(past), "Expected nothing to be created in %s", p)
+	}
+}
+
+func TestMountSymlinkTarget(t *testing.T) {
+	dir := t.TempDir()
+	real := filepath.Join(dir, "real")
+	link := filepath.Join(dir, "link")
+	require.NoError(t, os.Mkdir(real, 0755))
+	require.NoError(t, os.Symlink(real, link))
+
+	m, mi := newTestMounter(t)
+	err := m.Mount(0, "/dev/sym", link, "ext4", 0, "", 0, nil)
+	require.Equal(t, ErrTargetIsSymlink, err)
+	err = m.Mount(0, "/dev/sym", link+"/", "ext4", 0, "", 0, nil)
+	require.Equal(t, ErrTargetIsSymlink, err, "A trailing slash must not follow the symlink")
+	require.Empty(t, mi.calls)
+
+	// A symlink in a parent is not the mountpoint itself.
+	require.NoError(t, os.Mkdir(filepath.Join(real, "sub"), 0755))
+	require.NoError(t, m.Mount(0, "/dev/sym", filepath.Join(link, "sub"), "ext4", 0, "", 0, nil))
+
+	m, mi = newTestMounter(t, WithSymlinkTargets())
+	require.NoError(t, m.Mount(0, "/dev/sym", link, "ext4", 0, "", 0, nil))
+	require.Equal(t, "/dev/sym", mi.mounted[link])
+}
+
+func TestMountSymlinkTargetAllowedDirs(t *testing.T) {
+	allowed := t.TempDir()
+	outside := t.TempDir()
+	link := filepath.Join(allowed, "escape")
+	require.NoError(t, os.Symlink(outside, link))
+
+	inside := filepath.Join(allowed, "inside")
+	require.NoError(t, os.Mkdir(filepath.Join(allowed, "real"), 0755))
+	require.NoError(t, os.Symlink(filepath.Join(allowed, "real"), inside))
+
+	m, mi := newTestMounter(t, WithSymlinkTargets())
+	m.allowedDirs = []string{allowed}
+	err := m.Mount(0, "/dev/sym", link, "ext4", 0, "", 0, nil)
+	require.Equal(t, ErrMountpathNotAllowed, err, "Symlinks must not bypass the allowed directories")
+	err = m.Mount(0, "/dev/sym", inside, "ext4", 0, "", 0, nil)
+	require.Equal(t, ErrTargetIsSymlink, err, "Symlinks must not be followed with allowed directories")
+	require.Empty(t, mi.calls)
+
+	target := filepath.Join(allowed, "target")
+	require.NoError(t, os.Mkdir(target, 0755))
+	require.NoError(t, m.Mount(0, "/dev/sym", target, "ext4", 0, "", 0, nil))
+}
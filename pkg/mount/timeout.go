@@ -0,0 +1,43 @@
+package mount
+
+// timeoutManager is a Manager passing a default timeout to the Manager it
+// wraps.
+type timeoutManager struct {
+	Manager
+	timeout int
+}
+
+// WithDefaultTimeout returns a view of m passing the timeout seconds to
+// Mount and Unmount when they are called with a timeout of 0. Other
+// timeouts are passed as is. The other methods, including RemoveMountPath
+// which takes no timeout, go to m unchanged.
+func WithDefaultTimeout(m Manager, seconds int) Manager {
+	return &timeoutManager{Manager: m, timeout: seconds}
+}
+
+// withDefault returns the default timeout if timeout is 0.
+func (t *timeoutManager) withDefault(timeout int) int {
+	if timeout == 0 {
+		return t.timeout
+	}
+	return timeout
+}
+
+// Mount mounts device with the default timeout if timeout is 0.
+func (t *timeoutManager) Mount(
+	minor int,
+	device string,
+	path string,
+	fs string,
+	flags uintptr,
+	data string,
+	timeout int,
+	opts map[string]string,
+) error {
+	return t.Manager.Mount(minor, device, path, fs, flags, data, t.withDefault(timeout), opts)
+}
+
+// Unmount unmounts source from path with the default timeout if timeout is 0.
+func (t *timeoutManager) Unmount(source, path string, flags int, timeout int, opts map[string]string) error {
+	return t.Manager.Unmount(source, path, flags, t.withDefault(timeout), opts)
+}
@@ -0,0 +1,45 @@
+package mount
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// timeoutRecorder is a Manager recording the timeouts it is called with.
+type timeoutRecorder struct {
+	Manager
+	timeouts []int
+}
+
+func (r *timeoutRecorder) Mount(minor int, device, path, fs string, flags uintptr, data string,
+	timeout int, opts map[string]string) error {
+	r.timeouts = append(r.timeouts, timeout)
+	return nil
+}
+
+func (r *timeoutRecorder) Unmount(source, path string, flags int, timeout int, opts map[string]string) error {
+	r.timeouts = append(r.timeouts, timeout)
+	return nil
+}
+
+func TestWithDefaultTimeout(t *testing.T) {
+	r := &timeoutRecorder{}
+	m := WithDefaultTimeout(r, 30)
+
+	require.NoError(t, m.Mount(0, "/dev/dev", "/mnt/dev", "ext4", 0, "", 0, nil))
+	require.NoError(t, m.Mount(0, "/dev/dev", "/mnt/dev", "ext4", 0, "", 5, nil))
+	require.NoError(t, m.Unmount("/dev/dev", "/mnt/dev", 0, 0, nil))
+	require.NoError(t, m.Unmount("/dev/dev", "/mnt/dev", 0, 7, nil))
+	require.Equal(t, []int{30, 5, 30, 7}, r.timeouts)
+}
+
+func TestWithDefaultTimeoutMounter(t *testing.T) {
+	dm, mi := newTestMounter(t)
+	m := WithDefaultTimeout(dm, 30)
+	require.NoError(t, m.Mount(0, "/dev/dev", "/mnt/dev", "ext4", 0, "", 0, nil))
+	require.Equal(t, []string{"/mnt/dev"}, m.Mounts("/dev/dev"))
+	require.NoError(t, m.Unmount("/dev/dev", "/mnt/dev", 0, 0, nil))
+	require.Equal(t, []string{"/mnt/dev"}, mi.unmounted)
+	require.Equal(t, 0, m.HasMounts("/dev/dev"))
+}
@@ -0,0 +1,50 @@
+package mount
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// TmpfsDevice is the device under which tmpfs mounts are tracked.
+	TmpfsDevice = "tmpfs"
+	tmpfsType   = "tmpfs"
+)
+
+// TmpfsMount mounts a tmpfs at path limited to sizeBytes and with the root
+// directory mode set to mode. A zero sizeBytes uses the kernel default size.
+// The mount is tracked under TmpfsDevice.
+func (m *Mounter) TmpfsMount(path string, sizeBytes int64, mode os.FileMode, timeout int) error {
+	data, err := tmpfsData(sizeBytes, mode)
+	if err != nil {
+		return err
+	}
+	return m.mount(0, "", TmpfsDevice, path, tmpfsType, 0, data, timeout)
+}
+
+// tmpfsData returns the tmpfs mount data for the given size and mode.
+func tmpfsData(sizeBytes int64, mode os.FileMode) (string, error) {
+	if sizeBytes < 0 {
+		return "", ErrEinval
+	}
+	data := fmt.Sprintf("mode=%o", unixMode(mode))
+	if sizeBytes > 0 {
+		data = fmt.Sprintf("size=%d,%s", sizeBytes, data)
+	}
+	return data, nil
+}
+
+// unixMode converts mode to the permission bits of a unix mode.
+func unixMode(mode os.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= 01000
+	}
+	return m
+}
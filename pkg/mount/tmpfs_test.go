@@ -0,0 +1,51 @@
+package mount
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTmpfsData(t *testing.T) {
+	tests := []struct {
+		size     int64
+		mode     os.FileMode
+		expected string
+	}{
+		{size: 1 << 20, mode: 0755, expected: "size=1048576,mode=755"},
+		{size: 64 << 20, mode: 0700, expected: "size=67108864,mode=700"},
+		{size: 0, mode: 0777 | os.ModeSticky, expected: "mode=1777"},
+		{size: 4096, mode: 0750 | os.ModeSetgid, expected: "size=4096,mode=2750"},
+	}
+	for _, test := range tests {
+		data, err := tmpfsData(test.size, test.mode)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, data)
+	}
+
+	_, err := tmpfsData(-1, 0755)
+	require.Equal(t, ErrEinval, err)
+}
+
+func TestTmpfsMount(t *testing.T) {
+	m, mi := newTestMounter(t)
+	target := filepath.Join(t.TempDir(), "scratch")
+	require.NoError(t, os.Mkdir(target, 0755))
+
+	require.NoError(t, m.TmpfsMount(target, 16<<20, 0777|os.ModeSticky, 0))
+	call := mi.lastCall()
+	require.Equal(t, "", call.source, "Expected an empty device")
+	require.Equal(t, target, call.target)
+	require.Equal(t, "tmpfs", call.fstype)
+	require.Equal(t, "size=16777216,mode=1777", call.data)
+
+	info := m.testInfo(TmpfsDevice)
+	require.NotNil(t, info, "Expected the mount to be tracked")
+	require.Equal(t, "tmpfs", info.Fs)
+	require.Equal(t, []string{target}, m.Mounts(TmpfsDevice))
+
+	require.NoError(t, m.Unmount(TmpfsDevice, target, 0, 0, nil))
+	require.Equal(t, 0, m.HasMounts(TmpfsDevice))
+}
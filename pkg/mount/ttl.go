@@ -0,0 +1,91 @@
+package mount
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/libopenstorage/openstorage/pkg/sched"
+)
+
+// mountExpiry is the automatic unmount of a mount made with a TTL. path and
+// expireAt are guarded by the Mounter lock, path changing when the mount is
+// moved.
+type mountExpiry struct {
+	device   string
+	path     string
+	ttl      time.Duration
+	expireAt time.Time
+}
+
+// Refresh postpones the automatic unmount of the mount at path, made with a
+// MountOptions TTL, to a full TTL from now. It returns ErrEnoent if there is
+// no such mount.
+func (m *Mounter) Refresh(path string) error {
+	path = normalizeMountPath(path)
+	m.Lock()
+	defer m.Unlock()
+	e, ok := m.expiries[path]
+	if !ok {
+		return ErrEnoent
+	}
+	e.expireAt = m.clock.Now().Add(e.ttl)
+	return nil
+}
+
+// addExpiry schedules the unmount of device from path after ttl.
+func (m *Mounter) addExpiry(device, path string, ttl time.Duration) error {
+	e := &mountExpiry{device: device, path: path, ttl: ttl}
+	m.Lock()
+	e.expireAt = m.clock.Now().Add(ttl)
+	if m.expiries == nil {
+		m.expiries = make(map[string]*mountExpiry)
+	}
+	m.expiries[path] = e
+	m.Unlock()
+	if err := m.scheduleExpiry(e, ttl); err != nil {
+		m.deleteExpiry(e)
+		return fmt.Errorf("failed to schedule the unmount of %s: %w", path, err)
+	}
+	return nil
+}
+
+// scheduleExpiry checks e again after delay.
+func (m *Mounter) scheduleExpiry(e *mountExpiry, delay time.Duration) error {
+	return m.scheduleAfter(func(sched.Interval) {
+		m.expire(e)
+	}, delay)
+}
+
+// expire unmounts the mount of e if e is due, or checks it again when it is
+// due if it was refreshed. Nothing is done if the mount was unmounted in the
+// meantime.
+func (m *Mounter) expire(e *mountExpiry) {
+	m.Lock()
+	path := e.path
+	if m.expiries[path] != e {
+		m.Unlock()
+		return
+	}
+	remaining := e.expireAt.Sub(m.clock.Now())
+	m.Unlock()
+	if remaining > 0 {
+		if err := m.scheduleExpiry(e, remaining); err != nil && err != ErrClosed {
+			m.logger.Warnf("Failed to reschedule the unmount of %s: %v", path, err)
+		}
+		return
+	}
+	m.logger.Infof("Unmounting %s of %s after its TTL of %v", path, e.device, e.ttl)
+	if err := m.Unmount(e.device, path, 0, 0, nil); err != nil {
+		m.logger.Warnf("Failed to unmount %s after its TTL: %v", path, err)
+		m.deleteExpiry(e)
+	}
+}
+
+// deleteExpiry forgets e, unless its path has another expiry.
+func (m *Mounter) deleteExpiry(e *mountExpiry) {
+	m.Lock()
+	defer m.Unlock()
+	if m.expiries[e.path] == e {
+		delete(m.expiries, e.path)
+	}
+}
@@ -0,0 +1,48 @@
+package mount
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountTTL(t *testing.T) {
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	m, mi := newTestMounter(t, WithClock(clk), WithScheduler(s))
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/ttl", Path: "/mnt/ttl", Fs: "ext4", TTL: time.Hour,
+	}))
+	require.Equal(t, ErrEnoent, m.Refresh("/mnt/other"))
+
+	s.Advance(59 * time.Minute)
+	require.Equal(t, 1, m.HasMounts("/dev/ttl"))
+	require.NoError(t, m.Refresh("/mnt/ttl/"))
+	s.Advance(time.Minute)
+	require.Equal(t, 1, m.HasMounts("/dev/ttl"), "Expected Refresh to postpone the unmount")
+	s.Advance(58 * time.Minute)
+	require.Equal(t, 1, m.HasMounts("/dev/ttl"))
+	s.Advance(time.Minute)
+	require.Equal(t, 0, m.HasMounts("/dev/ttl"), "Expected the mount to be unmounted after its TTL")
+	require.Equal(t, []string{"/mnt/ttl"}, mi.unmounted)
+	require.Equal(t, 0, s.pending())
+	require.Equal(t, ErrEnoent, m.Refresh("/mnt/ttl"))
+}
+
+func TestMountTTLUnmounted(t *testing.T) {
+	clk := newTestClock()
+	s := newTestScheduler(clk)
+	m, mi := newTestMounter(t, WithClock(clk), WithScheduler(s))
+	require.NoError(t, m.MountWithOptions(MountOptions{
+		Device: "/dev/ttl", Path: "/mnt/ttl", Fs: "ext4", TTL: time.Hour,
+	}))
+	require.NoError(t, m.Unmount("/dev/ttl", "/mnt/ttl", 0, 0, nil))
+	require.Equal(t, ErrEnoent, m.Refresh("/mnt/ttl"))
+
+	// A new mount at the path without a TTL is kept.
+	require.NoError(t, m.Mount(0, "/dev/ttl", "/mnt/ttl", "ext4", 0, "", 0, nil))
+	s.Advance(time.Hour)
+	require.Equal(t, 1, m.HasMounts("/dev/ttl"))
+	require.Equal(t, []string{"/mnt/ttl"}, mi.unmounted)
+}
@@ -0,0 +1,25 @@
+package mount
+
+// MountUsage is the space and inode usage of a mounted filesystem.
+type MountUsage struct {
+	TotalBytes uint64
+	FreeBytes  uint64
+	// AvailableBytes are the free bytes available to unprivileged users.
+	AvailableBytes uint64
+	TotalInodes    uint64
+	FreeInodes     uint64
+}
+
+// Usage returns the usage of the filesystem mounted at path, from statfs. It
+// returns ErrEnoent if path is not a tracked mountpoint.
+func (m *Mounter) Usage(path string) (MountUsage, error) {
+	path = normalizeMountPath(path)
+	if _, ok := m.HasTarget(path); !ok {
+		return MountUsage{}, ErrEnoent
+	}
+	return statfs(path)
+}
+
+// statfs returns the usage of the filesystem of path. It is replaced by
+// tests.
+var statfs = statfsUsage
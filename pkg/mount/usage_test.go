@@ -0,0 +1,32 @@
+//go:build linux
+// +build linux
+
+package mount
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsage(t *testing.T) {
+	dir := t.TempDir()
+	// 1 MiB with 64 inodes.
+	if err := syscall.Mount("tmpfs", dir, "tmpfs", 0, "size=1m,nr_inodes=64"); err != nil {
+		t.Skipf("Cannot mount: %v", err)
+	}
+	defer syscall.Unmount(dir, 0)
+	m, _ := newTestMounter(t)
+	_, err := m.Usage(dir)
+	require.Equal(t, ErrEnoent, err)
+
+	require.NoError(t, m.Mount(0, "tmpfs", dir, "tmpfs", 0, "", 0, nil))
+	usage, err := m.Usage(dir + "/")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1<<20), usage.TotalBytes)
+	require.Equal(t, usage.TotalBytes, usage.FreeBytes)
+	require.Equal(t, usage.FreeBytes, usage.AvailableBytes)
+	require.Equal(t, uint64(64), usage.TotalInodes)
+	require.LessOrEqual(t, usage.FreeInodes, usage.TotalInodes)
+}
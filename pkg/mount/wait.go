@@ -0,0 +1,60 @@
+package mount
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// waitPollInterval is the interval at which the mount table is polled.
+const waitPollInterval = 100 * time.Millisecond
+
+// mountTable returns the current mount table, replaced by tests.
+var mountTable = GetMounts
+
+// WaitForMount polls the mount table until path is a mountpoint. It returns
+// ErrMountTimeout if path is not mounted within timeout.
+func WaitForMount(path string, timeout time.Duration) error {
+	return waitForMountpoint(path, true, timeout)
+}
+
+// WaitForUnmount polls the mount table until path is no longer a mountpoint.
+// It returns ErrMountTimeout if path is still mounted after timeout.
+func WaitForUnmount(path string, timeout time.Duration) error {
+	return waitForMountpoint(path, false, timeout)
+}
+
+func waitForMountpoint(path string, mounted bool, timeout time.Duration) error {
+	path = filepath.Clean(path)
+	deadline := time.Now().Add(timeout)
+	for {
+		found, err := inMountTable(path)
+		if err != nil {
+			return err
+		}
+		if found == mounted {
+			return nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return ErrMountTimeout
+		}
+		if remaining > waitPollInterval {
+			remaining = waitPollInterval
+		}
+		time.Sleep(remaining)
+	}
+}
+
+// inMountTable returns true if path is a mountpoint in the mount table.
+func inMountTable(path string) (bool, error) {
+	infos, err := mountTable()
+	if err != nil {
+		return false, err
+	}
+	for _, info := range infos {
+		if info.Mountpoint == path {
+			return true, nil
+		}
+	}
+	return false, nil
+}
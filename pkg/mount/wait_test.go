@@ -0,0 +1,56 @@
+package mount
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/mount"
+	"github.com/stretchr/testify/require"
+)
+
+// setTestMountTable replaces the mount table with one that lists path if
+// mountedAtStart is set. Calling the returned function flips that state.
+func setTestMountTable(t *testing.T, path string, mountedAtStart bool) func() {
+	var flipped int32
+	orig := mountTable
+	mountTable = func() ([]*mount.Info, error) {
+		infos := []*mount.Info{{Mountpoint: "/"}}
+		if (atomic.LoadInt32(&flipped) == 1) != mountedAtStart {
+			infos = append(infos, &mount.Info{Mountpoint: path})
+		}
+		return infos, nil
+	}
+	t.Cleanup(func() { mountTable = orig })
+	return func() { atomic.StoreInt32(&flipped, 1) }
+}
+
+func TestWaitForMount(t *testing.T) {
+	flip := setTestMountTable(t, "/mnt/wait", false)
+	time.AfterFunc(250*time.Millisecond, flip)
+
+	start := time.Now()
+	require.NoError(t, WaitForMount("/mnt/wait/", 5*time.Second))
+	require.True(t, time.Since(start) >= 250*time.Millisecond, "Returned before the mount appeared")
+	require.NoError(t, WaitForMount("/mnt/wait", 0), "Expected an existing mount to return at once")
+}
+
+func TestWaitForUnmount(t *testing.T) {
+	flip := setTestMountTable(t, "/mnt/wait", true)
+	require.Equal(t, ErrMountTimeout, WaitForUnmount("/mnt/wait", 250*time.Millisecond))
+
+	time.AfterFunc(100*time.Millisecond, flip)
+	require.NoError(t, WaitForUnmount("/mnt/wait", 5*time.Second))
+}
+
+func TestWaitForMountTimeout(t *testing.T) {
+	setTestMountTable(t, "/mnt/wait", false)
+	start := time.Now()
+	require.Equal(t, ErrMountTimeout, WaitForMount("/mnt/wait", 300*time.Millisecond))
+	require.True(t, time.Since(start) < 2*time.Second, "Waited past the timeout")
+
+	errRead := errors.New("read failed")
+	mountTable = func() ([]*mount.Info, error) { return nil, errRead }
+	require.Equal(t, errRead, WaitForMount("/mnt/wait", time.Second))
+}
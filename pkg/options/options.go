@@ -37,6 +37,15 @@ const (
 	// This option is used in conjunction with OptionsDeleteAfterUnmount.
 	// It indicates the Volume Driver to introduce a delay before deleting mount path
 	OptionsWaitBeforeDelete = "WAIT_BEFORE_DELETE"
+	// OptionsUnmountChildren is an option provided to the following Openstorage Volume API
+	// - Unmount
+	// It indicates the Volume Driver to first unmount the mounts under the mount path,
+	// such as the submounts of a recursive bind mount
+	OptionsUnmountChildren = "UNMOUNT_CHILDREN"
+	// OptionsUnmountLazyFallback is an option provided to the following Openstorage Volume API
+	// - Unmount
+	// It indicates the Volume Driver to lazily detach the mount path if it is busy
+	OptionsUnmountLazyFallback = "UNMOUNT_LAZY_FALLBACK"
 	// OptionsRedirectDetach is an option provided to the following Openstorage Volume API
 	// - Detach
 	// It indicates the Volume Driver to redirect detach to the node where volume is attached